@@ -4,16 +4,49 @@ import (
 	"context"
 	"cyber-docker/internal/wirex"
 	"cyber-docker/pkg/container/di"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func Run(ctx context.Context, dic *di.Container) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	injector, err := wirex.BuildInjector(dic)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("build injector: %w", err)
 	}
-	err = startHTTPServer(injector)
-	if err != nil {
-		return err
+	lc := injector.Lifecycle
+	cfg := injector.Config
+
+	go injector.Registry.Watch(lc.Done(), time.Second*time.Duration(cfg.Docker.HealthInterval))
+
+	srv := newHTTPServer(injector)
+	lc.AddHook("http server", srv.Shutdown)
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("http server listening", "addr", srv.Addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err = <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	case <-ctx.Done():
+		slog.Info("shutting down", "cause", context.Cause(ctx))
 	}
-	return nil
+
+	timeout := time.Second * time.Duration(cfg.HTTP.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return errors.Join(err, lc.Shutdown(shutdownCtx))
 }
@@ -7,17 +7,17 @@ import (
 	"time"
 )
 
-func startHTTPServer(injector *wirex.Injector) error {
+func newHTTPServer(injector *wirex.Injector) *http.Server {
+	cfg := injector.Config.HTTP
 
 	e := gin.New()
 	e.Use(gin.Recovery())
 	injector.RegisterRouters(e)
-	srv := &http.Server{
-		Addr:         ":8080",
+	return &http.Server{
+		Addr:         cfg.Addr,
 		Handler:      e,
-		ReadTimeout:  time.Second * time.Duration(60),
-		WriteTimeout: time.Second * time.Duration(60),
-		IdleTimeout:  time.Second * time.Duration(10),
+		ReadTimeout:  time.Second * time.Duration(cfg.ReadTimeout),
+		WriteTimeout: time.Second * time.Duration(cfg.WriteTimeout),
+		IdleTimeout:  time.Second * time.Duration(cfg.IdleTimeout),
 	}
-	return srv.ListenAndServe()
 }
@@ -0,0 +1,81 @@
+package bootstrap
+
+import (
+	"cyber-docker/internal/config"
+	"cyber-docker/internal/mods"
+	"cyber-docker/pkg/storage"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// OpenStorage 打开数据库并执行尚未执行的迁移
+func OpenStorage(cfg *config.Config) (*storage.DB, error) {
+	db, err := storage.Open(cfg.Storage.DatabasePath(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Migrate(mods.Migrations(cfg)...); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Backup 把数据库备份到 path，path 为 - 时写入标准输出。
+// 服务运行时数据库文件被锁定，需要通过 /api/v1/system/backup 在线备份
+func Backup(cfg *config.Config, path string) error {
+	if err := cfg.Security.CheckBackup(); err != nil {
+		return err
+	}
+	db, err := storage.Open(cfg.Storage.DatabasePath(), &storage.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		w = file
+	}
+	size, err := db.Backup(w)
+	if err != nil {
+		return err
+	}
+	slog.Info("storage", "backup", path, "size", size)
+	return nil
+}
+
+// Restore 用备份文件替换数据库，必须在服务停止时执行，原数据库保留为 .bak 文件
+func Restore(cfg *config.Config, path string) error {
+	// 确认数据库没有被正在运行的服务占用
+	db, err := storage.Open(cfg.Storage.DatabasePath(), &storage.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	if err = db.Close(); err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	if err = storage.Restore(cfg.Storage.DatabasePath(), file); err != nil {
+		return fmt.Errorf("restore %s: %w", path, err)
+	}
+	slog.Info("storage", "restored", path)
+	return nil
+}
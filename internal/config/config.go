@@ -0,0 +1,234 @@
+package config
+
+import (
+	"cyber-docker/pkg/container/di"
+	"cyber-docker/pkg/i18n"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+)
+
+var Name = di.TypeInstanceToName(Config{})
+
+func From(get di.Get) *Config {
+	return get(Name).(*Config)
+}
+
+type Config struct {
+	HTTP     HTTP     `yaml:"http" toml:"http"`
+	Docker   Docker   `yaml:"docker" toml:"docker"`
+	Storage  Storage  `yaml:"storage" toml:"storage"`
+	Security Security `yaml:"security" toml:"security"`
+	Auth     Auth     `yaml:"auth" toml:"auth"`
+	Audit    Audit    `yaml:"audit" toml:"audit"`
+}
+
+type HTTP struct {
+	Addr string `yaml:"addr" toml:"addr"`
+	// 超时时间，单位秒
+	ReadTimeout  int `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout int `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  int `yaml:"idle_timeout" toml:"idle_timeout"`
+	// 退出时等待请求处理完成的时间，单位秒
+	ShutdownTimeout int `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// 用户未设置语言且 Accept-Language 无法匹配时的响应语言，支持 zh-CN、en-US
+	Locale string `yaml:"locale" toml:"locale"`
+}
+
+type Docker struct {
+	// 默认端点名称，未指定端点的路由使用该端点
+	Name string `yaml:"name" toml:"name"`
+	// 为空时使用 DOCKER_HOST 等标准环境变量
+	Host string `yaml:"host" toml:"host"`
+	// 端点健康检查间隔，单位秒
+	HealthInterval int `yaml:"health_interval" toml:"health_interval"`
+	// 交互终端无输入输出时自动断开的时间，单位秒
+	ExecIdleTimeout int `yaml:"exec_idle_timeout" toml:"exec_idle_timeout"`
+	// 构建镜像使用的构建器，1 为经典构建器，2 为 BuildKit
+	BuilderVersion string     `yaml:"builder_version" toml:"builder_version"`
+	Endpoints      []Endpoint `yaml:"endpoints" toml:"endpoints"`
+	// 镜像仓库凭据，请求未提供凭据时按仓库地址匹配
+	Registries []Registry `yaml:"registries" toml:"registries"`
+}
+
+type Registry struct {
+	// 仓库地址，例如 registry.example.com:5000，Docker Hub 为 docker.io
+	Host          string `yaml:"host" toml:"host"`
+	Username      string `yaml:"username" toml:"username"`
+	Password      string `yaml:"password" toml:"password"`
+	IdentityToken string `yaml:"identity_token" toml:"identity_token"`
+}
+
+type Storage struct {
+	// 数据目录，保存数据库文件和开启 security.generate_key 时生成的密钥
+	Dir string `yaml:"dir" toml:"dir"`
+}
+
+// DatabasePath 返回嵌入式数据库文件的路径
+func (s Storage) DatabasePath() string {
+	return filepath.Join(s.Dir, "cyber-docker.db")
+}
+
+type Security struct {
+	// 加密保存敏感数据的密钥，必须配置，除非开启 GenerateKey
+	SecretKey string `yaml:"secret_key" toml:"secret_key"`
+	// SecretKey 为空时使用数据目录下自动生成的 secret.key，备份数据库时需要另外备份该文件
+	GenerateKey bool `yaml:"generate_key" toml:"generate_key"`
+}
+
+// CheckBackup 使用自动生成的 secret.key 时返回错误。该文件不在数据库备份中，只恢复数据库会使加密保存的密码无法解密
+func (s Security) CheckBackup() error {
+	if s.SecretKey == "" {
+		return errors.New("the secret key is generated in the data directory and is not part of the backup, " +
+			"set security.secret_key to the content of secret.key before backing up")
+	}
+	return nil
+}
+
+type Auth struct {
+	// 签名 JWT 的密钥，为空时使用首次启动时生成并保存在数据库中的随机密钥
+	JWTSecret string `yaml:"jwt_secret" toml:"jwt_secret"`
+	// 有效期，单位秒
+	AccessTokenTTL  int `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL int `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+	// 连续登录失败达到该次数后锁定账号
+	MaxLoginAttempts int `yaml:"max_login_attempts" toml:"max_login_attempts"`
+	// 账号锁定时间，单位秒
+	LockoutDuration int `yaml:"lockout_duration" toml:"lockout_duration"`
+	// 首次启动时创建的管理员，密码为空时生成随机密码并输出到日志，首次登录后必须修改密码
+	AdminUsername string `yaml:"admin_username" toml:"admin_username"`
+	AdminPassword string `yaml:"admin_password" toml:"admin_password"`
+}
+
+type Audit struct {
+	// 审计记录保留天数，为 0 时永久保留
+	RetentionDays int `yaml:"retention_days" toml:"retention_days"`
+}
+
+type Endpoint struct {
+	Name   string            `yaml:"name" toml:"name"`
+	Host   string            `yaml:"host" toml:"host"`
+	TLS    *EndpointTLS      `yaml:"tls" toml:"tls"`
+	Labels map[string]string `yaml:"labels" toml:"labels"`
+}
+
+// EndpointTLS 证书和私钥均为 PEM 内容
+type EndpointTLS struct {
+	CACert     string `yaml:"ca_cert" toml:"ca_cert"`
+	Cert       string `yaml:"cert" toml:"cert"`
+	Key        string `yaml:"key" toml:"key"`
+	SkipVerify bool   `yaml:"skip_verify" toml:"skip_verify"`
+}
+
+func Default() *Config {
+	return &Config{
+		HTTP: HTTP{
+			Addr:            ":8080",
+			ReadTimeout:     60,
+			WriteTimeout:    60,
+			IdleTimeout:     10,
+			ShutdownTimeout: 30,
+			Locale:          i18n.Default,
+		},
+		Docker: Docker{
+			Name:            "local",
+			HealthInterval:  30,
+			ExecIdleTimeout: 600,
+			BuilderVersion:  "1",
+		},
+		Storage: Storage{
+			Dir: "data",
+		},
+		Auth: Auth{
+			AccessTokenTTL:   900,
+			RefreshTokenTTL:  7 * 24 * 3600,
+			MaxLoginAttempts: 5,
+			LockoutDuration:  900,
+			AdminUsername:    "admin",
+		},
+		Audit: Audit{
+			RetentionDays: 90,
+		},
+	}
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	if c.HTTP.Addr == "" {
+		errs = append(errs, errors.New("http.addr is required"))
+	} else if _, _, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("http.addr: %w", err))
+	}
+	if c.HTTP.ReadTimeout < 0 {
+		errs = append(errs, errors.New("http.read_timeout must not be negative"))
+	}
+	if c.HTTP.WriteTimeout < 0 {
+		errs = append(errs, errors.New("http.write_timeout must not be negative"))
+	}
+	if c.HTTP.IdleTimeout < 0 {
+		errs = append(errs, errors.New("http.idle_timeout must not be negative"))
+	}
+	if c.HTTP.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("http.shutdown_timeout must not be negative"))
+	}
+	if !i18n.Supported(c.HTTP.Locale) {
+		errs = append(errs, fmt.Errorf("http.locale: unsupported locale %q", c.HTTP.Locale))
+	}
+	if c.Docker.Name == "" {
+		errs = append(errs, errors.New("docker.name is required"))
+	}
+	if c.Docker.HealthInterval <= 0 {
+		errs = append(errs, errors.New("docker.health_interval must be positive"))
+	}
+	if c.Docker.ExecIdleTimeout <= 0 {
+		errs = append(errs, errors.New("docker.exec_idle_timeout must be positive"))
+	}
+	if c.Docker.BuilderVersion != "1" && c.Docker.BuilderVersion != "2" {
+		errs = append(errs, fmt.Errorf("docker.builder_version: unsupported version %q", c.Docker.BuilderVersion))
+	}
+	if c.Storage.Dir == "" {
+		errs = append(errs, errors.New("storage.dir is required"))
+	}
+	if c.Security.SecretKey == "" && !c.Security.GenerateKey {
+		errs = append(errs, errors.New("security.secret_key is required, or set security.generate_key to use a generated key"))
+	}
+	if c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL <= 0 {
+		errs = append(errs, errors.New("auth.access_token_ttl and auth.refresh_token_ttl must be positive"))
+	}
+	if c.Auth.MaxLoginAttempts <= 0 {
+		errs = append(errs, errors.New("auth.max_login_attempts must be positive"))
+	}
+	if c.Auth.LockoutDuration <= 0 {
+		errs = append(errs, errors.New("auth.lockout_duration must be positive"))
+	}
+	if c.Auth.AdminUsername == "" {
+		errs = append(errs, errors.New("auth.admin_username is required"))
+	}
+	if c.Audit.RetentionDays < 0 {
+		errs = append(errs, errors.New("audit.retention_days must not be negative"))
+	}
+	if c.Docker.Host != "" {
+		if u, err := url.Parse(c.Docker.Host); err != nil {
+			errs = append(errs, fmt.Errorf("docker.host: %w", err))
+		} else if u.Scheme == "" {
+			errs = append(errs, fmt.Errorf("docker.host: missing scheme in %q", c.Docker.Host))
+		}
+	}
+	names := map[string]bool{c.Docker.Name: true}
+	for i, item := range c.Docker.Endpoints {
+		if item.Name == "" || item.Host == "" {
+			errs = append(errs, fmt.Errorf("docker.endpoints[%d]: name and host are required", i))
+		} else if names[item.Name] {
+			errs = append(errs, fmt.Errorf("docker.endpoints[%d]: duplicate name %q", i, item.Name))
+		}
+		names[item.Name] = true
+	}
+	for i, item := range c.Docker.Registries {
+		if item.Host == "" {
+			errs = append(errs, fmt.Errorf("docker.registries[%d]: host is required", i))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const envPrefix = "CYBER_DOCKER_"
+
+// Load 读取配置文件并应用环境变量覆盖，path 为空时只使用默认值和环境变量
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config %s: %w", path, err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(buf, cfg)
+	case ".toml":
+		err = toml.Unmarshal(buf, cfg)
+	default:
+		return fmt.Errorf("unsupported config format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+type envBinding struct {
+	key string
+	set func(cfg *Config, value string) error
+}
+
+func stringEnv(field func(cfg *Config) *string) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		*field(cfg) = value
+		return nil
+	}
+}
+
+func intEnv(field func(cfg *Config) *int) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field(cfg) = v
+		return nil
+	}
+}
+
+func boolEnv(field func(cfg *Config) *bool) func(cfg *Config, value string) error {
+	return func(cfg *Config, value string) error {
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field(cfg) = v
+		return nil
+	}
+}
+
+var envBindings = []envBinding{
+	{"HTTP_ADDR", stringEnv(func(cfg *Config) *string { return &cfg.HTTP.Addr })},
+	{"HTTP_READ_TIMEOUT", intEnv(func(cfg *Config) *int { return &cfg.HTTP.ReadTimeout })},
+	{"HTTP_WRITE_TIMEOUT", intEnv(func(cfg *Config) *int { return &cfg.HTTP.WriteTimeout })},
+	{"HTTP_IDLE_TIMEOUT", intEnv(func(cfg *Config) *int { return &cfg.HTTP.IdleTimeout })},
+	{"HTTP_SHUTDOWN_TIMEOUT", intEnv(func(cfg *Config) *int { return &cfg.HTTP.ShutdownTimeout })},
+	{"HTTP_LOCALE", stringEnv(func(cfg *Config) *string { return &cfg.HTTP.Locale })},
+	{"DOCKER_NAME", stringEnv(func(cfg *Config) *string { return &cfg.Docker.Name })},
+	{"DOCKER_HOST", stringEnv(func(cfg *Config) *string { return &cfg.Docker.Host })},
+	{"DOCKER_HEALTH_INTERVAL", intEnv(func(cfg *Config) *int { return &cfg.Docker.HealthInterval })},
+	{"DOCKER_EXEC_IDLE_TIMEOUT", intEnv(func(cfg *Config) *int { return &cfg.Docker.ExecIdleTimeout })},
+	{"DOCKER_BUILDER_VERSION", stringEnv(func(cfg *Config) *string { return &cfg.Docker.BuilderVersion })},
+	{"STORAGE_DIR", stringEnv(func(cfg *Config) *string { return &cfg.Storage.Dir })},
+	{"SECURITY_SECRET_KEY", stringEnv(func(cfg *Config) *string { return &cfg.Security.SecretKey })},
+	{"SECURITY_GENERATE_KEY", boolEnv(func(cfg *Config) *bool { return &cfg.Security.GenerateKey })},
+	{"AUTH_JWT_SECRET", stringEnv(func(cfg *Config) *string { return &cfg.Auth.JWTSecret })},
+	{"AUTH_ACCESS_TOKEN_TTL", intEnv(func(cfg *Config) *int { return &cfg.Auth.AccessTokenTTL })},
+	{"AUTH_REFRESH_TOKEN_TTL", intEnv(func(cfg *Config) *int { return &cfg.Auth.RefreshTokenTTL })},
+	{"AUTH_MAX_LOGIN_ATTEMPTS", intEnv(func(cfg *Config) *int { return &cfg.Auth.MaxLoginAttempts })},
+	{"AUTH_LOCKOUT_DURATION", intEnv(func(cfg *Config) *int { return &cfg.Auth.LockoutDuration })},
+	{"AUTH_ADMIN_USERNAME", stringEnv(func(cfg *Config) *string { return &cfg.Auth.AdminUsername })},
+	{"AUTH_ADMIN_PASSWORD", stringEnv(func(cfg *Config) *string { return &cfg.Auth.AdminPassword })},
+	{"AUDIT_RETENTION_DAYS", intEnv(func(cfg *Config) *int { return &cfg.Audit.RetentionDays })},
+}
+
+func applyEnv(cfg *Config) error {
+	for _, binding := range envBindings {
+		value, ok := os.LookupEnv(envPrefix + binding.key)
+		if !ok {
+			continue
+		}
+		if err := binding.set(cfg, value); err != nil {
+			return fmt.Errorf("env %s%s: %w", envPrefix, binding.key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"cyber-docker/internal/mods/audit/entity"
+	"cyber-docker/internal/mods/audit/entity/dto"
+	"cyber-docker/internal/mods/audit/repo"
+	"cyber-docker/pkg/utils"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultPageSize = 20
+
+var csvHeader = []string{
+	"id", "time", "actor", "token_id", "client_ip", "user_agent", "method", "route", "path",
+	"endpoint", "resource_id", "params", "status", "code", "success", "error", "duration_ms",
+}
+
+func toFilter(params dto.AuditQueryDto) repo.Filter {
+	return repo.Filter{
+		Actor:      params.Actor,
+		TokenID:    params.TokenID,
+		Method:     params.Method,
+		Route:      params.Route,
+		Endpoint:   params.Endpoint,
+		ResourceID: params.ResourceID,
+		Success:    params.Success,
+		Since:      params.Since,
+		Until:      params.Until,
+	}
+}
+
+// List 按时间倒序分页查询审计记录
+func (a *Audit) List(c *gin.Context) {
+	var params dto.AuditQueryDto
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		utils.ResFail(c, utils.NewError(http.StatusBadRequest, err))
+		return
+	}
+	page, size := max(params.Page, 1), params.PageSize
+	if size == 0 {
+		size = defaultPageSize
+	}
+	list, total, err := a.Records.List(toFilter(params), (page-1)*size, size)
+	if err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.ResPage(c, total, list)
+}
+
+// Export 导出所有匹配的审计记录，format 为 csv 或 jsonl，默认 jsonl
+func (a *Audit) Export(c *gin.Context) {
+	var params dto.AuditExportDto
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		utils.ResFail(c, utils.NewError(http.StatusBadRequest, err))
+		return
+	}
+	if params.Format == "" {
+		params.Format = "jsonl"
+	}
+	filename := fmt.Sprintf("audit-%s.%s", time.Now().Format("20060102-150405"), params.Format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	if params.Format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		err = a.exportCSV(c, toFilter(params.AuditQueryDto))
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(c.Writer)
+		err = a.Records.Each(toFilter(params.AuditQueryDto), func(record entity.Record) error {
+			return encoder.Encode(record)
+		})
+	}
+	// 响应头已发送，出错时只能中断输出
+	if err != nil {
+		slog.Error("audit", "export", err)
+	}
+}
+
+func (a *Audit) exportCSV(c *gin.Context, filter repo.Filter) error {
+	writer := csv.NewWriter(c.Writer)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	err := a.Records.Each(filter, func(record entity.Record) error {
+		params, err := json.Marshal(record.Params)
+		if err != nil {
+			return err
+		}
+		return writer.Write([]string{
+			strconv.FormatUint(record.ID, 10),
+			record.Time.Format(time.RFC3339Nano),
+			record.Actor,
+			record.TokenID,
+			record.ClientIP,
+			record.UserAgent,
+			record.Method,
+			record.Route,
+			record.Path,
+			record.Endpoint,
+			record.ResourceID,
+			string(params),
+			strconv.Itoa(record.Status),
+			strconv.Itoa(record.Code),
+			strconv.FormatBool(record.Success),
+			record.Error,
+			strconv.FormatInt(record.Duration, 10),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
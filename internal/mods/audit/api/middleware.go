@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"cyber-docker/internal/mods/audit/entity"
+	"cyber-docker/internal/mods/audit/repo"
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/identity"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// maxBodySize 超过该大小的请求体不记录
+	maxBodySize = 64 << 10
+	// maxCaptureSize 用于解析响应中的业务状态码，流式响应只保留开头部分
+	maxCaptureSize = 4 << 10
+)
+
+// auditedReads 需要审计的 GET 路由后缀，例如进入容器终端和下载数据库备份
+var auditedReads = []string{
+	"/containers/:id/exec",
+	"/containers/:id/file",
+	"/images/export",
+	"/system/backup",
+}
+
+// resourceParams 按顺序取第一个存在的路径参数作为资源 ID
+var resourceParams = []string{"id", "name", "host", "username"}
+
+type Audit struct {
+	Records repo.AuditRepository
+}
+
+func audited(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return c.FullPath() != ""
+	}
+	for _, item := range auditedReads {
+		if strings.HasSuffix(c.FullPath(), item) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureWriter 保存响应的开头部分
+type captureWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	if remain := maxCaptureSize - w.buf.Len(); remain > 0 {
+		w.buf.Write(data[:min(len(data), remain)])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	if remain := maxCaptureSize - w.buf.Len(); remain > 0 {
+		w.buf.WriteString(s[:min(len(s), remain)])
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Record 记录所有修改操作，需要在认证中间件之前注册，认证失败的请求也会被记录
+func (a *Audit) Record(c *gin.Context) {
+	if !audited(c) {
+		c.Next()
+		return
+	}
+	start := time.Now()
+	params := make(map[string]interface{})
+	if len(c.Params) > 0 {
+		path := make(map[string]string, len(c.Params))
+		for _, item := range c.Params {
+			path[item.Key] = item.Value
+		}
+		params["path"] = path
+	}
+	if query := c.Request.URL.Query(); len(query) > 0 {
+		params["query"] = redactQuery(query)
+	}
+	if body := readBody(c); body != nil {
+		params["body"] = body
+	}
+	writer := &captureWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+
+	c.Next()
+
+	record := entity.Record{
+		Time:      start,
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Method:    c.Request.Method,
+		Route:     c.FullPath(),
+		Path:      c.Request.URL.Path,
+		Endpoint:  docker.ContextEndpoint(c),
+		Params:    params,
+		Status:    writer.Status(),
+		Code:      writer.Status(),
+		Success:   writer.Status() < http.StatusBadRequest,
+		Duration:  time.Since(start).Milliseconds(),
+	}
+	if id := identity.FromContext(c); id != nil {
+		record.Actor = id.Username
+		record.TokenID = id.TokenID
+	}
+	for _, key := range resourceParams {
+		if value := c.Param(key); value != "" {
+			record.ResourceID = value
+			break
+		}
+	}
+	applyOutcome(&record, writer.Header().Get("Content-Type"), writer.buf.Bytes())
+	if len(c.Errors) > 0 && record.Error == "" {
+		record.Error = c.Errors.String()
+	}
+	if err := a.Records.Save(&record); err != nil {
+		slog.Error("audit", "save record", err)
+	}
+}
+
+// readBody 读取并还原 JSON 请求体，上传文件等其他类型的请求体不记录
+func readBody(c *gin.Context) interface{} {
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return nil
+	}
+	buf, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodySize+1))
+	c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(buf), c.Request.Body), c.Request.Body}
+	if err != nil || len(buf) == 0 || len(buf) > maxBodySize {
+		return nil
+	}
+	return redactBody(buf)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// applyOutcome 业务状态码和错误信息从 JSON 响应体中解析，优先记录未翻译的原始错误信息
+func applyOutcome(record *entity.Record, contentType string, body []byte) {
+	if !strings.HasPrefix(contentType, "application/json") {
+		return
+	}
+	var result struct {
+		Success *bool  `json:"success"`
+		Code    int    `json:"code"`
+		Msg     string `json:"msg"`
+		Detail  string `json:"detail"`
+	}
+	// 流式响应只截取了开头，只解析第一个 JSON 值
+	if json.NewDecoder(bytes.NewReader(body)).Decode(&result) != nil || result.Success == nil {
+		return
+	}
+	record.Success = *result.Success && record.Success
+	if result.Code != 0 {
+		record.Code = result.Code
+	}
+	if !*result.Success {
+		record.Error = result.Detail
+		if record.Error == "" {
+			record.Error = result.Msg
+		}
+	}
+}
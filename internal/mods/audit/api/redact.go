@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+const redacted = "******"
+
+// sensitiveKeys 字段名包含这些词（不区分大小写）时值被替换为 ******
+var sensitiveKeys = []string{"password", "secret", "token", "auth", "key", "credential", "cert"}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, item := range sensitiveKeys {
+		if strings.Contains(key, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue 递归脱敏 JSON 值，env 数组中 KEY=VALUE 形式的敏感变量只保留变量名
+func redactValue(key string, value interface{}) interface{} {
+	if key != "" && isSensitive(key) {
+		if value == nil || value == "" {
+			return value
+		}
+		return redacted
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			result[k] = redactValue(k, item)
+		}
+		return result
+	case []interface{}:
+		isEnv := strings.EqualFold(key, "env")
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && isEnv {
+				if name, _, found := strings.Cut(s, "="); found && isSensitive(name) {
+					item = name + "=" + redacted
+				}
+			}
+			result = append(result, redactValue("", item))
+		}
+		return result
+	}
+	return value
+}
+
+// redactBody 脱敏 JSON 请求体，不是 JSON 时返回 nil
+func redactBody(body []byte) interface{} {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil
+	}
+	return redactValue("", value)
+}
+
+// redactQuery 多个值的参数转换为 JSON 数组后脱敏，与请求体中的数组相同处理
+func redactQuery(query url.Values) map[string]interface{} {
+	result := make(map[string]interface{}, len(query))
+	for key, values := range query {
+		var value interface{}
+		if len(values) == 1 {
+			value = values[0]
+		} else {
+			list := make([]interface{}, 0, len(values))
+			for _, item := range values {
+				list = append(list, item)
+			}
+			value = list
+		}
+		result[key] = redactValue(key, value)
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestRedactValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value interface{}
+		want  interface{}
+	}{
+		{"plain field", "image", "nginx:1.25", "nginx:1.25"},
+		{"sensitive field", "password", "s3cret", redacted},
+		{"case insensitive", "RegistryAuth", "e30=", redacted},
+		{"key contains word", "identity_token", "abc", redacted},
+		{"empty secret is kept", "secret", "", ""},
+		{"null secret is kept", "secret", nil, nil},
+		{"non-string secret", "key", float64(42), redacted},
+		{"sensitive object", "auth", map[string]interface{}{"username": "ci"}, redacted},
+		{
+			"nested object",
+			"",
+			map[string]interface{}{"name": "web", "tls": map[string]interface{}{"cert": "PEM", "skip_verify": true}},
+			map[string]interface{}{"name": "web", "tls": map[string]interface{}{"cert": redacted, "skip_verify": true}},
+		},
+		{
+			"objects in array",
+			"registries",
+			[]interface{}{map[string]interface{}{"host": "r.example.com", "password": "p"}},
+			[]interface{}{map[string]interface{}{"host": "r.example.com", "password": redacted}},
+		},
+		{
+			"env keeps variable names",
+			"env",
+			[]interface{}{"DB_PASSWORD=p", "api_token=t", "MODE=prod", "SECRET", "KEY="},
+			[]interface{}{"DB_PASSWORD=" + redacted, "api_token=" + redacted, "MODE=prod", "SECRET", "KEY=" + redacted},
+		},
+		{"env key is case insensitive", "Env", []interface{}{"AWS_SECRET_ACCESS_KEY=x"}, []interface{}{"AWS_SECRET_ACCESS_KEY=" + redacted}},
+		{"only env arrays are parsed", "cmd", []interface{}{"PASSWORD=p"}, []interface{}{"PASSWORD=p"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactValue(tt.key, tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("redactValue(%q, %v) = %v, want %v", tt.key, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	got := redactBody([]byte(`{"image":"redis:7","env":["REDIS_PASSWORD=p"],"auth":{"password":"p"}}`))
+	want := map[string]interface{}{
+		"image": "redis:7",
+		"env":   []interface{}{"REDIS_PASSWORD=" + redacted},
+		"auth":  redacted,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("redactBody = %v, want %v", got, want)
+	}
+	if got = redactBody([]byte("not json")); got != nil {
+		t.Errorf("redactBody of a non-JSON body = %v", got)
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	got := redactQuery(url.Values{
+		"access_token": {"jwt"},
+		"ref":          {"nginx:1.25"},
+		"tag":          {"a", "b"},
+		"env":          {"TOKEN=t", "MODE=prod"},
+	})
+	want := map[string]interface{}{
+		"access_token": redacted,
+		"ref":          "nginx:1.25",
+		"tag":          []interface{}{"a", "b"},
+		"env":          []interface{}{"TOKEN=" + redacted, "MODE=prod"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("redactQuery = %v, want %v", got, want)
+	}
+}
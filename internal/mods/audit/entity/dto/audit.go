@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+type AuditQueryDto struct {
+	Actor   string `form:"actor"`
+	TokenID string `form:"token_id"`
+	Method  string `form:"method"`
+	// Route 按路由模板或实际路径的子串匹配
+	Route      string     `form:"route"`
+	Endpoint   string     `form:"endpoint"`
+	ResourceID string     `form:"resource_id"`
+	Success    *bool      `form:"success"`
+	Since      *time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until      *time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page       int        `form:"page" binding:"omitempty,min=1"`
+	PageSize   int        `form:"page_size" binding:"omitempty,min=1,max=500"`
+}
+
+type AuditExportDto struct {
+	AuditQueryDto
+	Format string `form:"format" binding:"omitempty,oneof=csv jsonl"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// Record 是一次修改操作的审计记录
+type Record struct {
+	ID   uint64    `json:"id"`
+	Time time.Time `json:"time"`
+	// Actor 为调用者的用户名，未通过认证的请求为空
+	Actor     string `json:"actor"`
+	TokenID   string `json:"token_id,omitempty"`
+	ClientIP  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	Method    string `json:"method"`
+	// Route 为路由模板，例如 /api/v1/containers/:id，Path 为实际请求路径
+	Route      string `json:"route"`
+	Path       string `json:"path"`
+	Endpoint   string `json:"endpoint,omitempty"`
+	ResourceID string `json:"resource_id,omitempty"`
+	// Params 为路径参数、查询参数和请求体，敏感字段已脱敏
+	Params map[string]interface{} `json:"params,omitempty"`
+	// Status 为 HTTP 状态码，Code 为响应体中的业务状态码
+	Status   int    `json:"status"`
+	Code     int    `json:"code"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration_ms"`
+}
@@ -0,0 +1,24 @@
+package audit
+
+import (
+	"cyber-docker/internal/mods/audit/api"
+	"cyber-docker/pkg/rbac"
+	"github.com/gin-gonic/gin"
+)
+
+type Audit struct {
+	AuditApi api.Audit
+}
+
+// Record 是 /api/v1 下所有路由的审计中间件
+func (a *Audit) Record(c *gin.Context) {
+	a.AuditApi.Record(c)
+}
+
+func (a *Audit) RegisterV1Routers(v1 *gin.RouterGroup) {
+	audit := v1.Group("/audit", rbac.Require(rbac.AuditRead))
+	{
+		audit.GET("", a.AuditApi.List)
+		audit.GET("/export", a.AuditApi.Export)
+	}
+}
@@ -0,0 +1,159 @@
+package repo
+
+import (
+	"cyber-docker/internal/config"
+	"cyber-docker/internal/mods/audit/entity"
+	"cyber-docker/pkg/storage"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const auditBucket = "audit"
+
+// batchSize 为 Each 在一个只读事务中读取的最大记录数，导出大量记录时不会长时间持有事务
+const batchSize = 256
+
+// Filter 为空的字段不参与过滤
+type Filter struct {
+	Actor      string
+	TokenID    string
+	Method     string
+	Route      string
+	Endpoint   string
+	ResourceID string
+	Success    *bool
+	Since      *time.Time
+	Until      *time.Time
+}
+
+func (f Filter) Match(record entity.Record) bool {
+	switch {
+	case f.Actor != "" && record.Actor != f.Actor,
+		f.TokenID != "" && record.TokenID != f.TokenID,
+		f.Method != "" && !strings.EqualFold(record.Method, f.Method),
+		f.Route != "" && !strings.Contains(record.Route, f.Route) && !strings.Contains(record.Path, f.Route),
+		f.Endpoint != "" && record.Endpoint != f.Endpoint,
+		f.ResourceID != "" && record.ResourceID != f.ResourceID,
+		f.Success != nil && record.Success != *f.Success,
+		f.Since != nil && record.Time.Before(*f.Since),
+		f.Until != nil && !record.Time.Before(*f.Until):
+		return false
+	}
+	return true
+}
+
+type AuditRepository interface {
+	// Save 保存记录并删除超过保留期限的记录
+	Save(record *entity.Record) error
+	// List 按时间倒序返回第 offset 条开始的最多 limit 条记录和匹配的总数
+	List(filter Filter, offset, limit int) ([]entity.Record, int64, error)
+	// Each 按时间倒序遍历匹配的记录，fn 返回 storage.ErrStop 时提前结束
+	Each(filter Filter, fn func(record entity.Record) error) error
+}
+
+// NewAuditRepository audit.retention_days 为 0 时永久保留
+func NewAuditRepository(db *storage.DB, cfg *config.Config) AuditRepository {
+	return &auditRepository{db: db, retention: time.Duration(cfg.Audit.RetentionDays) * 24 * time.Hour}
+}
+
+type auditRepository struct {
+	db        *storage.DB
+	retention time.Duration
+}
+
+// Save 记录的 ID 为递增序号，key 按写入顺序排列
+func (r *auditRepository) Save(record *entity.Record) error {
+	return r.db.Update(func(tx *storage.Tx) error {
+		seq, err := tx.NextSequence(auditBucket)
+		if err != nil {
+			return err
+		}
+		record.ID = seq
+		if err = tx.Put(auditBucket, storage.SequenceKey(seq), record); err != nil {
+			return err
+		}
+		return r.prune(tx, record.Time)
+	})
+}
+
+func (r *auditRepository) prune(tx *storage.Tx, now time.Time) error {
+	if r.retention <= 0 {
+		return nil
+	}
+	cutoff := now.Add(-r.retention)
+	var expired []string
+	err := tx.ForEach(auditBucket, func(key string, value []byte) error {
+		var record entity.Record
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		if !record.Time.Before(cutoff) {
+			return storage.ErrStop
+		}
+		expired = append(expired, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range expired {
+		if err = tx.Delete(auditBucket, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *auditRepository) List(filter Filter, offset, limit int) ([]entity.Record, int64, error) {
+	list := make([]entity.Record, 0, limit)
+	var total int64
+	err := r.Each(filter, func(record entity.Record) error {
+		if total >= int64(offset) && len(list) < limit {
+			list = append(list, record)
+		}
+		total++
+		return nil
+	})
+	return list, total, err
+}
+
+// Each 分批读取记录，fn 在事务之外执行，导出期间写入新记录不受影响
+func (r *auditRepository) Each(filter Filter, fn func(record entity.Record) error) error {
+	before := ""
+	for {
+		batch := make([]entity.Record, 0, batchSize)
+		scanned := 0
+		err := r.db.View(func(tx *storage.Tx) error {
+			return tx.ForEachReverseBefore(auditBucket, before, func(key string, value []byte) error {
+				before = key
+				scanned++
+				var record entity.Record
+				if err := json.Unmarshal(value, &record); err != nil {
+					return err
+				}
+				if filter.Match(record) {
+					batch = append(batch, record)
+				}
+				if scanned == batchSize {
+					return storage.ErrStop
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		for _, record := range batch {
+			if err = fn(record); errors.Is(err, storage.ErrStop) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		if scanned < batchSize {
+			return nil
+		}
+	}
+}
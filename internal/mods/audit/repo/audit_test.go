@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"cyber-docker/internal/config"
+	"cyber-docker/internal/mods/audit/entity"
+	"cyber-docker/pkg/storage"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEach(t *testing.T) {
+	db, err := storage.Open(filepath.Join(t.TempDir(), "data.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	records := NewAuditRepository(db, config.Default())
+	// 超过两个批次，最后一个批次不满
+	total := batchSize*2 + 10
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < total; i++ {
+		actor := "alice"
+		if i%2 == 1 {
+			actor = "bob"
+		}
+		if err = records.Save(&entity.Record{Time: start.Add(time.Duration(i) * time.Second), Actor: actor}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []uint64
+	err = records.Each(Filter{Actor: "bob"}, func(record entity.Record) error {
+		if len(ids) == 0 {
+			// 导出期间可以写入，新记录不在本次导出中
+			if err := records.Save(&entity.Record{Time: time.Now(), Actor: "bob"}); err != nil {
+				return err
+			}
+		}
+		ids = append(ids, record.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != total/2 {
+		t.Fatalf("exported %d records, want %d", len(ids), total/2)
+	}
+	for i, id := range ids {
+		if want := uint64(total - 2*i); id != want {
+			t.Fatalf("record %d has id %d, want %d", i, id, want)
+		}
+	}
+
+	count := 0
+	err = records.Each(Filter{}, func(record entity.Record) error {
+		count++
+		if count == batchSize+1 {
+			return storage.ErrStop
+		}
+		return nil
+	})
+	if err != nil || count != batchSize+1 {
+		t.Fatalf("stopped after %d records, err %v", count, err)
+	}
+
+	list, matched, err := records.List(Filter{Actor: "alice"}, batchSize, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matched != int64(total/2) || len(list) != 5 || list[0].ID != uint64(total-1-2*batchSize) {
+		t.Fatalf("list total %d, page %+v", matched, list)
+	}
+}
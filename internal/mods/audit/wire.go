@@ -0,0 +1,13 @@
+package audit
+
+import (
+	"cyber-docker/internal/mods/audit/api"
+	"cyber-docker/internal/mods/audit/repo"
+	"github.com/google/wire"
+)
+
+var Set = wire.NewSet(
+	wire.Struct(new(Audit), "*"),
+	wire.Struct(new(api.Audit), "*"),
+	repo.NewAuditRepository,
+)
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"cyber-docker/internal/mods/auth/entity"
+	"cyber-docker/internal/mods/auth/entity/dto"
+	"cyber-docker/internal/mods/auth/repo"
+	"cyber-docker/pkg/identity"
+	"cyber-docker/pkg/rbac"
+	"cyber-docker/pkg/utils"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// apiTokenPrefix 用于区分 API 令牌和 JWT，完整格式为 cdt_<id>_<secret>
+const apiTokenPrefix = "cdt_"
+
+var errInvalidAPIToken = errors.New("invalid api token")
+
+type APITokens struct {
+	APITokens repo.APITokenRepository
+}
+
+// apiTokenInfo 是返回给客户端的令牌信息，Token 只在创建时返回一次
+type apiTokenInfo struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Username   string     `json:"username"`
+	Scopes     []string   `json:"scopes"`
+	Token      string     `json:"token,omitempty"`
+	Expired    bool       `json:"expired"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func redactAPIToken(token entity.APIToken) apiTokenInfo {
+	return apiTokenInfo{
+		ID:         token.ID,
+		Name:       token.Name,
+		Username:   token.Username,
+		Scopes:     token.Scopes,
+		Expired:    token.Expired(time.Now()),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		LastUsedIP: token.LastUsedIP,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseAPIToken 拆分出令牌 ID 和密钥，不是 API 令牌格式时 ok 为 false
+func parseAPIToken(raw string) (id, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(raw, apiTokenPrefix)
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, "_")
+}
+
+// verifyAPIToken 校验令牌的密钥和有效期
+func verifyAPIToken(tokens repo.APITokenRepository, raw string) (entity.APIToken, error) {
+	id, secret, ok := parseAPIToken(raw)
+	if !ok || id == "" || secret == "" {
+		return entity.APIToken{}, errInvalidAPIToken
+	}
+	token, err := tokens.Get(id)
+	if errors.Is(err, repo.ErrAPITokenNotFound) {
+		return token, errInvalidAPIToken
+	}
+	if err != nil {
+		return token, err
+	}
+	if subtle.ConstantTimeCompare([]byte(token.Hash), []byte(hashAPIToken(secret))) != 1 {
+		return token, errInvalidAPIToken
+	}
+	if token.Expired(time.Now()) {
+		return token, errors.New("api token expired")
+	}
+	return token, nil
+}
+
+// List 列出自己的令牌，拥有 users:manage 权限时可以用 username 查询其他用户的令牌，username=* 查询所有用户
+func (a *APITokens) List(c *gin.Context) {
+	id := identity.FromContext(c)
+	username := c.DefaultQuery("username", id.Username)
+	if username != id.Username && !rbac.Granted(id, rbac.UsersManage) {
+		utils.ResError(c, http.StatusForbidden, "permission denied: "+string(rbac.UsersManage))
+		return
+	}
+	if username == "*" {
+		username = ""
+	}
+	tokens, err := a.APITokens.List(username)
+	if err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+	list := make([]apiTokenInfo, 0, len(tokens))
+	for _, item := range tokens {
+		list = append(list, redactAPIToken(item))
+	}
+	utils.ResSuccess(c, list)
+}
+
+// Create 为自己创建令牌，令牌明文只在响应中返回一次
+func (a *APITokens) Create(c *gin.Context) {
+	var params dto.APITokenCreateDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		utils.ResFail(c, utils.NewError(http.StatusBadRequest, err))
+		return
+	}
+	for _, item := range params.Scopes {
+		if !rbac.Valid(rbac.Permission(item)) {
+			utils.ResError(c, http.StatusBadRequest, "unknown scope: "+item)
+			return
+		}
+	}
+	now := time.Now()
+	secret := randomID() + randomID()
+	token := entity.APIToken{
+		ID:        randomID()[:16],
+		Name:      params.Name,
+		Username:  identity.FromContext(c).Username,
+		Hash:      hashAPIToken(secret),
+		Scopes:    params.Scopes,
+		ExpiresAt: now.AddDate(0, 0, params.ExpiresInDays),
+		CreatedAt: now,
+	}
+	if err = a.APITokens.Save(token); err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	info := redactAPIToken(token)
+	info.Token = apiTokenPrefix + token.ID + "_" + secret
+	utils.ResSuccess(c, info)
+}
+
+// Delete 吊销令牌，拥有 users:manage 权限时可以吊销其他用户的令牌
+func (a *APITokens) Delete(c *gin.Context) {
+	id := identity.FromContext(c)
+	token, err := a.APITokens.Get(c.Param("id"))
+	if err == nil && token.Username != id.Username && !rbac.Granted(id, rbac.UsersManage) {
+		// 不暴露其他用户的令牌是否存在
+		err = repo.ErrAPITokenNotFound
+	}
+	if err == nil {
+		err = a.APITokens.Delete(token.ID)
+	}
+	if errors.Is(err, repo.ErrAPITokenNotFound) {
+		utils.ResError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.ResOK(c)
+}
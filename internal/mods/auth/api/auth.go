@@ -0,0 +1,291 @@
+package api
+
+import (
+	"crypto/rand"
+	"cyber-docker/internal/config"
+	"cyber-docker/internal/mods/auth/entity"
+	"cyber-docker/internal/mods/auth/entity/dto"
+	"cyber-docker/internal/mods/auth/repo"
+	"cyber-docker/pkg/identity"
+	"cyber-docker/pkg/rbac"
+	"cyber-docker/pkg/utils"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	errInvalidCredentials = errors.New("invalid username or password")
+	errAccountDisabled    = errors.New("account is disabled")
+	errAccountLocked      = errors.New("account is locked")
+
+	// dummyHash 用户不存在时同样执行一次 bcrypt 比较，避免通过响应时间判断用户是否存在
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+type Auth struct {
+	Config    *config.Config
+	Tokens    *Tokens
+	Users     repo.UserRepository
+	Sessions  repo.SessionRepository
+	APITokens repo.APITokenRepository
+}
+
+// userInfo 是返回给客户端的账号信息，不包含密码
+type userInfo struct {
+	Username           string            `json:"username"`
+	Role               string            `json:"role"`
+	Labels             map[string]string `json:"labels,omitempty"`
+	Permissions        []rbac.Permission `json:"permissions"`
+	Disabled           bool              `json:"disabled"`
+	Locale             string            `json:"locale,omitempty"`
+	MustChangePassword bool              `json:"must_change_password"`
+	LockedUntil        *time.Time        `json:"locked_until,omitempty"`
+	LastLoginAt        *time.Time        `json:"last_login_at,omitempty"`
+	PasswordChangedAt  time.Time         `json:"password_changed_at"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+}
+
+func redactUser(user entity.User) userInfo {
+	info := userInfo{
+		Username:           user.Username,
+		Role:               user.Role,
+		Labels:             user.Labels,
+		Permissions:        rbac.Permissions(user.Role),
+		Disabled:           user.Disabled,
+		Locale:             user.Locale,
+		MustChangePassword: user.MustChangePassword,
+		LastLoginAt:        user.LastLoginAt,
+		PasswordChangedAt:  user.PasswordChangedAt,
+		CreatedAt:          user.CreatedAt,
+		UpdatedAt:          user.UpdatedAt,
+	}
+	if user.Locked(time.Now()) {
+		info.LockedUntil = user.LockedUntil
+	}
+	return info
+}
+
+// Login 校验用户名和密码，连续失败达到 auth.max_login_attempts 次后锁定账号
+func (a *Auth) Login(c *gin.Context) {
+	var params dto.LoginDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		utils.ResFail(c, utils.NewError(http.StatusBadRequest, err))
+		return
+	}
+	now := time.Now()
+	// failure 为密码错误等需要保存失败次数的登录失败，保存后返回给客户端
+	var failure error
+	user, err := a.Users.Update(params.Username, func(user *entity.User) error {
+		if user.Locked(now) {
+			return fmt.Errorf("%w until %s", errAccountLocked, user.LockedUntil.Format(time.RFC3339))
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(params.Password)) != nil {
+			failure = errInvalidCredentials
+			user.FailedAttempts++
+			if user.FailedAttempts >= a.Config.Auth.MaxLoginAttempts {
+				until := now.Add(time.Duration(a.Config.Auth.LockoutDuration) * time.Second)
+				user.LockedUntil = &until
+				user.FailedAttempts = 0
+				failure = fmt.Errorf("too many failed attempts, %w until %s", errAccountLocked, until.Format(time.RFC3339))
+			}
+			return nil
+		}
+		user.FailedAttempts = 0
+		user.LockedUntil = nil
+		if user.Disabled {
+			failure = errAccountDisabled
+			return nil
+		}
+		user.LastLoginAt = &now
+		return nil
+	})
+	if errors.Is(err, repo.ErrUserNotFound) {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+		})
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(params.Password))
+		utils.ResError(c, http.StatusUnauthorized, errInvalidCredentials.Error())
+		return
+	}
+	if errors.Is(err, errAccountLocked) {
+		utils.ResError(c, http.StatusLocked, err.Error())
+		return
+	}
+	if err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	switch {
+	case errors.Is(failure, errInvalidCredentials):
+		utils.ResError(c, http.StatusUnauthorized, failure.Error())
+		return
+	case errors.Is(failure, errAccountDisabled):
+		utils.ResError(c, http.StatusForbidden, failure.Error())
+		return
+	case errors.Is(failure, errAccountLocked):
+		utils.ResError(c, http.StatusLocked, failure.Error())
+		return
+	}
+
+	session := entity.Session{
+		ID:        randomID(),
+		Username:  user.Username,
+		RefreshID: randomID(),
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.Tokens.refreshTTL),
+	}
+	// 登录时顺便清理已过期的会话
+	if err = a.Sessions.DeleteExpired(); err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	a.issue(c, user, session, now)
+}
+
+// Refresh 使用刷新令牌换取新的令牌，刷新令牌只能使用一次，重复使用时会话作废
+func (a *Auth) Refresh(c *gin.Context) {
+	var params dto.RefreshDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		utils.ResFail(c, utils.NewError(http.StatusBadRequest, err))
+		return
+	}
+	claims, err := a.Tokens.Parse(params.RefreshToken, tokenRefresh)
+	if err != nil {
+		utils.ResError(c, http.StatusUnauthorized, "invalid refresh token: "+err.Error())
+		return
+	}
+	session, err := a.Sessions.Get(claims.SessionID)
+	if err != nil {
+		utils.ResError(c, http.StatusUnauthorized, "session expired, please login again")
+		return
+	}
+	if session.RefreshID != claims.ID {
+		_ = a.Sessions.Delete(session.ID)
+		utils.ResError(c, http.StatusUnauthorized, "refresh token has already been used, please login again")
+		return
+	}
+	user, err := a.Users.Get(session.Username)
+	if err != nil || user.Disabled {
+		_ = a.Sessions.Delete(session.ID)
+		utils.ResError(c, http.StatusUnauthorized, "session expired, please login again")
+		return
+	}
+	now := time.Now()
+	session.RefreshID = randomID()
+	session.ExpiresAt = now.Add(a.Tokens.refreshTTL)
+	a.issue(c, user, session, now)
+}
+
+func (a *Auth) issue(c *gin.Context, user entity.User, session entity.Session, now time.Time) {
+	tokens, err := a.Tokens.Issue(user.Username, session.ID, session.RefreshID, now)
+	if err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err = a.Sessions.Save(session); err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	tokens.MustChangePassword = user.MustChangePassword
+	utils.ResSuccess(c, tokens)
+}
+
+// Logout 作废当前会话，会话的访问令牌和刷新令牌立即失效
+func (a *Auth) Logout(c *gin.Context) {
+	err := a.Sessions.Delete(identity.FromContext(c).SessionID)
+	if err != nil && !errors.Is(err, repo.ErrSessionNotFound) {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.ResOK(c)
+}
+
+func (a *Auth) Me(c *gin.Context) {
+	user, err := a.Users.Get(identity.FromContext(c).Username)
+	if err != nil {
+		resUserError(c, err)
+		return
+	}
+	utils.ResSuccess(c, redactUser(user))
+}
+
+// ChangePassword 修改自己的密码，成功后其他会话全部失效
+func (a *Auth) ChangePassword(c *gin.Context) {
+	var params dto.PasswordChangeDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		utils.ResFail(c, utils.NewError(http.StatusBadRequest, err))
+		return
+	}
+	if params.NewPassword == params.OldPassword {
+		utils.ResError(c, http.StatusBadRequest, "new password must be different from the old one")
+		return
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(params.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	id := identity.FromContext(c)
+	_, err = a.Users.Update(id.Username, func(user *entity.User) error {
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(params.OldPassword)) != nil {
+			return errInvalidCredentials
+		}
+		user.PasswordHash = string(hash)
+		user.MustChangePassword = false
+		user.PasswordChangedAt = time.Now()
+		return nil
+	})
+	if errors.Is(err, errInvalidCredentials) {
+		utils.ResError(c, http.StatusBadRequest, "old password is incorrect")
+		return
+	}
+	if err != nil {
+		resUserError(c, err)
+		return
+	}
+	if err = a.Sessions.DeleteByUser(id.Username, id.SessionID); err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.ResOK(c)
+}
+
+// UpdatePreferences 修改自己的偏好设置，目前只有响应语言
+func (a *Auth) UpdatePreferences(c *gin.Context) {
+	var params dto.PreferenceUpdateDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		utils.ResFail(c, utils.NewError(http.StatusBadRequest, err))
+		return
+	}
+	user, err := a.Users.Update(identity.FromContext(c).Username, func(user *entity.User) error {
+		user.Locale = params.Locale
+		return nil
+	})
+	if err != nil {
+		resUserError(c, err)
+		return
+	}
+	utils.ResSuccess(c, redactUser(user))
+}
+
+func randomID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"cyber-docker/pkg/i18n"
+	"cyber-docker/pkg/identity"
+	"cyber-docker/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// skippedPaths 不需要登录即可访问的路由
+var skippedPaths = map[string]bool{
+	"/api/v1/health":       true,
+	"/api/v1/auth/login":   true,
+	"/api/v1/auth/refresh": true,
+}
+
+// passwordChangePaths 必须修改密码的用户仍可访问的路由
+var passwordChangePaths = map[string]bool{
+	"/api/v1/auth/me":       true,
+	"/api/v1/auth/logout":   true,
+	"/api/v1/auth/password": true,
+}
+
+// sessionPaths 只能通过登录会话访问的路由，API 令牌不能修改密码或管理令牌
+var sessionPaths = map[string]bool{
+	"/api/v1/auth/logout":     true,
+	"/api/v1/auth/password":   true,
+	"/api/v1/auth/tokens":     true,
+	"/api/v1/auth/tokens/:id": true,
+}
+
+// apiTokenTouchInterval 令牌最后使用时间的最小更新间隔，避免每个请求都写数据库
+const apiTokenTouchInterval = time.Minute
+
+// Authenticate 校验访问令牌或 API 令牌并保存调用者。浏览器的 WebSocket 和 EventSource 无法设置请求头，
+// 因此也接受 access_token 查询参数
+func (a *Auth) Authenticate(c *gin.Context) {
+	if skippedPaths[c.FullPath()] {
+		c.Next()
+		return
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		token = c.Query("access_token")
+	}
+	if token == "" {
+		utils.ResError(c, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	if strings.HasPrefix(token, apiTokenPrefix) {
+		a.authenticateAPIToken(c, token)
+		return
+	}
+	claims, err := a.Tokens.Parse(token, tokenAccess)
+	if err != nil {
+		utils.ResError(c, http.StatusUnauthorized, "invalid access token: "+err.Error())
+		return
+	}
+	if _, err = a.Sessions.Get(claims.SessionID); err != nil {
+		utils.ResError(c, http.StatusUnauthorized, "session expired, please login again")
+		return
+	}
+	user, err := a.Users.Get(claims.Subject)
+	if err != nil || user.Disabled {
+		utils.ResError(c, http.StatusUnauthorized, "session expired, please login again")
+		return
+	}
+	i18n.WithPreference(c, user.Locale)
+	if user.MustChangePassword && !passwordChangePaths[c.FullPath()] {
+		utils.ResError(c, http.StatusForbidden, "password change required")
+		return
+	}
+	identity.WithContext(c, &identity.Identity{
+		Username:  user.Username,
+		SessionID: claims.SessionID,
+		Role:      user.Role,
+		Labels:    user.Labels,
+	})
+	c.Next()
+}
+
+// authenticateAPIToken 使用令牌所属用户当前的角色和标签，每个请求都记录执行操作的令牌
+func (a *Auth) authenticateAPIToken(c *gin.Context, raw string) {
+	token, err := verifyAPIToken(a.APITokens, raw)
+	if err != nil {
+		utils.ResError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	user, err := a.Users.Get(token.Username)
+	if err != nil || user.Disabled {
+		utils.ResError(c, http.StatusUnauthorized, errInvalidAPIToken.Error())
+		return
+	}
+	i18n.WithPreference(c, user.Locale)
+	if user.MustChangePassword {
+		utils.ResError(c, http.StatusForbidden, "password change required")
+		return
+	}
+	if sessionPaths[c.FullPath()] {
+		utils.ResError(c, http.StatusForbidden, "not allowed with an api token")
+		return
+	}
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval || token.LastUsedIP != c.ClientIP() {
+		if err = a.APITokens.Touch(token.ID, now, c.ClientIP()); err != nil {
+			slog.Warn("auth", "touch api token", token.ID, "err", err)
+		}
+	}
+	identity.WithContext(c, &identity.Identity{
+		Username: user.Username,
+		Role:     user.Role,
+		Labels:   user.Labels,
+		TokenID:  token.ID,
+		Scopes:   token.Scopes,
+	})
+	c.Next()
+	slog.Info("auth", "api token", token.ID, "user", user.Username,
+		"method", c.Request.Method, "path", c.Request.URL.Path, "status", c.Writer.Status())
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"cyber-docker/internal/config"
+	"cyber-docker/internal/mods/auth/repo"
+	"cyber-docker/pkg/storage"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+const (
+	tokenIssuer  = "cyber-docker"
+	tokenAccess  = "access"
+	tokenRefresh = "refresh"
+)
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Type      string `json:"typ"`
+	SessionID string `json:"sid"`
+}
+
+type tokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	// ExpiresIn 访问令牌的有效期，单位秒
+	ExpiresIn          int  `json:"expires_in"`
+	MustChangePassword bool `json:"must_change_password"`
+}
+
+// Tokens 签发和校验 HS256 JWT
+type Tokens struct {
+	key        []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewTokens 未配置 auth.jwt_secret 时使用数据库中保存的密钥
+func NewTokens(cfg *config.Config, db *storage.DB) (*Tokens, error) {
+	secret := cfg.Auth.JWTSecret
+	if secret == "" {
+		var err error
+		if secret, err = repo.JWTSecret(db); err != nil {
+			return nil, fmt.Errorf("load jwt secret: %w", err)
+		}
+	}
+	return &Tokens{
+		key:        []byte(secret),
+		accessTTL:  time.Duration(cfg.Auth.AccessTokenTTL) * time.Second,
+		refreshTTL: time.Duration(cfg.Auth.RefreshTokenTTL) * time.Second,
+	}, nil
+}
+
+// Issue 为会话签发访问令牌和刷新令牌，刷新令牌的 ID 为 session.RefreshID
+func (t *Tokens) Issue(username, sessionID, refreshID string, now time.Time) (tokenPair, error) {
+	access, err := t.sign(tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   username,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.accessTTL)),
+		},
+		Type:      tokenAccess,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return tokenPair{}, err
+	}
+	refresh, err := t.sign(tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   username,
+			ID:        refreshID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.refreshTTL)),
+		},
+		Type:      tokenRefresh,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return tokenPair{}, err
+	}
+	return tokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(t.accessTTL / time.Second),
+	}, nil
+}
+
+func (t *Tokens) sign(claims tokenClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.key)
+}
+
+// Parse 校验签名、有效期和令牌类型
+func (t *Tokens) Parse(token, typ string) (*tokenClaims, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return t.key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(tokenIssuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != typ || claims.Subject == "" || claims.SessionID == "" {
+		return nil, errors.New("invalid token type")
+	}
+	return &claims, nil
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"cyber-docker/internal/mods/auth/entity"
+	"cyber-docker/internal/mods/auth/entity/dto"
+	"cyber-docker/internal/mods/auth/repo"
+	"cyber-docker/pkg/identity"
+	"cyber-docker/pkg/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"time"
+)
+
+type Users struct {
+	Users     repo.UserRepository
+	Sessions  repo.SessionRepository
+	APITokens repo.APITokenRepository
+}
+
+func (a *Users) List(c *gin.Context) {
+	users, err := a.Users.List()
+	if err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	list := make([]userInfo, 0, len(users))
+	for _, item := range users {
+		list = append(list, redactUser(item))
+	}
+	utils.ResSuccess(c, list)
+}
+
+func (a *Users) Inspect(c *gin.Context) {
+	user, err := a.Users.Get(c.Param("username"))
+	if err != nil {
+		resUserError(c, err)
+		return
+	}
+	utils.ResSuccess(c, redactUser(user))
+}
+
+// Create 创建的用户首次登录后必须修改密码
+func (a *Users) Create(c *gin.Context) {
+	var params dto.UserCreateDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		utils.ResFail(c, utils.NewError(http.StatusBadRequest, err))
+		return
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost)
+	if err != nil {
+		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	now := time.Now()
+	user := entity.User{
+		Username:           params.Username,
+		PasswordHash:       string(hash),
+		Role:               params.Role,
+		Labels:             params.Labels,
+		MustChangePassword: true,
+		PasswordChangedAt:  now,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if err = a.Users.Create(user); err != nil {
+		resUserError(c, err)
+		return
+	}
+	utils.ResSuccess(c, redactUser(user))
+}
+
+// Update 重置密码或禁用账号后该用户的所有会话失效
+func (a *Users) Update(c *gin.Context) {
+	var params dto.UserUpdateDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		utils.ResFail(c, utils.NewError(http.StatusBadRequest, err))
+		return
+	}
+	username := c.Param("username")
+	if username == identity.FromContext(c).Username {
+		// 避免管理员把自己锁在外面
+		if params.Disabled != nil && *params.Disabled {
+			utils.ResError(c, http.StatusBadRequest, "cannot disable yourself")
+			return
+		}
+		if params.Role != "" || params.Labels != nil {
+			utils.ResError(c, http.StatusBadRequest, "cannot change your own role or labels")
+			return
+		}
+	}
+	var hash []byte
+	if params.Password != "" {
+		if hash, err = bcrypt.GenerateFromPassword([]byte(params.Password), bcrypt.DefaultCost); err != nil {
+			utils.ResError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	user, err := a.Users.Update(username, func(user *entity.User) error {
+		if hash != nil {
+			user.PasswordHash = string(hash)
+			user.MustChangePassword = true
+			user.PasswordChangedAt = time.Now()
+		}
+		if params.Disabled != nil {
+			user.Disabled = *params.Disabled
+		}
+		if params.Role != "" {
+			user.Role = params.Role
+		}
+		if params.Labels != nil {
+			user.Labels = params.Labels
+		}
+		if params.Unlock {
+			user.LockedUntil = nil
+			user.FailedAttempts = 0
+		}
+		return nil
+	})
+	if err != nil {
+		resUserError(c, err)
+		return
+	}
+	if hash != nil || user.Disabled {
+		if err = a.Sessions.DeleteByUser(username, ""); err != nil {
+			utils.ResError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	utils.ResSuccess(c, redactUser(user))
+}
+
+func (a *Users) Delete(c *gin.Context) {
+	username := c.Param("username")
+	if username == identity.FromContext(c).Username {
+		utils.ResError(c, http.StatusBadRequest, "cannot delete yourself")
+		return
+	}
+	if err := a.Users.Delete(username); err != nil {
+		resUserError(c, err)
+		return
+	}
+	if err := a.Sessions.DeleteByUser(username, ""); err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := a.APITokens.DeleteByUser(username); err != nil {
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.ResOK(c)
+}
+
+func resUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repo.ErrUserNotFound):
+		utils.ResError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, repo.ErrUserExists):
+		utils.ResError(c, http.StatusConflict, err.Error())
+	default:
+		utils.ResError(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,218 @@
+package auth_test
+
+import (
+	"cyber-docker/internal/config"
+	authmod "cyber-docker/internal/mods/auth"
+	"cyber-docker/internal/mods/auth/api"
+	"cyber-docker/internal/mods/auth/entity"
+	"cyber-docker/internal/mods/auth/repo"
+	"cyber-docker/pkg/i18n"
+	"cyber-docker/pkg/storage"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const adminPassword = "admin-password"
+
+type testServer struct {
+	t      *testing.T
+	engine *gin.Engine
+	users  repo.UserRepository
+}
+
+// newTestServer 创建只有 auth 模块的服务，3 次登录失败后锁定 60 秒
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	i18n.RegisterValidator()
+	db, err := storage.Open(filepath.Join(t.TempDir(), "data.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	cfg := config.Default()
+	cfg.Auth.AdminPassword = adminPassword
+	cfg.Auth.MaxLoginAttempts = 3
+	cfg.Auth.LockoutDuration = 60
+	if err = db.Migrate(repo.Migrations(cfg.Auth)...); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := api.NewTokens(cfg, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{t: t, users: repo.NewUserRepository(db)}
+	sessions := repo.NewSessionRepository(db)
+	apiTokens := repo.NewAPITokenRepository(db)
+	module := &authmod.Auth{
+		AuthApi:     api.Auth{Config: cfg, Tokens: tokens, Users: s.users, Sessions: sessions, APITokens: apiTokens},
+		UserApi:     api.Users{Users: s.users, Sessions: sessions, APITokens: apiTokens},
+		APITokenApi: api.APITokens{APITokens: apiTokens},
+	}
+	s.engine = gin.New()
+	v1 := s.engine.Group("/api/v1")
+	v1.Use(i18n.Middleware(i18n.Default), module.Authenticate)
+	module.RegisterV1Routers(v1)
+	return s
+}
+
+type response struct {
+	Code  int             `json:"code"`
+	Error string          `json:"error"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// do 发送请求，token 不为空时放在 Authorization 请求头中
+func (s *testServer) do(method, path, token, body string) (int, response) {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+	var resp response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		s.t.Fatalf("%s %s: invalid response %q", method, path, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+// expect 要求响应状态为 status，data 不为 nil 时解析响应数据
+func (s *testServer) expect(status int, method, path, token, body string, data interface{}) {
+	s.t.Helper()
+	code, resp := s.do(method, path, token, body)
+	if code != status {
+		s.t.Fatalf("%s %s: status %d, want %d, response %+v", method, path, code, status, resp)
+	}
+	if data != nil {
+		if err := json.Unmarshal(resp.Data, data); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+}
+
+type tokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (s *testServer) login(username, password string) tokenPair {
+	s.t.Helper()
+	var tokens tokenPair
+	s.expect(http.StatusOK, "POST", "/api/v1/auth/login", "", `{"username":"`+username+`","password":"`+password+`"}`, &tokens)
+	return tokens
+}
+
+func TestLoginLockout(t *testing.T) {
+	s := newTestServer(t)
+	wrong := `{"username":"admin","password":"wrong-password"}`
+	s.expect(http.StatusUnauthorized, "POST", "/api/v1/auth/login", "", wrong, nil)
+	s.expect(http.StatusUnauthorized, "POST", "/api/v1/auth/login", "", wrong, nil)
+	code, resp := s.do("POST", "/api/v1/auth/login", "", wrong)
+	if code != http.StatusLocked || resp.Error != "locked" {
+		t.Fatalf("third failure: status %d, response %+v", code, resp)
+	}
+	user, err := s.users.Get("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.LockedUntil == nil || time.Until(*user.LockedUntil) < 50*time.Second {
+		t.Fatalf("locked until = %v", user.LockedUntil)
+	}
+	// 锁定期间正确的密码也不能登录
+	s.expect(http.StatusLocked, "POST", "/api/v1/auth/login", "", `{"username":"admin","password":"`+adminPassword+`"}`, nil)
+
+	// 把锁定时间移到过去，模拟锁定时间已过
+	_, err = s.users.Update("admin", func(user *entity.User) error {
+		past := time.Now().Add(-time.Second)
+		user.LockedUntil = &past
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.login("admin", adminPassword)
+	if user, err = s.users.Get("admin"); err != nil {
+		t.Fatal(err)
+	}
+	if user.FailedAttempts != 0 || user.LockedUntil != nil {
+		t.Fatalf("lockout is not cleared: %+v", user)
+	}
+
+	s.expect(http.StatusUnauthorized, "POST", "/api/v1/auth/login", "", `{"username":"nobody","password":"wrong-password"}`, nil)
+}
+
+func TestRefreshTokenReuse(t *testing.T) {
+	s := newTestServer(t)
+	first := s.login("admin", adminPassword)
+	var second tokenPair
+	s.expect(http.StatusOK, "POST", "/api/v1/auth/refresh", "", `{"refresh_token":"`+first.RefreshToken+`"}`, &second)
+	s.expect(http.StatusOK, "GET", "/api/v1/auth/me", second.AccessToken, "", nil)
+
+	// 已使用的刷新令牌再次使用时整个会话作废，新签发的令牌也失效
+	s.expect(http.StatusUnauthorized, "POST", "/api/v1/auth/refresh", "", `{"refresh_token":"`+first.RefreshToken+`"}`, nil)
+	s.expect(http.StatusUnauthorized, "POST", "/api/v1/auth/refresh", "", `{"refresh_token":"`+second.RefreshToken+`"}`, nil)
+	s.expect(http.StatusUnauthorized, "GET", "/api/v1/auth/me", second.AccessToken, "", nil)
+
+	// 访问令牌不能用来刷新
+	other := s.login("admin", adminPassword)
+	s.expect(http.StatusUnauthorized, "POST", "/api/v1/auth/refresh", "", `{"refresh_token":"`+other.AccessToken+`"}`, nil)
+	s.expect(http.StatusOK, "GET", "/api/v1/auth/me", other.AccessToken, "", nil)
+}
+
+func TestDisabledUser(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.login("admin", adminPassword)
+	s.expect(http.StatusOK, "POST", "/api/v1/users", admin.AccessToken, `{"username":"ops","password":"ops-password","role":"operator"}`, nil)
+	ops := s.login("ops", "ops-password")
+	s.expect(http.StatusOK, "GET", "/api/v1/auth/me", ops.AccessToken, "", nil)
+
+	// 直接修改账号，会话仍然存在时令牌也不能再使用
+	_, err := s.users.Update("ops", func(user *entity.User) error {
+		user.Disabled = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.expect(http.StatusUnauthorized, "GET", "/api/v1/auth/me", ops.AccessToken, "", nil)
+	s.expect(http.StatusUnauthorized, "POST", "/api/v1/auth/refresh", "", `{"refresh_token":"`+ops.RefreshToken+`"}`, nil)
+	s.expect(http.StatusForbidden, "POST", "/api/v1/auth/login", "", `{"username":"ops","password":"ops-password"}`, nil)
+	// 禁用的账号密码错误时仍按密码错误处理
+	s.expect(http.StatusUnauthorized, "POST", "/api/v1/auth/login", "", `{"username":"ops","password":"wrong-password"}`, nil)
+
+	s.expect(http.StatusOK, "PUT", "/api/v1/users/ops", admin.AccessToken, `{"disabled":false}`, nil)
+	ops = s.login("ops", "ops-password")
+	s.expect(http.StatusOK, "PUT", "/api/v1/users/ops", admin.AccessToken, `{"disabled":true}`, nil)
+	s.expect(http.StatusUnauthorized, "GET", "/api/v1/auth/me", ops.AccessToken, "", nil)
+}
+
+func TestAccessTokenQuery(t *testing.T) {
+	s := newTestServer(t)
+	tokens := s.login("admin", adminPassword)
+	var me struct {
+		Username string `json:"username"`
+	}
+	s.expect(http.StatusOK, "GET", "/api/v1/auth/me?access_token="+tokens.AccessToken, "", "", &me)
+	if me.Username != "admin" {
+		t.Fatalf("me = %+v", me)
+	}
+	s.expect(http.StatusUnauthorized, "GET", "/api/v1/auth/me", "", "", nil)
+	s.expect(http.StatusUnauthorized, "GET", "/api/v1/auth/me?access_token=invalid", "", "", nil)
+	s.expect(http.StatusUnauthorized, "GET", "/api/v1/auth/me?access_token="+tokens.RefreshToken, "", "", nil)
+	// 请求头优先于查询参数
+	s.expect(http.StatusUnauthorized, "GET", "/api/v1/auth/me?access_token="+tokens.AccessToken, "invalid", "", nil)
+
+	s.expect(http.StatusOK, "POST", "/api/v1/auth/logout?access_token="+tokens.AccessToken, "", "", nil)
+	s.expect(http.StatusUnauthorized, "GET", "/api/v1/auth/me?access_token="+tokens.AccessToken, "", "", nil)
+}
@@ -0,0 +1,48 @@
+package dto
+
+type LoginDto struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshDto struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// bcrypt 只使用密码的前 72 个字节
+type PasswordChangeDto struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
+// 为空时清除语言设置
+type PreferenceUpdateDto struct {
+	Locale string `json:"locale" binding:"omitempty,oneof=zh-CN en-US"`
+}
+
+type UserCreateDto struct {
+	Username string `json:"username" binding:"required,max=64"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+	Role     string `json:"role" binding:"required,oneof=admin operator read-only"`
+	// 限制只能访问带有这些标签的容器和存储卷，例如 {"team": "payments"}
+	Labels map[string]string `json:"labels"`
+}
+
+type UserUpdateDto struct {
+	// 不为空时重置密码，用户下次登录后必须修改密码
+	Password string `json:"password" binding:"omitempty,min=8,max=72"`
+	Disabled *bool  `json:"disabled"`
+	Role     string `json:"role" binding:"omitempty,oneof=admin operator read-only"`
+	// 为 null 时不修改，为 {} 时取消限制
+	Labels map[string]string `json:"labels"`
+	// 解除登录失败导致的锁定
+	Unlock bool `json:"unlock"`
+}
+
+type APITokenCreateDto struct {
+	Name string `json:"name" binding:"required,max=64"`
+	// 例如 ["containers:read", "containers:start", "images:*"]
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// 有效期，单位天
+	ExpiresInDays int `json:"expires_in_days" binding:"required,min=1,max=3650"`
+}
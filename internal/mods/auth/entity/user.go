@@ -0,0 +1,59 @@
+package entity
+
+import "time"
+
+// User 是管理端的登录账号
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	// Role 为 rbac 中定义的角色，初始管理员为 rbac.RoleAdmin，其他账号创建时必须指定
+	Role string `json:"role"`
+	// Labels 不为空时只能访问带有全部这些标签的容器和存储卷
+	Labels   map[string]string `json:"labels,omitempty"`
+	Disabled bool              `json:"disabled"`
+	// Locale 为用户设置的响应语言，为空时根据 Accept-Language 协商
+	Locale string `json:"locale,omitempty"`
+	// MustChangePassword 为 true 时只能修改密码、查看自己的信息和登出
+	MustChangePassword bool `json:"must_change_password"`
+	// FailedAttempts 连续登录失败的次数，登录成功或锁定时清零
+	FailedAttempts    int        `json:"failed_attempts"`
+	LockedUntil       *time.Time `json:"locked_until,omitempty"`
+	LastLoginAt       *time.Time `json:"last_login_at,omitempty"`
+	PasswordChangedAt time.Time  `json:"password_changed_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (u *User) Locked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// Session 是一次登录，刷新令牌每次使用后轮换，登出或修改密码时删除
+type Session struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	// RefreshID 为当前有效的刷新令牌 ID，旧的刷新令牌再次使用时会话作废
+	RefreshID string    `json:"refresh_id"`
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// APIToken 是供自动化客户端使用的个人令牌，只保存令牌的 SHA-256 摘要
+type APIToken struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Hash     string `json:"hash"`
+	// Scopes 令牌允许的权限，不能超出所属用户的角色
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t *APIToken) Expired(now time.Time) bool {
+	return now.After(t.ExpiresAt)
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"cyber-docker/internal/mods/auth/api"
+	"cyber-docker/pkg/rbac"
+	"github.com/gin-gonic/gin"
+)
+
+type Auth struct {
+	AuthApi     api.Auth
+	UserApi     api.Users
+	APITokenApi api.APITokens
+}
+
+// Authenticate 是 /api/v1 下所有路由的认证中间件
+func (a *Auth) Authenticate(c *gin.Context) {
+	a.AuthApi.Authenticate(c)
+}
+
+func (a *Auth) RegisterV1Routers(v1 *gin.RouterGroup) {
+	auth := v1.Group("/auth")
+	{
+		auth.POST("/login", a.AuthApi.Login)
+		auth.POST("/refresh", a.AuthApi.Refresh)
+		auth.POST("/logout", a.AuthApi.Logout)
+		auth.GET("/me", a.AuthApi.Me)
+		auth.PUT("/password", a.AuthApi.ChangePassword)
+		auth.PUT("/preferences", a.AuthApi.UpdatePreferences)
+		auth.GET("/tokens", a.APITokenApi.List)
+		auth.POST("/tokens", a.APITokenApi.Create)
+		auth.DELETE("/tokens/:id", a.APITokenApi.Delete)
+	}
+
+	users := v1.Group("/users", rbac.Require(rbac.UsersManage))
+	{
+		users.GET("", a.UserApi.List)
+		users.POST("", a.UserApi.Create)
+		users.GET("/:username", a.UserApi.Inspect)
+		users.PUT("/:username", a.UserApi.Update)
+		users.DELETE("/:username", a.UserApi.Delete)
+	}
+}
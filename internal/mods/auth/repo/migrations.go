@@ -0,0 +1,89 @@
+// Package repo 保存账号、登录会话和签名密钥
+package repo
+
+import (
+	"crypto/rand"
+	"cyber-docker/internal/config"
+	"cyber-docker/internal/mods/auth/entity"
+	"cyber-docker/pkg/rbac"
+	"cyber-docker/pkg/storage"
+	"encoding/hex"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"time"
+)
+
+const (
+	settingBucket = "auth"
+	jwtSecretKey  = "jwt_secret"
+)
+
+// Migrations 返回 auth 模块的迁移
+func Migrations(cfg config.Auth) []storage.Migration {
+	return []storage.Migration{
+		{ID: "auth/0001_jwt_secret", Up: createJWTSecret},
+		{ID: "auth/0002_bootstrap_admin", Up: bootstrapAdmin(cfg.AdminUsername, cfg.AdminPassword)},
+	}
+}
+
+func createJWTSecret(tx *storage.Tx) error {
+	return tx.Put(settingBucket, jwtSecretKey, randomHex(32))
+}
+
+// bootstrapAdmin 没有任何账号时创建管理员，未配置密码时生成随机密码并输出到日志
+func bootstrapAdmin(username, password string) func(tx *storage.Tx) error {
+	return func(tx *storage.Tx) error {
+		empty := true
+		err := tx.ForEach(userBucket, func(key string, value []byte) error {
+			empty = false
+			return storage.ErrStop
+		})
+		if err != nil || !empty {
+			return err
+		}
+		generated := password == ""
+		if generated {
+			password = randomHex(8)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		err = createUser(tx, entity.User{
+			Username:           username,
+			PasswordHash:       string(hash),
+			Role:               rbac.RoleAdmin,
+			MustChangePassword: generated,
+			PasswordChangedAt:  now,
+			CreatedAt:          now,
+			UpdatedAt:          now,
+		})
+		if err != nil {
+			return err
+		}
+		if generated {
+			slog.Warn("auth", "initial admin", username, "password", password)
+		} else {
+			slog.Info("auth", "initial admin", username)
+		}
+		return nil
+	}
+}
+
+// JWTSecret 返回首次启动时生成的签名密钥
+func JWTSecret(db *storage.DB) (string, error) {
+	var secret string
+	err := db.View(func(tx *storage.Tx) error {
+		return tx.Get(settingBucket, jwtSecretKey, &secret)
+	})
+	return secret, err
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"cyber-docker/internal/mods/auth/entity"
+	"cyber-docker/pkg/storage"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const sessionBucket = "sessions"
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRepository interface {
+	// Get 过期的会话视为不存在
+	Get(id string) (entity.Session, error)
+	Save(session entity.Session) error
+	Delete(id string) error
+	// DeleteByUser 删除用户除 except 以外的所有会话
+	DeleteByUser(username, except string) error
+	DeleteExpired() error
+}
+
+func NewSessionRepository(db *storage.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+type sessionRepository struct {
+	db *storage.DB
+}
+
+func (r *sessionRepository) Get(id string) (entity.Session, error) {
+	var session entity.Session
+	err := r.db.View(func(tx *storage.Tx) error {
+		return tx.Get(sessionBucket, id, &session)
+	})
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && time.Now().After(session.ExpiresAt)) {
+		return session, ErrSessionNotFound
+	}
+	return session, err
+}
+
+func (r *sessionRepository) Save(session entity.Session) error {
+	return r.db.Update(func(tx *storage.Tx) error {
+		return tx.Put(sessionBucket, session.ID, session)
+	})
+}
+
+func (r *sessionRepository) Delete(id string) error {
+	err := r.db.Update(func(tx *storage.Tx) error {
+		return tx.Delete(sessionBucket, id)
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+func (r *sessionRepository) DeleteByUser(username, except string) error {
+	return r.deleteWhere(func(session entity.Session) bool {
+		return session.Username == username && session.ID != except
+	})
+}
+
+func (r *sessionRepository) DeleteExpired() error {
+	now := time.Now()
+	return r.deleteWhere(func(session entity.Session) bool {
+		return now.After(session.ExpiresAt)
+	})
+}
+
+func (r *sessionRepository) deleteWhere(match func(session entity.Session) bool) error {
+	return r.db.Update(func(tx *storage.Tx) error {
+		var ids []string
+		err := tx.ForEach(sessionBucket, func(key string, value []byte) error {
+			var session entity.Session
+			if err := json.Unmarshal(value, &session); err != nil {
+				return err
+			}
+			if match(session) {
+				ids = append(ids, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err = tx.Delete(sessionBucket, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
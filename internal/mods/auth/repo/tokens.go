@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"cyber-docker/internal/mods/auth/entity"
+	"cyber-docker/pkg/storage"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const apiTokenBucket = "api_tokens"
+
+var ErrAPITokenNotFound = errors.New("api token not found")
+
+type APITokenRepository interface {
+	Get(id string) (entity.APIToken, error)
+	// List username 为空时返回所有用户的令牌
+	List(username string) ([]entity.APIToken, error)
+	Save(token entity.APIToken) error
+	// Touch 记录令牌的最后使用时间和地址
+	Touch(id string, at time.Time, ip string) error
+	Delete(id string) error
+	DeleteByUser(username string) error
+}
+
+func NewAPITokenRepository(db *storage.DB) APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+type apiTokenRepository struct {
+	db *storage.DB
+}
+
+func (r *apiTokenRepository) Get(id string) (entity.APIToken, error) {
+	var token entity.APIToken
+	err := r.db.View(func(tx *storage.Tx) error {
+		return tx.Get(apiTokenBucket, id, &token)
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return token, ErrAPITokenNotFound
+	}
+	return token, err
+}
+
+func (r *apiTokenRepository) List(username string) ([]entity.APIToken, error) {
+	list := make([]entity.APIToken, 0)
+	err := r.db.View(func(tx *storage.Tx) error {
+		return tx.ForEach(apiTokenBucket, func(key string, value []byte) error {
+			var token entity.APIToken
+			if err := json.Unmarshal(value, &token); err != nil {
+				return err
+			}
+			if username == "" || token.Username == username {
+				list = append(list, token)
+			}
+			return nil
+		})
+	})
+	return list, err
+}
+
+func (r *apiTokenRepository) Save(token entity.APIToken) error {
+	return r.db.Update(func(tx *storage.Tx) error {
+		return tx.Put(apiTokenBucket, token.ID, token)
+	})
+}
+
+func (r *apiTokenRepository) Touch(id string, at time.Time, ip string) error {
+	err := r.db.Update(func(tx *storage.Tx) error {
+		var token entity.APIToken
+		if err := tx.Get(apiTokenBucket, id, &token); err != nil {
+			return err
+		}
+		token.LastUsedAt = &at
+		token.LastUsedIP = ip
+		return tx.Put(apiTokenBucket, id, token)
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrAPITokenNotFound
+	}
+	return err
+}
+
+func (r *apiTokenRepository) Delete(id string) error {
+	err := r.db.Update(func(tx *storage.Tx) error {
+		return tx.Delete(apiTokenBucket, id)
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrAPITokenNotFound
+	}
+	return err
+}
+
+func (r *apiTokenRepository) DeleteByUser(username string) error {
+	return r.db.Update(func(tx *storage.Tx) error {
+		var ids []string
+		err := tx.ForEach(apiTokenBucket, func(key string, value []byte) error {
+			var token entity.APIToken
+			if err := json.Unmarshal(value, &token); err != nil {
+				return err
+			}
+			if token.Username == username {
+				ids = append(ids, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err = tx.Delete(apiTokenBucket, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"cyber-docker/internal/mods/auth/entity"
+	"cyber-docker/pkg/storage"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const userBucket = "users"
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("user already exists")
+)
+
+type UserRepository interface {
+	Get(username string) (entity.User, error)
+	List() ([]entity.User, error)
+	Create(user entity.User) error
+	// Update 在同一个事务中读取、修改并保存用户，fn 返回错误时不保存
+	Update(username string, fn func(user *entity.User) error) (entity.User, error)
+	Delete(username string) error
+}
+
+func NewUserRepository(db *storage.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+type userRepository struct {
+	db *storage.DB
+}
+
+func (r *userRepository) Get(username string) (entity.User, error) {
+	var user entity.User
+	err := r.db.View(func(tx *storage.Tx) error {
+		return tx.Get(userBucket, username, &user)
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return user, ErrUserNotFound
+	}
+	return user, err
+}
+
+func (r *userRepository) List() ([]entity.User, error) {
+	list := make([]entity.User, 0)
+	err := r.db.View(func(tx *storage.Tx) error {
+		return tx.ForEach(userBucket, func(key string, value []byte) error {
+			var user entity.User
+			if err := json.Unmarshal(value, &user); err != nil {
+				return err
+			}
+			list = append(list, user)
+			return nil
+		})
+	})
+	return list, err
+}
+
+func (r *userRepository) Create(user entity.User) error {
+	return r.db.Update(func(tx *storage.Tx) error {
+		return createUser(tx, user)
+	})
+}
+
+func createUser(tx *storage.Tx, user entity.User) error {
+	var existing entity.User
+	if err := tx.Get(userBucket, user.Username, &existing); err == nil {
+		return ErrUserExists
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	return tx.Put(userBucket, user.Username, user)
+}
+
+func (r *userRepository) Update(username string, fn func(user *entity.User) error) (entity.User, error) {
+	var user entity.User
+	err := r.db.Update(func(tx *storage.Tx) error {
+		if err := tx.Get(userBucket, username, &user); err != nil {
+			return err
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+		user.UpdatedAt = time.Now()
+		return tx.Put(userBucket, username, user)
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return user, ErrUserNotFound
+	}
+	return user, err
+}
+
+func (r *userRepository) Delete(username string) error {
+	err := r.db.Update(func(tx *storage.Tx) error {
+		return tx.Delete(userBucket, username)
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
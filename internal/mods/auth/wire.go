@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"cyber-docker/internal/mods/auth/api"
+	"cyber-docker/internal/mods/auth/repo"
+	"github.com/google/wire"
+)
+
+var Set = wire.NewSet(
+	wire.Struct(new(Auth), "*"),
+	wire.Struct(new(api.Auth), "*"),
+	wire.Struct(new(api.Users), "*"),
+	wire.Struct(new(api.APITokens), "*"),
+	api.NewTokens,
+	repo.NewUserRepository,
+	repo.NewSessionRepository,
+	repo.NewAPITokenRepository,
+)
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/stream"
+	"fmt"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"strings"
+)
+
+// sdk 返回当前请求所选端点的客户端
+func sdk(c *gin.Context) docker.Engine {
+	return docker.ContextClient(c)
+}
+
+// openStream 打开事件流，客户端断开时调用 cancel 结束对应的 Docker 请求
+func openStream(c *gin.Context, ctx context.Context, cancel context.CancelFunc) (stream.Sender, error) {
+	sender, err := stream.Open(c)
+	if err != nil {
+		return nil, err
+	}
+	// gin.Context 在请求结束后会被复用，必须在启动协程前取得 Done
+	done := sender.Done()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return sender, nil
+}
+
+// parsePlatform 解析 os[/arch[/variant]] 格式的平台，为空时返回 nil
+func parsePlatform(value string) (*ocispec.Platform, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parts := strings.Split(value, "/")
+	if len(parts) > 3 || parts[0] == "" {
+		return nil, fmt.Errorf("invalid platform %q", value)
+	}
+	platform := &ocispec.Platform{OS: parts[0]}
+	if len(parts) > 1 {
+		platform.Architecture = parts[1]
+	}
+	if len(parts) > 2 {
+		platform.Variant = parts[2]
+	}
+	return platform, nil
+}
+
+func formatPlatform(platform ocispec.Platform) string {
+	value := platform.OS + "/" + platform.Architecture
+	if platform.Variant != "" {
+		value += "/" + platform.Variant
+	}
+	return value
+}
+
+// pathParam 返回不能为空的路径参数
+func pathParam(c *gin.Context, name string) (string, error) {
+	value := c.Param(name)
+	if value == "" {
+		return "", errdefs.InvalidParameter(fmt.Errorf("%s is required", name))
+	}
+	return value, nil
+}
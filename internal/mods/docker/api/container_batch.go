@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"cyber-docker/internal/mods/docker/entity/dto"
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/identity"
+	"cyber-docker/pkg/utils"
+	"errors"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"sync"
+)
+
+// defaultBatchConcurrency 批量操作默认同时操作的容器数量
+const defaultBatchConcurrency = 4
+
+type batchItem struct {
+	// Ref 为请求中的 ID 或名称，通过过滤条件选中的容器为空
+	Ref   string `json:"ref,omitempty"`
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	State string `json:"state,omitempty"`
+	// Success 预演时表示容器可以操作
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// Code 为失败时的错误码，与接口响应的 error 字段一致
+	Code string `json:"code,omitempty"`
+}
+
+func (i *batchItem) fail(err error) {
+	i.Success = false
+	i.Error = err.Error()
+	i.Code = utils.CodeOf(utils.StatusOf(err))
+}
+
+type batchResult struct {
+	Action    string      `json:"action"`
+	DryRun    bool        `json:"dry_run"`
+	Total     int         `json:"total"`
+	Succeeded int         `json:"succeeded"`
+	Failed    int         `json:"failed"`
+	Items     []batchItem `json:"items"`
+}
+
+// Batch 对多个容器执行同一个操作，单个容器失败不影响其他容器，结果按目标顺序返回
+func (a *Containers) Batch(c *gin.Context) (interface{}, error) {
+	var params dto.ContainerBatchDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	byFilter := len(params.Labels) > 0 || params.Name != ""
+	switch {
+	case len(params.IDs) == 0 && !byFilter:
+		return nil, errdefs.InvalidParameter(errors.New("ids or a labels/name filter is required"))
+	case len(params.IDs) > 0 && byFilter:
+		return nil, errdefs.InvalidParameter(errors.New("ids and labels/name filter cannot be used together"))
+	case params.Action == "update" && params.RestartPolicy == nil:
+		return nil, errdefs.InvalidParameter(errors.New("restart_policy is required for update"))
+	}
+	if err = checkActionPermissions(c, params.Action); err != nil {
+		return nil, err
+	}
+	if err = checkDeleteVolume(c, params.Action == "delete" && params.DeleteVolume); err != nil {
+		return nil, err
+	}
+
+	var items []batchItem
+	if byFilter {
+		items, err = a.batchFilter(c, params)
+	} else {
+		items = a.batchResolve(c, params.IDs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !params.DryRun {
+		ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+		defer cancel()
+		batchRun(ctx, sdk(c), identity.FromContext(c), items, params)
+	}
+
+	result := batchResult{Action: params.Action, DryRun: params.DryRun, Total: len(items), Items: items}
+	for _, item := range items {
+		if item.Success {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// batchFilter 列表接口已经按调用者的标签范围过滤
+func (a *Containers) batchFilter(c *gin.Context, params dto.ContainerBatchDto) ([]batchItem, error) {
+	args := scopeFilter(c, filters.NewArgs())
+	for key, value := range params.Labels {
+		if value == "" {
+			args.Add("label", key)
+		} else {
+			args.Add("label", key+"="+value)
+		}
+	}
+	if params.Name != "" {
+		args.Add("name", params.Name)
+	}
+	list, err := sdk(c).ContainerList(c, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]batchItem, 0, len(list))
+	for _, item := range list {
+		var name string
+		if len(item.Names) > 0 {
+			name = strings.TrimPrefix(item.Names[0], "/")
+		}
+		items = append(items, batchItem{ID: item.ID, Name: name, State: item.State, Success: true})
+	}
+	return items, nil
+}
+
+// batchResolve 逐个查询请求中的容器，不存在或不在访问范围内的容器直接标记为失败，重复的容器只操作一次
+func (a *Containers) batchResolve(c *gin.Context, refs []string) []batchItem {
+	items := make([]batchItem, 0, len(refs))
+	seen := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		item := batchItem{Ref: ref}
+		info, err := sdk(c).ContainerInspect(c, ref)
+		if err == nil {
+			err = checkContainerScope(c, info.ID)
+		}
+		if err != nil {
+			item.fail(err)
+			items = append(items, item)
+			continue
+		}
+		if seen[info.ID] {
+			continue
+		}
+		seen[info.ID] = true
+		item.ID = info.ID
+		item.Name = strings.TrimPrefix(info.Name, "/")
+		if info.State != nil {
+			item.State = info.State.Status
+		}
+		item.Success = true
+		items = append(items, item)
+	}
+	return items
+}
+
+// batchRun 以有限的并发数操作已解析的容器，结果直接写回 items
+func batchRun(ctx context.Context, engine docker.Engine, caller *identity.Identity, items []batchItem, params dto.ContainerBatchDto) {
+	concurrency := params.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range items {
+		if !items[i].Success {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(item *batchItem) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := batchAction(ctx, engine, caller, item.ID, params); err != nil {
+				item.fail(err)
+			}
+		}(&items[i])
+	}
+	wg.Wait()
+}
+
+func batchAction(ctx context.Context, engine docker.Engine, caller *identity.Identity, id string, params dto.ContainerBatchDto) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	switch params.Action {
+	case "delete":
+		return removeContainer(ctx, engine, caller, id, params.DeleteVolume, params.DeleteLink)
+	case "update":
+		_, err := engine.ContainerUpdate(ctx, id, container.UpdateConfig{
+			RestartPolicy: restartPolicy(params.RestartPolicy),
+		})
+		return err
+	}
+	return lifecycleAction(ctx, engine, id, params.Action, container.StopOptions{Signal: params.Signal, Timeout: params.Timeout})
+}
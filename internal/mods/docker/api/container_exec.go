@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"cyber-docker/internal/mods/docker/entity/dto"
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/stream"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+var defaultShells = []string{"/bin/bash", "/bin/sh"}
+
+// Exec 打开交互终端，必须以 WebSocket 方式请求。
+// 服务端以二进制消息发送终端输出，结束时以文本消息发送 exit 或 error 事件；
+// 客户端发送二进制消息作为输入，或发送 dto.ContainerExecMessage 文本消息
+func (a *Containers) Exec(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
+	}
+	var params dto.ContainerExecDto
+	err = c.ShouldBindQuery(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	if !stream.IsWebSocket(c) {
+		return nil, errdefs.InvalidParameter(errors.New("websocket upgrade required"))
+	}
+
+	ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+	defer cancel()
+	cli := sdk(c)
+	execID, resp, err := startExec(ctx, cli, id, params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+
+	conn, err := stream.Upgrade(c)
+	if err != nil {
+		slog.Debug("container exec", "upgrade", err)
+		return nil, nil
+	}
+	s := &execSession{
+		conn:    conn,
+		cli:     cli,
+		execID:  execID,
+		resp:    resp,
+		timeout: time.Duration(a.Config.Docker.ExecIdleTimeout) * time.Second,
+	}
+	s.run(ctx)
+	return nil, nil
+}
+
+// startExec 依次尝试候选命令，命令不存在（退出码 126/127）时尝试下一个
+func startExec(ctx context.Context, cli docker.Engine, id string, params dto.ContainerExecDto) (string, types.HijackedResponse, error) {
+	commands := params.Cmd
+	if len(commands) == 0 {
+		commands = defaultShells
+	}
+	var consoleSize *[2]uint
+	if params.Rows > 0 && params.Cols > 0 {
+		consoleSize = &[2]uint{params.Rows, params.Cols}
+	}
+	var tried []string
+	for _, command := range commands {
+		cmd := strings.Fields(command)
+		if len(cmd) == 0 {
+			continue
+		}
+		created, err := cli.ContainerExecCreate(ctx, id, container.ExecOptions{
+			User:         params.User,
+			Env:          params.Env,
+			WorkingDir:   params.WorkingDir,
+			Cmd:          cmd,
+			Tty:          true,
+			AttachStdin:  true,
+			AttachStdout: true,
+			AttachStderr: true,
+			ConsoleSize:  consoleSize,
+		})
+		if err != nil {
+			return "", types.HijackedResponse{}, err
+		}
+		resp, err := cli.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{Tty: true, ConsoleSize: consoleSize})
+		if err != nil {
+			return "", types.HijackedResponse{}, err
+		}
+		if !commandNotFound(ctx, cli, created.ID) {
+			return created.ID, resp, nil
+		}
+		resp.Close()
+		tried = append(tried, cmd[0])
+	}
+	return "", types.HijackedResponse{}, errdefs.NotFound(fmt.Errorf("no executable found in container: %s", strings.Join(tried, ", ")))
+}
+
+func commandNotFound(ctx context.Context, cli docker.Engine, execID string) bool {
+	for i := 0; i < 20; i++ {
+		info, err := cli.ContainerExecInspect(ctx, execID)
+		if err != nil || info.Running {
+			return false
+		}
+		if info.ExitCode == 126 || info.ExitCode == 127 {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	return false
+}
+
+type execSession struct {
+	conn    *websocket.Conn
+	cli     docker.Engine
+	execID  string
+	resp    types.HijackedResponse
+	timeout time.Duration
+	mutex   sync.Mutex
+	once    sync.Once
+	idle    *time.Timer
+}
+
+func (s *execSession) run(ctx context.Context) {
+	s.idle = time.AfterFunc(s.timeout, func() {
+		s.close(stream.EventError, "idle timeout")
+	})
+	defer s.idle.Stop()
+	stop := context.AfterFunc(ctx, func() {
+		s.close(stream.EventError, "session closed")
+	})
+	defer stop()
+
+	go s.output()
+	for {
+		kind, buf, err := s.conn.ReadMessage()
+		if err != nil {
+			// 客户端断开，关闭输入让 shell 退出
+			_ = s.resp.CloseWrite()
+			s.close("", nil)
+			return
+		}
+		s.idle.Reset(s.timeout)
+		if kind == websocket.BinaryMessage {
+			// 写入失败说明进程已退出，由 output 发送退出事件
+			_, _ = s.resp.Conn.Write(buf)
+			continue
+		}
+		var msg dto.ContainerExecMessage
+		if err = json.Unmarshal(buf, &msg); err != nil {
+			continue
+		}
+		switch msg.Type {
+		case "input":
+			_, _ = s.resp.Conn.Write([]byte(msg.Data))
+		case "resize":
+			if msg.Rows == 0 || msg.Cols == 0 {
+				continue
+			}
+			err = s.cli.ContainerExecResize(ctx, s.execID, container.ResizeOptions{Height: msg.Rows, Width: msg.Cols})
+			if err != nil {
+				slog.Debug("container exec", "resize", err)
+			}
+		}
+	}
+}
+
+// output 把终端输出转发给客户端，进程退出后发送退出码并关闭连接
+func (s *execSession) output() {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := s.resp.Reader.Read(buf)
+		if n > 0 {
+			s.idle.Reset(s.timeout)
+			s.mutex.Lock()
+			werr := s.conn.WriteMessage(websocket.BinaryMessage, buf[:n])
+			s.mutex.Unlock()
+			if werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	info, err := s.cli.ContainerExecInspect(context.Background(), s.execID)
+	if err != nil {
+		s.close(stream.EventError, err.Error())
+		return
+	}
+	s.close("exit", gin.H{"exit_code": info.ExitCode})
+}
+
+// close 发送结束事件后关闭 WebSocket 和终端连接，只执行一次
+func (s *execSession) close(event string, data interface{}) {
+	s.once.Do(func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if event != "" {
+			_ = s.conn.WriteJSON(stream.Event{Event: event, Data: data})
+		}
+		_ = s.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		_ = s.conn.Close()
+		s.resp.Close()
+	})
+}
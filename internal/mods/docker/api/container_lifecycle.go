@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"cyber-docker/internal/mods/docker/entity/dto"
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/identity"
+	"cyber-docker/pkg/rbac"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"time"
+)
+
+// defaultWaitTimeout wait 未指定超时时间时最长等待的时间
+const defaultWaitTimeout = 30 * time.Second
+
+// actionPermissions 每个生命周期操作和批量操作需要的权限
+var actionPermissions = map[string][]rbac.Permission{
+	"start":   {rbac.ContainersStart},
+	"unpause": {rbac.ContainersStart},
+	"stop":    {rbac.ContainersStop},
+	"pause":   {rbac.ContainersStop},
+	"kill":    {rbac.ContainersStop},
+	"restart": {rbac.ContainersStart, rbac.ContainersStop},
+	"wait":    {rbac.ContainersRead},
+	"delete":  {rbac.ContainersDelete},
+	"update":  {rbac.ContainersUpdate},
+}
+
+func checkActionPermissions(c *gin.Context, action string) error {
+	caller := identity.FromContext(c)
+	for _, p := range actionPermissions[action] {
+		if !rbac.Granted(caller, p) {
+			return errdefs.Forbidden(fmt.Errorf("permission denied: %s", p))
+		}
+	}
+	return nil
+}
+
+// checkDeleteVolume 删除容器时同时删除存储卷还需要删除存储卷的权限
+func checkDeleteVolume(c *gin.Context, deleteVolume bool) error {
+	if deleteVolume && !rbac.Granted(identity.FromContext(c), rbac.VolumesDelete) {
+		return errdefs.Forbidden(fmt.Errorf("permission denied: %s", rbac.VolumesDelete))
+	}
+	return nil
+}
+
+type containerActionResult struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	// State 为操作完成后重新查询的容器状态，容器已被删除时为空
+	State   *container.State `json:"state,omitempty"`
+	Removed bool             `json:"removed,omitempty"`
+	// ExitCode 只有 wait 返回
+	ExitCode *int64 `json:"exit_code,omitempty"`
+}
+
+// Action 执行容器生命周期操作，完成后返回容器的最新状态
+func (a *Containers) Action(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
+	}
+	var params dto.ContainerActionDto
+	if err = c.ShouldBindJSON(&params); err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	if err = checkActionPermissions(c, params.Action); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+	defer cancel()
+	result := containerActionResult{ID: id, Action: params.Action}
+	if params.Action == "wait" {
+		result.ExitCode, err = wait(ctx, c, id, params)
+	} else {
+		err = lifecycleAction(ctx, sdk(c), id, params.Action, container.StopOptions{Signal: params.Signal, Timeout: params.Timeout})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := sdk(c).ContainerInspect(c, id)
+	switch {
+	case errdefs.IsNotFound(err):
+		result.Removed = true
+	case err != nil:
+		// 操作已经成功，查询状态失败不影响结果
+		slog.Warn("docker", "inspect after "+params.Action, id, "err", err)
+	default:
+		result.ID = info.ID
+		result.State = info.State
+	}
+	return result, nil
+}
+
+// lifecycleAction 执行 wait 以外的生命周期操作，options.Signal 同时用于 kill
+func lifecycleAction(ctx context.Context, engine docker.Engine, id, action string, options container.StopOptions) error {
+	switch action {
+	case "start":
+		return engine.ContainerStart(ctx, id, container.StartOptions{})
+	case "stop":
+		return engine.ContainerStop(ctx, id, options)
+	case "restart":
+		return engine.ContainerRestart(ctx, id, options)
+	case "pause":
+		return engine.ContainerPause(ctx, id)
+	case "unpause":
+		return engine.ContainerUnpause(ctx, id)
+	case "kill":
+		return engine.ContainerKill(ctx, id, options.Signal)
+	}
+	return errdefs.InvalidParameter(fmt.Errorf("unsupported action: %s", action))
+}
+
+// wait 超时返回 504，容器以非零状态退出不算错误
+func wait(ctx context.Context, c *gin.Context, id string, params dto.ContainerActionDto) (*int64, error) {
+	timeout := defaultWaitTimeout
+	if params.Timeout != nil {
+		timeout = time.Duration(*params.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	condition := container.WaitConditionNotRunning
+	if params.Condition != "" {
+		condition = container.WaitCondition(params.Condition)
+	}
+	resultC, errC := sdk(c).ContainerWait(ctx, id, condition)
+	select {
+	case res := <-resultC:
+		if res.Error != nil && res.Error.Message != "" {
+			return nil, fmt.Errorf("wait container: %s", res.Error.Message)
+		}
+		return &res.StatusCode, nil
+	case err := <-errC:
+		return nil, err
+	}
+}
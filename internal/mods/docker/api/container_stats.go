@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"cyber-docker/internal/mods/docker/entity/dto"
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/stream"
+	"encoding/json"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultStatsInterval = 2
+
+type containerStats struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	docker.Stats
+}
+
+type statsSnapshot struct {
+	Time       time.Time        `json:"time"`
+	Containers []containerStats `json:"containers"`
+}
+
+// StatsAll 订阅运行中容器的统计数据，每隔 interval 秒推送一次 stats 事件，包含所有已有数据的容器。
+// 每次推送前重新列出容器，新启动的容器自动加入，停止的容器自动移除
+func (a *Containers) StatsAll(c *gin.Context) (interface{}, error) {
+	var params dto.ContainerStatsDto
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	interval := params.Interval
+	if interval == 0 {
+		interval = defaultStatsInterval
+	}
+	cli := sdk(c)
+	options := container.ListOptions{Filters: statsFilter(c, params)}
+	// 打开流之前先列出一次，端点不可用等错误直接返回
+	list, err := cli.ContainerList(c, options)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+	defer cancel()
+	sender, err := openStream(c, ctx, cancel)
+	if err != nil {
+		slog.Debug("container stats", "upgrade", err)
+		return nil, nil
+	}
+	defer func() {
+		_ = sender.Close()
+	}()
+
+	aggregator := newStatsAggregator(ctx, cli)
+	defer aggregator.close()
+	aggregator.sync(list)
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = sender.Send(stream.EventDone, nil)
+			return nil, nil
+		case <-ticker.C:
+		}
+		list, err = cli.ContainerList(ctx, options)
+		if err != nil {
+			if ctx.Err() == nil {
+				_ = sender.Send(stream.EventError, err.Error())
+			}
+			return nil, nil
+		}
+		aggregator.sync(list)
+		if err = sender.Send("stats", aggregator.snapshot()); err != nil {
+			return nil, nil
+		}
+	}
+}
+
+func statsFilter(c *gin.Context, params dto.ContainerStatsDto) filters.Args {
+	args := scopeFilter(c, filters.NewArgs())
+	for _, id := range params.IDs {
+		args.Add("id", id)
+	}
+	for _, label := range params.Label {
+		args.Add("label", label)
+	}
+	if params.Name != "" {
+		args.Add("name", params.Name)
+	}
+	return args
+}
+
+// statsAggregator 为每个容器维持一个统计数据流，只保留最新计算出的指标
+type statsAggregator struct {
+	ctx      context.Context
+	cli      docker.Engine
+	mutex    sync.Mutex
+	wg       sync.WaitGroup
+	watchers map[string]*statsWatcher
+}
+
+type statsWatcher struct {
+	name   string
+	cancel context.CancelFunc
+	// 收到第一条数据之前为 nil
+	stats *docker.Stats
+}
+
+func newStatsAggregator(ctx context.Context, cli docker.Engine) *statsAggregator {
+	return &statsAggregator{ctx: ctx, cli: cli, watchers: make(map[string]*statsWatcher)}
+}
+
+// sync 为新出现的容器打开数据流，关闭已不在列表中的容器的数据流
+func (a *statsAggregator) sync(list []container.Summary) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	running := make(map[string]struct{}, len(list))
+	for _, item := range list {
+		running[item.ID] = struct{}{}
+		var name string
+		if len(item.Names) > 0 {
+			name = strings.TrimPrefix(item.Names[0], "/")
+		}
+		if w, ok := a.watchers[item.ID]; ok {
+			w.name = name
+			continue
+		}
+		ctx, cancel := context.WithCancel(a.ctx)
+		w := &statsWatcher{name: name, cancel: cancel}
+		a.watchers[item.ID] = w
+		a.wg.Add(1)
+		go a.watch(ctx, item.ID, w)
+	}
+	for id, w := range a.watchers {
+		if _, ok := running[id]; !ok {
+			w.cancel()
+			delete(a.watchers, id)
+		}
+	}
+}
+
+// watch 数据流结束时移除自身，容器仍在运行时下次 sync 会重新打开
+func (a *statsAggregator) watch(ctx context.Context, id string, w *statsWatcher) {
+	defer a.wg.Done()
+	defer func() {
+		w.cancel()
+		a.mutex.Lock()
+		if a.watchers[id] == w {
+			delete(a.watchers, id)
+		}
+		a.mutex.Unlock()
+	}()
+	response, err := a.cli.ContainerStats(ctx, id, true)
+	if err != nil {
+		slog.Debug("container stats", "id", id, "err", err)
+		return
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	decoder := json.NewDecoder(response.Body)
+	var previous *container.StatsResponse
+	for {
+		var current container.StatsResponse
+		if err := decoder.Decode(&current); err != nil {
+			return
+		}
+		stats := docker.CalculateStats(previous, &current, response.OSType)
+		a.mutex.Lock()
+		w.stats = &stats
+		a.mutex.Unlock()
+		previous = &current
+	}
+}
+
+func (a *statsAggregator) snapshot() statsSnapshot {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	result := statsSnapshot{Time: time.Now(), Containers: make([]containerStats, 0, len(a.watchers))}
+	for id, w := range a.watchers {
+		if w.stats != nil {
+			result.Containers = append(result.Containers, containerStats{ID: id, Name: w.name, Stats: *w.stats})
+		}
+	}
+	sort.Slice(result.Containers, func(i, j int) bool {
+		return result.Containers[i].Name < result.Containers[j].Name
+	})
+	return result
+}
+
+func (a *statsAggregator) close() {
+	a.mutex.Lock()
+	for _, w := range a.watchers {
+		w.cancel()
+	}
+	a.mutex.Unlock()
+	a.wg.Wait()
+}
@@ -2,16 +2,32 @@ package api
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
+	"cyber-docker/internal/config"
 	"cyber-docker/internal/mods/docker/entity/dto"
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/identity"
+	"cyber-docker/pkg/lifecycle"
+	"cyber-docker/pkg/query"
+	"cyber-docker/pkg/stream"
 	"cyber-docker/pkg/utils"
+	"errors"
+	"fmt"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
-	"github.com/docker/docker/client"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
 	"github.com/gin-gonic/gin"
 	"io"
 	"log/slog"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 )
 
 var (
@@ -23,218 +39,516 @@ var (
 	}
 )
 
+func restartPolicy(params *dto.ContainerRestartPolicy) container.RestartPolicy {
+	restartPolicy := container.RestartPolicy{}
+	if params.Name != "" {
+		if name, ok := restartPolicyMap[params.Name]; ok {
+			restartPolicy.Name = name
+		} else {
+			restartPolicy.Name = container.RestartPolicyDisabled
+		}
+	}
+	if restartPolicy.Name == container.RestartPolicyOnFailure {
+		restartPolicy.MaximumRetryCount = 5
+	}
+	if params.MaxAttempt > 0 {
+		restartPolicy.Name = container.RestartPolicyOnFailure
+		restartPolicy.MaximumRetryCount = params.MaxAttempt
+	}
+	return restartPolicy
+}
+
 type Containers struct {
-	SDK *client.Client
+	Config      *config.Config
+	Credentials *docker.CredentialStore
+	Lifecycle   *lifecycle.Manager
 }
 
-func (a *Containers) List(c *gin.Context) {
-	var params dto.ContainerDto
-	err := c.ShouldBind(&params)
+// containerFields 容器名称取第一个名称，搜索同时匹配镜像
+var containerFields = query.Fields[container.Summary]{
+	Name: func(item container.Summary) string {
+		if len(item.Names) == 0 {
+			return ""
+		}
+		return strings.TrimPrefix(item.Names[0], "/")
+	},
+	Created: func(item container.Summary) int64 { return item.Created },
+	Size:    func(item container.Summary) int64 { return item.SizeRw },
+	State:   func(item container.Summary) string { return item.State },
+	Labels:  func(item container.Summary) map[string]string { return item.Labels },
+	Text:    func(item container.Summary) []string { return []string{item.Image, item.ID} },
+}
+
+func (a *Containers) List(c *gin.Context) (interface{}, error) {
+	var params dto.ContainerListDto
+	err := c.ShouldBindQuery(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
 
-	filter := filters.NewArgs()
+	filter := scopeFilter(c, filters.NewArgs())
 	if params.Sha != "" {
 		filter.Add("id", params.Sha)
 	}
 
-	imageList, err := a.SDK.ContainerList(c, container.ListOptions{
-		All:     true,
-		Latest:  true,
+	containerList, err := sdk(c).ContainerList(c, container.ListOptions{
+		All: true,
+		// 只有按大小排序时才计算可写层大小，计算比较耗时
+		Size:    params.SortBy == query.SortSize,
 		Filters: filter,
 	})
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
+	}
+	page, total, err := query.Apply(containerList, params.Params, containerFields)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
 	}
-	utils.ResSuccess(c, imageList)
+	return utils.Page{Total: total, Data: page}, nil
 }
 
-func (a *Containers) Inspect(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+func (a *Containers) Create(c *gin.Context) (interface{}, error) {
+	var params dto.ContainerCreateDto
+	err := c.ShouldBind(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
 	}
-	detail, err := a.SDK.ContainerInspect(c, id)
+	config, hostConfig, networkingConfig, err := containerCreateConfig(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, errdefs.InvalidParameter(err)
+	}
+	if err = checkCreateScope(c, hostConfig); err != nil {
+		return nil, err
+	}
+	config.Labels = scopeLabels(c, config.Labels)
+	platform, err := parsePlatform(params.Platform)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
 	}
-	utils.ResSuccess(c, detail)
-}
 
-func (a *Containers) Start(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+	pull := params.Pull == "always"
+	if params.Pull == "" || params.Pull == "missing" {
+		_, err = sdk(c).ImageInspect(c, params.Image)
+		if err != nil && !errdefs.IsNotFound(err) {
+			return nil, err
+		}
+		pull = err != nil
 	}
-	err := a.SDK.ContainerStart(c, id, container.StartOptions{})
+	if pull {
+		err = pullImage(c, sdk(c), a.Config, a.Credentials, params.Image, params.Platform)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := sdk(c).ContainerCreate(c, config, hostConfig, networkingConfig, platform, params.Name)
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
-	utils.ResOK(c)
+	started := false
+	if params.Start {
+		err = sdk(c).ContainerStart(c, response.ID, container.StartOptions{})
+		if err != nil {
+			// 容器已创建，启动失败时返回容器 ID 便于排查
+			return nil, fmt.Errorf("container %s created but failed to start: %w", response.ID, err)
+		}
+		started = true
+	}
+	return gin.H{
+		"id":       response.ID,
+		"warnings": response.Warnings,
+		"pulled":   pull,
+		"started":  started,
+	}, nil
 }
 
-func (a *Containers) Stop(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+func containerCreateConfig(params *dto.ContainerCreateDto) (*container.Config, *container.HostConfig, *network.NetworkingConfig, error) {
+	config := &container.Config{
+		Image:        params.Image,
+		Hostname:     params.Hostname,
+		User:         params.User,
+		WorkingDir:   params.WorkingDir,
+		Env:          params.Env,
+		Cmd:          params.Cmd,
+		Entrypoint:   params.Entrypoint,
+		Labels:       params.Labels,
+		ExposedPorts: nat.PortSet{},
+	}
+	hostConfig := &container.HostConfig{
+		PortBindings: nat.PortMap{},
+		CapAdd:       params.CapAdd,
+		CapDrop:      params.CapDrop,
+		Privileged:   params.Privileged,
+	}
+	networkingConfig := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{},
+	}
+
+	for _, item := range params.Ports {
+		protocol := item.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		port, err := nat.NewPort(protocol, item.ContainerPort)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		config.ExposedPorts[port] = struct{}{}
+		if item.HostPort != "" || item.HostIP != "" {
+			hostConfig.PortBindings[port] = append(hostConfig.PortBindings[port], nat.PortBinding{
+				HostIP:   item.HostIP,
+				HostPort: item.HostPort,
+			})
+		}
+	}
+
+	for _, item := range params.Mounts {
+		m := mount.Mount{
+			Type:     mount.Type(item.Type),
+			Source:   item.Source,
+			Target:   item.Target,
+			ReadOnly: item.ReadOnly,
+		}
+		switch m.Type {
+		case mount.TypeBind:
+			if !strings.HasPrefix(item.Source, "/") {
+				return nil, nil, nil, fmt.Errorf("bind mount source must be an absolute path: %q", item.Source)
+			}
+		case mount.TypeTmpfs:
+			if item.Source != "" {
+				return nil, nil, nil, fmt.Errorf("tmpfs mount does not accept a source: %q", item.Source)
+			}
+			m.TmpfsOptions = &mount.TmpfsOptions{
+				SizeBytes: item.TmpfsSize,
+				Mode:      os.FileMode(item.TmpfsMode),
+			}
+		}
+		hostConfig.Mounts = append(hostConfig.Mounts, m)
 	}
-	err := a.SDK.ContainerStop(c, id, container.StopOptions{})
+
+	for i, item := range params.Networks {
+		settings := &network.EndpointSettings{
+			Aliases: item.Aliases,
+		}
+		if item.IpV4 != "" || item.IpV6 != "" {
+			settings.IPAMConfig = &network.EndpointIPAMConfig{
+				IPv4Address: item.IpV4,
+				IPv6Address: item.IpV6,
+			}
+		}
+		// 第一个网络作为容器的主网络
+		if i == 0 {
+			hostConfig.NetworkMode = container.NetworkMode(item.Name)
+		}
+		networkingConfig.EndpointsConfig[item.Name] = settings
+	}
+
+	if params.RestartPolicy != nil {
+		hostConfig.RestartPolicy = restartPolicy(params.RestartPolicy)
+	}
+	if params.Resources != nil {
+		hostConfig.Resources = container.Resources{
+			NanoCPUs:          int64(params.Resources.Cpus * 1e9),
+			CPUShares:         params.Resources.CpuShares,
+			Memory:            params.Resources.Memory,
+			MemoryReservation: params.Resources.MemoryReservation,
+			MemorySwap:        params.Resources.MemorySwap,
+			PidsLimit:         params.Resources.PidsLimit,
+		}
+	}
+	if params.Healthcheck != nil {
+		config.Healthcheck = &container.HealthConfig{
+			Test:        params.Healthcheck.Test,
+			Interval:    time.Second * time.Duration(params.Healthcheck.Interval),
+			Timeout:     time.Second * time.Duration(params.Healthcheck.Timeout),
+			StartPeriod: time.Second * time.Duration(params.Healthcheck.StartPeriod),
+			Retries:     params.Healthcheck.Retries,
+		}
+	}
+	return config, hostConfig, networkingConfig, nil
+}
+
+func (a *Containers) Inspect(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
-	utils.ResOK(c)
+	return sdk(c).ContainerInspect(c, id)
 }
 
-func (a *Containers) Stat(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+func (a *Containers) Start(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
 	}
+	return nil, sdk(c).ContainerStart(c, id, container.StartOptions{})
+}
 
-	response, err := a.SDK.ContainerStats(c, id, true)
+func (a *Containers) Stop(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
-	io.Copy(c.Writer, response.Body)
+	return nil, sdk(c).ContainerStop(c, id, container.StopOptions{})
 }
 
-func (a *Containers) Top(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+func (a *Containers) Stat(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+	defer cancel()
+	response, err := sdk(c).ContainerStats(ctx, id, true)
+	if err != nil {
+		return nil, err
 	}
-	response, err := a.SDK.ContainerTop(c, id, nil)
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	c.Status(http.StatusOK)
+	_, err = io.Copy(c.Writer, response.Body)
+	return nil, err
+}
+
+func (a *Containers) Top(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
-	utils.ResSuccess(c, response)
+	return sdk(c).ContainerTop(c, id, nil)
 }
 
-func (a *Containers) Update(c *gin.Context) {
+func (a *Containers) Update(c *gin.Context) (interface{}, error) {
 	var params dto.ContainerUpdateDto
 	err := c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
 	}
 	if params.RestartPolicy != nil {
-		restartPolicy := container.RestartPolicy{}
-		if params.RestartPolicy.Name != "" {
-			if name, ok := restartPolicyMap[params.RestartPolicy.Name]; ok {
-				restartPolicy.Name = name
-			} else {
-				restartPolicy.Name = container.RestartPolicyDisabled
-			}
-		}
-		if restartPolicy.Name == container.RestartPolicyOnFailure {
-			restartPolicy.MaximumRetryCount = 5
-		}
-		if params.RestartPolicy.MaxAttempt > 0 {
-			restartPolicy.Name = container.RestartPolicyOnFailure
-			restartPolicy.MaximumRetryCount = params.RestartPolicy.MaxAttempt
-		}
-
-		_, err := a.SDK.ContainerUpdate(c, id, container.UpdateConfig{
-			RestartPolicy: restartPolicy,
+		_, err = sdk(c).ContainerUpdate(c, id, container.UpdateConfig{
+			RestartPolicy: restartPolicy(params.RestartPolicy),
 		})
 		if err != nil {
-			utils.ResError(c, http.StatusInternalServerError, err.Error())
+			return nil, err
 		}
 	}
 	if params.Name != "" {
-		err = a.SDK.ContainerRename(c, id, params.Name)
-		if err != nil {
-			utils.ResError(c, http.StatusInternalServerError, err.Error())
+		if err = sdk(c).ContainerRename(c, id, params.Name); err != nil {
+			return nil, err
 		}
 	}
-	utils.ResOK(c)
+	return nil, nil
 }
 
-func (a *Containers) Prune(c *gin.Context) {
-	info, err := a.SDK.ContainersPrune(c, filters.NewArgs())
-	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
-	}
-	utils.ResSuccess(c, info)
+func (a *Containers) Prune(c *gin.Context) (interface{}, error) {
+	return sdk(c).ContainersPrune(c, scopeFilter(c, filters.NewArgs()))
 }
 
-func (a *Containers) Delete(c *gin.Context) {
+func (a *Containers) Delete(c *gin.Context) (interface{}, error) {
 	var params dto.ContainerDeleteDto
 	err := c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+	if err = checkDeleteVolume(c, params.DeleteVolume); err != nil {
+		return nil, err
 	}
-	containerInfo, err := a.SDK.ContainerInspect(c, id)
+	id, err := pathParam(c, "id")
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
+	return nil, removeContainer(c, sdk(c), identity.FromContext(c), id, params.DeleteVolume, params.DeleteLink)
+}
 
-	err = a.SDK.ContainerStop(c, id, container.StopOptions{})
+// removeContainer 停止并删除容器，deleteVolume 为 true 时同时删除容器使用的命名存储卷，不在 caller 访问范围内的存储卷保留
+func removeContainer(ctx context.Context, engine docker.Engine, caller *identity.Identity, id string, deleteVolume, deleteLink bool) error {
+	containerInfo, err := engine.ContainerInspect(ctx, id)
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	err = a.SDK.ContainerRemove(c, id, container.RemoveOptions{
-		RemoveVolumes: params.DeleteVolume,
-		RemoveLinks:   params.DeleteLink,
+	err = engine.ContainerStop(ctx, id, container.StopOptions{})
+	if err != nil {
+		return err
+	}
+
+	err = engine.ContainerRemove(ctx, id, container.RemoveOptions{
+		RemoveVolumes: deleteVolume,
+		RemoveLinks:   deleteLink,
 	})
+	if err != nil {
+		return err
+	}
 
-	if params.DeleteVolume {
+	if deleteVolume {
 		for _, item := range containerInfo.Mounts {
-			if item.Type == mount.TypeVolume {
-				err = a.SDK.VolumeRemove(c, item.Name, false)
-				if err != nil {
-					slog.Debug("remove container volume", err.Error())
+			if item.Type != mount.TypeVolume {
+				continue
+			}
+			if caller != nil && len(caller.Labels) > 0 {
+				info, err := engine.VolumeInspect(ctx, item.Name)
+				if err != nil || !caller.InScope(info.Labels) {
+					slog.Debug("remove container volume", "skip", item.Name, "err", err)
+					continue
 				}
 			}
+			err = engine.VolumeRemove(ctx, item.Name, false)
+			if err != nil {
+				slog.Debug("remove container volume", "err", err)
+			}
 		}
 	}
-	utils.ResOK(c)
+	return nil
 }
 
-func (a *Containers) Export(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+func (a *Containers) Export(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
 	}
 
-	out, err := a.SDK.ContainerExport(c, id)
+	out, err := sdk(c).ContainerExport(c, id)
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
 	defer func() {
 		_ = out.Close()
 	}()
 
-	reader := bufio.NewReader(out)
-	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
-	}
 	c.Header("Content-Type", "application/tar")
 	c.Header("Content-Disposition", "attachment; filename="+id+".tar")
-	io.Copy(c.Writer, reader)
+	c.Status(http.StatusOK)
+	_, err = io.Copy(c.Writer, bufio.NewReader(out))
+	return nil, err
+}
+
+func (a *Containers) Commit(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
+	}
+	return sdk(c).ContainerCommit(c, id, container.CommitOptions{
+		Reference: c.Param("name"),
+	})
+}
+
+type logLine struct {
+	Stream    string `json:"stream"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Line      string `json:"line"`
 }
 
-func (a *Containers) Commit(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+func (a *Containers) Logs(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
+	}
+	var params dto.ContainerLogsDto
+	err = c.ShouldBindQuery(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
 	}
-	name := c.Param("name")
-	out, err := a.SDK.ContainerCommit(c, id, container.CommitOptions{
-		Reference: name,
+	download := params.Format == "text" || params.Format == "gzip"
+	if download && params.Follow {
+		return nil, errdefs.InvalidParameter(errors.New("follow is not supported when downloading logs"))
+	}
+	info, err := sdk(c).ContainerInspect(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+	defer cancel()
+	out, err := sdk(c).ContainerLogs(ctx, id, container.LogsOptions{
+		ShowStdout: params.Stdout == nil || *params.Stdout,
+		ShowStderr: params.Stderr == nil || *params.Stderr,
+		Since:      params.Since,
+		Until:      params.Until,
+		Tail:       params.Tail,
+		Follow:     params.Follow,
+		// 推送时总是带上时间戳，由 logLine.Timestamp 返回
+		Timestamps: params.Timestamps || !download,
 	})
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	tty := info.Config != nil && info.Config.Tty
+
+	if download {
+		downloadLogs(c, id, params.Format, tty, out)
+		return nil, nil
 	}
 
-	utils.ResSuccess(c, out)
+	sender, err := openStream(c, ctx, cancel)
+	if err != nil {
+		slog.Debug("container logs", "upgrade", err)
+		return nil, nil
+	}
+	defer func() {
+		_ = sender.Close()
+	}()
+
+	emit := func(name string) *stream.LineWriter {
+		return stream.NewLineWriter(func(line string) error {
+			item := logLine{Stream: name, Line: line}
+			if ts, text, ok := strings.Cut(line, " "); ok {
+				if _, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+					item.Timestamp, item.Line = ts, text
+				}
+			}
+			return sender.Send("log", item)
+		})
+	}
+	stdout, stderr := emit("stdout"), emit("stderr")
+	if tty {
+		_, err = io.Copy(stdout, out)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, out)
+	}
+	_ = stdout.Flush()
+	_ = stderr.Flush()
+	if err != nil && ctx.Err() == nil {
+		_ = sender.Send(stream.EventError, err.Error())
+		return nil, nil
+	}
+	_ = sender.Send(stream.EventDone, nil)
+	return nil, nil
+}
+
+func downloadLogs(c *gin.Context, id, format string, tty bool, out io.Reader) {
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	var w io.Writer = c.Writer
+	filename := id + ".log"
+	if format == "gzip" {
+		filename += ".gz"
+		c.Header("Content-Type", "application/gzip")
+		gz := gzip.NewWriter(c.Writer)
+		defer func() {
+			_ = gz.Close()
+		}()
+		w = gz
+	} else {
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+	}
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	var err error
+	if tty {
+		_, err = io.Copy(w, out)
+	} else {
+		_, err = stdcopy.StdCopy(w, w, out)
+	}
+	if err != nil {
+		slog.Error("docker", "download logs", err)
+	}
 }
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"cyber-docker/internal/mods/docker/entity/dto"
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/utils"
+	"errors"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// pingTimeout 添加、修改和检查端点时等待守护进程响应的时间
+const pingTimeout = 10 * time.Second
+
+type Endpoints struct {
+	Registry *docker.Registry
+}
+
+func (a *Endpoints) List(c *gin.Context) (interface{}, error) {
+	var params dto.EndpointListDto
+	err := c.ShouldBind(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	key, value, _ := strings.Cut(params.Label, "=")
+	list := make([]docker.EndpointInfo, 0)
+	for _, item := range a.Registry.List() {
+		if key != "" && item.Labels[key] != value {
+			continue
+		}
+		list = append(list, redactEndpoint(item))
+	}
+	return list, nil
+}
+
+func (a *Endpoints) Inspect(c *gin.Context) (interface{}, error) {
+	return a.inspect(c.Param("endpoint"))
+}
+
+func (a *Endpoints) inspect(name string) (interface{}, error) {
+	info, err := a.Registry.Get(name)
+	if err != nil {
+		return nil, endpointError(err)
+	}
+	return redactEndpoint(info), nil
+}
+
+func (a *Endpoints) Create(c *gin.Context) (interface{}, error) {
+	var params dto.EndpointCreateDto
+	err := c.ShouldBind(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	endpoint := docker.Endpoint{
+		Name:   params.Name,
+		Host:   params.Host,
+		TLS:    endpointTLS(params.TLS),
+		Labels: params.Labels,
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), pingTimeout)
+	defer cancel()
+	err = a.Registry.Add(ctx, endpoint)
+	if err != nil {
+		return nil, endpointError(err)
+	}
+	return a.inspect(endpoint.Name)
+}
+
+func (a *Endpoints) Update(c *gin.Context) (interface{}, error) {
+	var params dto.EndpointUpdateDto
+	err := c.ShouldBind(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	endpoint := docker.Endpoint{
+		Name:   c.Param("endpoint"),
+		Host:   params.Host,
+		TLS:    endpointTLS(params.TLS),
+		Labels: params.Labels,
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), pingTimeout)
+	defer cancel()
+	err = a.Registry.Update(ctx, endpoint)
+	if err != nil {
+		return nil, endpointError(err)
+	}
+	return a.inspect(endpoint.Name)
+}
+
+func (a *Endpoints) Delete(c *gin.Context) (interface{}, error) {
+	return nil, endpointError(a.Registry.Remove(c.Param("endpoint")))
+}
+
+func (a *Endpoints) Ping(c *gin.Context) (interface{}, error) {
+	name := c.Param("endpoint")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), pingTimeout)
+	defer cancel()
+	_, err := a.Registry.Ping(ctx, name)
+	if errors.Is(err, docker.ErrEndpointNotFound) {
+		return nil, endpointError(err)
+	}
+	if err != nil {
+		if utils.StatusOf(err) == http.StatusInternalServerError {
+			err = errdefs.Unavailable(err)
+		}
+		return nil, err
+	}
+	return a.inspect(name)
+}
+
+func endpointTLS(params *dto.EndpointTLSDto) *docker.EndpointTLS {
+	if params == nil {
+		return nil
+	}
+	return &docker.EndpointTLS{
+		CACert:     params.CACert,
+		Cert:       params.Cert,
+		Key:        params.Key,
+		SkipVerify: params.SkipVerify,
+	}
+}
+
+// redactEndpoint 不返回客户端私钥
+func redactEndpoint(info docker.EndpointInfo) docker.EndpointInfo {
+	if info.TLS != nil {
+		tls := *info.TLS
+		tls.Key = ""
+		info.TLS = &tls
+	}
+	return info
+}
+
+// endpointError 为端点管理的错误分类，未分类的错误为端点配置无效
+func endpointError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, docker.ErrEndpointNotFound):
+		return errdefs.NotFound(err)
+	case errors.Is(err, docker.ErrEndpointExists), errors.Is(err, docker.ErrEndpointReadOnly):
+		return errdefs.Conflict(err)
+	case utils.StatusOf(err) == http.StatusInternalServerError:
+		return errdefs.InvalidParameter(err)
+	}
+	return err
+}
+
+// Select 根据路由中的 :endpoint 选择客户端，未指定时使用默认端点
+func (a *Endpoints) Select(c *gin.Context) {
+	name := c.Param("endpoint")
+	if name == "" {
+		name = a.Registry.Default()
+	}
+	cli, release, err := a.Registry.Acquire(name)
+	if err != nil {
+		utils.ResFail(c, endpointError(err))
+		return
+	}
+	// 请求结束前端点被修改或删除时，客户端延迟到此时关闭
+	defer release()
+	docker.WithContextClient(c, cli)
+	docker.WithContextEndpoint(c, name)
+	c.Next()
+}
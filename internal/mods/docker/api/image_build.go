@@ -0,0 +1,250 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/rand"
+	"cyber-docker/internal/mods/docker/entity"
+	"cyber-docker/internal/mods/docker/entity/dto"
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/stream"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxInlineContextSize 限制内联 Dockerfile 和 files 打包后的总大小，构建上下文在内存中打包，更大的上下文应上传 context 文件
+const maxInlineContextSize = 64 << 20
+
+var buildStepPattern = regexp.MustCompile(`^Step (\d+)/(\d+) : (.*)$`)
+
+type buildStep struct {
+	Step        int    `json:"step"`
+	Total       int    `json:"total"`
+	Instruction string `json:"instruction"`
+}
+
+// Build 构建镜像，以 step 事件推送每个构建步骤，log 事件推送步骤输出，结束时在 done 事件中返回构建记录
+func (a *Images) Build(c *gin.Context) (interface{}, error) {
+	var params dto.ImageBuildDto
+	err := c.ShouldBind(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	if _, err = parsePlatform(params.Platform); err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	buildArgs := make(map[string]*string)
+	for key, value := range parseKeyValues(params.BuildArgs) {
+		buildArgs[key] = &value
+	}
+	labels := parseKeyValues(params.Labels)
+	buildContext, err := buildContext(c, params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	defer func() {
+		_ = buildContext.Close()
+	}()
+
+	ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+	defer cancel()
+	resp, err := sdk(c).ImageBuild(ctx, buildContext, types.ImageBuildOptions{
+		Tags:        params.Tags,
+		NoCache:     params.NoCache,
+		PullParent:  params.Pull,
+		Remove:      true,
+		ForceRemove: true,
+		Dockerfile:  params.DockerfilePath,
+		BuildArgs:   buildArgs,
+		Labels:      labels,
+		Target:      params.Target,
+		Platform:    params.Platform,
+		// BuildKit 的进度为编码后的 trace，step 事件只在经典构建器下推送
+		Version:     types.BuilderVersion(a.Config.Docker.BuilderVersion),
+		AuthConfigs: registryAuthConfigs(a.Config, a.Credentials),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	record := entity.BuildRecord{
+		ID:        newBuildID(),
+		Endpoint:  docker.ContextEndpoint(c),
+		Tags:      params.Tags,
+		Target:    params.Target,
+		Platform:  params.Platform,
+		Labels:    labels,
+		Status:    entity.BuildRunning,
+		StartedAt: time.Now(),
+	}
+	a.saveBuild(record)
+	defer func() {
+		now := time.Now()
+		record.FinishedAt = &now
+		if record.Status == entity.BuildRunning {
+			record.Status = entity.BuildFailed
+			record.Error = "build canceled"
+		}
+		a.saveBuild(record)
+	}()
+
+	sender, err := openStream(c, ctx, cancel)
+	if err != nil {
+		slog.Debug("image build", "upgrade", err)
+		return nil, nil
+	}
+	defer func() {
+		_ = sender.Close()
+	}()
+	err = readProgress(resp.Body, func(msg *jsonmessage.JSONMessage) error {
+		if msg.Aux != nil {
+			var aux types.BuildResult
+			if err := json.Unmarshal(*msg.Aux, &aux); err == nil && aux.ID != "" {
+				record.ImageID = aux.ID
+			}
+			return nil
+		}
+		line := strings.TrimRight(msg.Stream, "\n")
+		if line == "" {
+			return nil
+		}
+		if match := buildStepPattern.FindStringSubmatch(line); match != nil {
+			step, _ := strconv.Atoi(match[1])
+			total, _ := strconv.Atoi(match[2])
+			return sender.Send("step", buildStep{Step: step, Total: total, Instruction: match[3]})
+		}
+		return sender.Send("log", gin.H{"line": line})
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			record.Status = entity.BuildFailed
+			record.Error = err.Error()
+			_ = sender.Send(stream.EventError, err.Error())
+		}
+		return nil, nil
+	}
+	record.Status = entity.BuildSucceeded
+	now := time.Now()
+	record.FinishedAt = &now
+	_ = sender.Send(stream.EventDone, record)
+	return nil, nil
+}
+
+// BuildList 返回当前端点最近的构建记录
+func (a *Images) BuildList(c *gin.Context) (interface{}, error) {
+	return a.Builds.List(docker.ContextEndpoint(c))
+}
+
+// saveBuild 构建记录保存失败不影响构建本身
+func (a *Images) saveBuild(record entity.BuildRecord) {
+	if err := a.Builds.Save(record); err != nil {
+		slog.Error("docker", "save build record", err)
+	}
+}
+
+func newBuildID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// parseKeyValues 解析 KEY=VALUE 列表，没有 = 的项值为空
+func parseKeyValues(list []string) map[string]string {
+	result := make(map[string]string, len(list))
+	for _, item := range list {
+		key, value, _ := strings.Cut(item, "=")
+		if key != "" {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// buildContext 返回上传的构建上下文，未上传时用内联 Dockerfile 和 files 打包成 tar
+func buildContext(c *gin.Context, params dto.ImageBuildDto) (io.ReadCloser, error) {
+	file, _, err := c.Request.FormFile("context")
+	if err == nil {
+		if params.Dockerfile != "" {
+			_ = file.Close()
+			return nil, errors.New("dockerfile and context cannot be used together")
+		}
+		return file, nil
+	}
+	if !errors.Is(err, http.ErrMissingFile) {
+		return nil, err
+	}
+	if params.Dockerfile == "" {
+		return nil, errors.New("either context or dockerfile is required")
+	}
+	name := params.DockerfilePath
+	if name == "" {
+		name = "Dockerfile"
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err = writeTarFile(tw, name, []byte(params.Dockerfile)); err != nil {
+		return nil, err
+	}
+	remaining := int64(maxInlineContextSize - len(params.Dockerfile))
+	if c.Request.MultipartForm != nil {
+		for _, header := range c.Request.MultipartForm.File["files"] {
+			if remaining, err = copyFormFile(tw, header, remaining); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&buf), nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, content []byte) error {
+	name = path.Clean(name)
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), ModTime: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(content)
+	return err
+}
+
+// copyFormFile 把上传的文件写入 tar，返回内联上下文剩余的可用大小
+func copyFormFile(tw *tar.Writer, header *multipart.FileHeader, remaining int64) (int64, error) {
+	file, err := header.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	content, err := io.ReadAll(io.LimitReader(file, remaining+1))
+	if err != nil {
+		return 0, err
+	}
+	if int64(len(content)) > remaining {
+		return 0, fmt.Errorf("inline build files exceed %d MiB, upload a context archive instead", maxInlineContextSize>>20)
+	}
+	return remaining - int64(len(content)), writeTarFile(tw, header.Filename, content)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"cyber-docker/internal/mods/docker/entity/dto"
+	"cyber-docker/pkg/stream"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"path"
+	"strings"
+)
+
+type promoteResult struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Digest string `json:"digest,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Push 推送镜像，以 progress 事件推送每一层的进度，结束时在 done 事件中返回 digest
+func (a *Images) Push(c *gin.Context) (interface{}, error) {
+	var params dto.ImagePushDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	auth, err := registryAuth(a.Config, a.Credentials, params.Reference, params.Auth)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+
+	ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+	defer cancel()
+	out, err := sdk(c).ImagePush(ctx, params.Reference, image.PushOptions{RegistryAuth: auth})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	var result types.PushResult
+	streamProgress(c, ctx, cancel, out, func(msg *jsonmessage.JSONMessage) {
+		pushResult(msg, &result)
+	}, func() (interface{}, error) {
+		return gin.H{
+			"reference": params.Reference,
+			"digest":    result.Digest,
+			"size":      result.Size,
+		}, nil
+	})
+	return nil, nil
+}
+
+// Promote 把镜像重新打上目标仓库的 tag 并依次推送，单个镜像失败不影响其他镜像，
+// 每个镜像完成时发送 result 事件，全部完成后在 done 事件中返回所有结果
+func (a *Images) Promote(c *gin.Context) (interface{}, error) {
+	var params dto.ImagePromoteDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	targets := make([]string, 0, len(params.Images))
+	for _, item := range params.Images {
+		target, err := promoteTarget(item, params.Registry, params.Namespace)
+		if err != nil {
+			return nil, errdefs.InvalidParameter(err)
+		}
+		targets = append(targets, target)
+	}
+	auth, err := registryAuth(a.Config, a.Credentials, targets[0], params.Auth)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+
+	ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+	defer cancel()
+	sender, err := openStream(c, ctx, cancel)
+	if err != nil {
+		slog.Debug("image promote", "upgrade", err)
+		return nil, nil
+	}
+	defer func() {
+		_ = sender.Close()
+	}()
+
+	results := make([]promoteResult, 0, len(params.Images))
+	for i, source := range params.Images {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		item := promoteResult{Source: source, Target: targets[i]}
+		digest, err := a.tagAndPush(c, ctx, sender, source, targets[i], auth)
+		if err != nil {
+			item.Error = err.Error()
+		}
+		item.Digest = digest
+		results = append(results, item)
+		_ = sender.Send("result", item)
+	}
+	_ = sender.Send(stream.EventDone, results)
+	return nil, nil
+}
+
+func (a *Images) tagAndPush(c *gin.Context, ctx context.Context, sender stream.Sender, source, target, auth string) (string, error) {
+	if err := sdk(c).ImageTag(ctx, source, target); err != nil {
+		return "", err
+	}
+	out, err := sdk(c).ImagePush(ctx, target, image.PushOptions{RegistryAuth: auth})
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	var result types.PushResult
+	err = readProgress(out, func(msg *jsonmessage.JSONMessage) error {
+		pushResult(msg, &result)
+		if msg.Status == "" {
+			return nil
+		}
+		item := layerProgress{Image: target, ID: msg.ID, Status: msg.Status}
+		if msg.Progress != nil {
+			item.Current, item.Total = msg.Progress.Current, msg.Progress.Total
+		}
+		return sender.Send("progress", item)
+	})
+	return result.Digest, err
+}
+
+func pushResult(msg *jsonmessage.JSONMessage, result *types.PushResult) {
+	if msg.Aux != nil {
+		_ = json.Unmarshal(*msg.Aux, result)
+	}
+}
+
+// promoteTarget 计算镜像在目标仓库中的引用，保留原来的路径（或替换命名空间）和 tag
+func promoteTarget(source, registry, namespace string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(source)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", source, err)
+	}
+	tag := "latest"
+	if tagged, ok := named.(reference.Tagged); ok {
+		tag = tagged.Tag()
+	} else if _, ok := named.(reference.Digested); ok {
+		return "", errors.New(source + ": images referenced by digest cannot be promoted")
+	}
+	repo := strings.TrimPrefix(reference.Path(named), "library/")
+	if namespace != "" {
+		repo = strings.Trim(namespace, "/") + "/" + path.Base(repo)
+	}
+	target := strings.TrimSuffix(registry, "/") + "/" + repo + ":" + tag
+	if _, err = reference.ParseNormalizedNamed(target); err != nil {
+		return "", fmt.Errorf("%s: %w", target, err)
+	}
+	return target, nil
+}
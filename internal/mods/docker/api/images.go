@@ -2,75 +2,128 @@ package api
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
+	"cyber-docker/internal/config"
 	"cyber-docker/internal/mods/docker/entity/dto"
+	"cyber-docker/internal/mods/docker/repo"
+	"cyber-docker/pkg/docker"
 	"cyber-docker/pkg/function"
+	"cyber-docker/pkg/lifecycle"
+	"cyber-docker/pkg/query"
+	"cyber-docker/pkg/stream"
 	"cyber-docker/pkg/utils"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/go-units"
 	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type Images struct {
-	SDK *client.Client
+	Config      *config.Config
+	Credentials *docker.CredentialStore
+	Lifecycle   *lifecycle.Manager
+	Builds      repo.BuildRepository
 }
 
-func (a *Images) List(c *gin.Context) {
-	imageList, err := a.SDK.ImageList(c, image.ListOptions{
+// imageFields 镜像名称取第一个 tag，没有 tag 的镜像名称为 ID
+var imageFields = query.Fields[image.Summary]{
+	Name: func(item image.Summary) string {
+		if len(item.RepoTags) == 0 {
+			return item.ID
+		}
+		return item.RepoTags[0]
+	},
+	Created: func(item image.Summary) int64 { return item.Created },
+	Size:    func(item image.Summary) int64 { return item.Size },
+	State: func(item image.Summary) string {
+		if len(item.RepoTags) == 0 {
+			return "dangling"
+		}
+		return "tagged"
+	},
+	Labels: func(item image.Summary) map[string]string { return item.Labels },
+	Text: func(item image.Summary) []string {
+		return append(append([]string{item.ID}, item.RepoTags...), item.RepoDigests...)
+	},
+}
+
+func (a *Images) List(c *gin.Context) (interface{}, error) {
+	var params dto.ImageListDto
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	imageList, err := sdk(c).ImageList(c, image.ListOptions{
 		All:            true,
 		ContainerCount: false,
 	})
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
+	}
+	page, total, err := query.Apply(imageList, params.Params, imageFields)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
 	}
-	utils.ResSuccess(c, imageList)
+	return utils.Page{Total: total, Data: page}, nil
 }
 
-func (a *Images) Inspect(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+func (a *Images) Inspect(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
 	}
 	var params dto.ImageGetDto
-	err := c.ShouldBind(&params)
+	err = c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
 
-	imageDetail, err := a.SDK.ImageInspect(c, id)
+	imageDetail, err := sdk(c).ImageInspect(c, id)
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
 	var layers []image.HistoryResponseItem
 	if params.Layer {
-		imageHistory, err := a.SDK.ImageHistory(c, id)
+		imageHistory, err := sdk(c).ImageHistory(c, id)
 		if err != nil {
-			utils.ResError(c, http.StatusInternalServerError, err.Error())
+			return nil, err
 		}
 		layers = append(layers, imageHistory...)
 	}
-	utils.ResSuccess(c, gin.H{
+	return gin.H{
 		"info":  imageDetail,
 		"layer": layers,
-	})
+	}, nil
 }
 
-func (a *Images) Delete(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+func (a *Images) Delete(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
 	}
 	var params dto.ImageDeleteDto
-	err := c.ShouldBind(&params)
+	err = c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
 	opts := image.RemoveOptions{
 		PruneChildren: true,
@@ -78,29 +131,58 @@ func (a *Images) Delete(c *gin.Context) {
 	if params.Force {
 		opts.Force = true
 	}
-	_, err = a.SDK.ImageRemove(c, id, opts)
+	_, err = sdk(c).ImageRemove(c, id, opts)
+	return nil, err
+}
+
+func (a *Images) Tag(c *gin.Context) (interface{}, error) {
+	var params dto.ImageTagDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	if _, err = reference.ParseNormalizedNamed(params.Tag); err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	return nil, sdk(c).ImageTag(c, params.Source, params.Tag)
+}
+
+// Untag 删除镜像的一个 tag，镜像只剩这一个 tag 时拒绝操作，避免误删镜像
+func (a *Images) Untag(c *gin.Context) (interface{}, error) {
+	var params dto.ImageUntagDto
+	err := c.ShouldBind(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	if strings.HasPrefix(params.Tag, "sha256:") {
+		return nil, errdefs.InvalidParameter(errors.New("tag must be an image reference"))
+	}
+	imageInfo, err := sdk(c).ImageInspect(c, params.Tag)
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
+	}
+	if len(imageInfo.RepoTags) <= 1 {
+		return nil, errdefs.Conflict(errors.New("image has only one tag, delete the image instead"))
 	}
-	utils.ResOK(c)
+	return sdk(c).ImageRemove(c, params.Tag, image.RemoveOptions{PruneChildren: false})
 }
 
-func (a *Images) Prune(c *gin.Context) {
+func (a *Images) Prune(c *gin.Context) (interface{}, error) {
 	var params dto.ImagePruneDto
 	err := c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
 	if params.Build {
-		res, err := a.SDK.BuildCachePrune(c, types.BuildCachePruneOptions{
+		res, err := sdk(c).BuildCachePrune(c, types.BuildCachePruneOptions{
 			All: true,
 		})
 		if err != nil {
-			utils.ResError(c, http.StatusInternalServerError, err.Error())
+			return nil, err
 		}
-		utils.ResSuccess(c, gin.H{
+		return gin.H{
 			"size": units.HumanSize(float64(res.SpaceReclaimed)),
-		})
+		}, nil
 	}
 
 	// 清理未使用的 tag 时，直接调用 Prune 处理
@@ -108,90 +190,459 @@ func (a *Images) Prune(c *gin.Context) {
 	if params.Unused {
 		filter := filters.NewArgs()
 		filter.Add("dangling", "0")
-		res, err := a.SDK.ImagesPrune(c, filter)
+		res, err := sdk(c).ImagesPrune(c, filter)
 		if err != nil {
-			utils.ResError(c, http.StatusInternalServerError, err.Error())
+			return nil, err
 		}
-		utils.ResSuccess(c, gin.H{
+		return gin.H{
 			"size":  units.HumanSize(float64(res.SpaceReclaimed)),
 			"count": fmt.Sprintf("%d", len(res.ImagesDeleted)),
-		})
-	} else {
-		var deleteImageSpaceReclaimed int64 = 0
-		deleteImageTotal := 0
-		useImageList := make([]string, 0)
-		if containerList, err := a.SDK.ContainerList(c, container.ListOptions{}); err != nil {
-			useImageList = function.PluckArrayWalk(containerList, func(item container.Summary) (string, bool) {
-				return item.ImageID, true
-			})
-		}
-		if imageList, err := a.SDK.ImageList(c, image.ListOptions{
-			All:            true,
-			ContainerCount: true,
-		}); err != nil {
-			for _, item := range imageList {
-				if !function.InSlice(useImageList, item.ID) {
-					deleteImageSpaceReclaimed += item.Size
-					deleteImageTotal += 1
-					_, _ = a.SDK.ImageRemove(c, item.ID, image.RemoveOptions{PruneChildren: true})
-				}
-			}
+		}, nil
+	}
+
+	var deleteImageSpaceReclaimed int64 = 0
+	deleteImageTotal := 0
+	containerList, err := sdk(c).ContainerList(c, container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+	useImageList := function.PluckArrayWalk(containerList, func(item container.Summary) (string, bool) {
+		return item.ImageID, true
+	})
+	imageList, err := sdk(c).ImageList(c, image.ListOptions{
+		All:            true,
+		ContainerCount: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range imageList {
+		if function.InSlice(useImageList, item.ID) {
+			continue
 		}
-		utils.ResSuccess(c, gin.H{
-			"size":  units.HumanSize(float64(deleteImageSpaceReclaimed)),
-			"count": fmt.Sprintf("%d", deleteImageTotal),
-		})
+		// 被其他镜像依赖等原因无法删除的镜像跳过
+		if _, err = sdk(c).ImageRemove(c, item.ID, image.RemoveOptions{PruneChildren: true}); err != nil {
+			continue
+		}
+		deleteImageSpaceReclaimed += item.Size
+		deleteImageTotal += 1
 	}
+	return gin.H{
+		"size":  units.HumanSize(float64(deleteImageSpaceReclaimed)),
+		"count": fmt.Sprintf("%d", deleteImageTotal),
+	}, nil
 }
 
-func (a *Images) CheckUpgrade(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+const (
+	upgradeUpToDate = "up-to-date"
+	upgradeOutdated = "outdated"
+	upgradeUnknown  = "unknown"
+)
+
+type tagUpgrade struct {
+	Tag          string `json:"tag"`
+	Status       string `json:"status"`
+	LocalDigest  string `json:"local_digest,omitempty"`
+	RemoteDigest string `json:"remote_digest,omitempty"`
+	Message      string `json:"message,omitempty"`
+}
+
+// CheckUpgrade 通过当前端点的 Docker 守护进程查询每个 tag 在仓库中的 digest，与本地记录的 digest 比较判断镜像是否有更新
+func (a *Images) CheckUpgrade(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
+	}
+	var params dto.ImageCheckUpgradeDto
+	if err = c.ShouldBindJSON(&params); err != nil && !errors.Is(err, io.EOF) {
+		return nil, errdefs.InvalidParameter(err)
 	}
 
-	imageInfo, err := a.SDK.ImageInspect(c, id)
+	imageInfo, err := sdk(c).ImageInspect(c, id)
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
 	if function.IsEmptySlice(imageInfo.RepoTags) {
-		utils.ResError(c, http.StatusBadRequest, "image repo tags is empty")
+		return nil, errdefs.InvalidParameter(errors.New("image repo tags is empty"))
+	}
+	platform := ocispec.Platform{OS: imageInfo.Os, Architecture: imageInfo.Architecture, Variant: imageInfo.Variant}
+	tags := make([]tagUpgrade, 0, len(imageInfo.RepoTags))
+	for _, tag := range imageInfo.RepoTags {
+		tags = append(tags, a.checkTag(c, tag, imageInfo.RepoDigests, platform, params.Auth))
 	}
-	// TODO 镜像仓库拿
 
-	utils.ResOK(c)
+	containerList, err := sdk(c).ContainerList(c, container.ListOptions{
+		All:     true,
+		Filters: scopeFilter(c, filters.NewArgs(filters.Arg("ancestor", imageInfo.ID))),
+	})
+	if err != nil {
+		return nil, err
+	}
+	containers := make([]gin.H, 0, len(containerList))
+	for _, item := range containerList {
+		containers = append(containers, gin.H{
+			"id":    item.ID,
+			"names": item.Names,
+			"state": item.State,
+		})
+	}
+	return gin.H{
+		"id":         imageInfo.ID,
+		"tags":       tags,
+		"containers": containers,
+	}, nil
 }
 
-func (a *Images) Import(c *gin.Context) {
+func (a *Images) checkTag(c *gin.Context, tag string, repoDigests []string, platform ocispec.Platform, explicit *dto.RegistryAuthDto) tagUpgrade {
+	result := tagUpgrade{Tag: tag, Status: upgradeUnknown}
+	named, err := reference.ParseNormalizedNamed(tag)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	var localDigests []string
+	for _, item := range repoDigests {
+		if canonical, err := reference.ParseNormalizedNamed(item); err == nil && canonical.Name() == named.Name() {
+			if digested, ok := canonical.(reference.Digested); ok {
+				localDigests = append(localDigests, digested.Digest().String())
+			}
+		}
+	}
+	if len(localDigests) > 0 {
+		result.LocalDigest = localDigests[0]
+	}
+	auth, err := registryAuth(a.Config, a.Credentials, tag, explicit)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	dist, err := sdk(c).DistributionInspect(c, tag, auth)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.RemoteDigest = dist.Descriptor.Digest.String()
+
+	// manifest list 的 digest 覆盖所有平台，但本地镜像的平台必须在其中
+	if len(dist.Platforms) > 0 && !matchPlatform(dist.Platforms, platform) {
+		result.Message = fmt.Sprintf("platform %s is not available in registry", formatPlatform(platform))
+		return result
+	}
+	switch {
+	case len(localDigests) == 0:
+		result.Message = "image has no repo digest, it may be built locally"
+	case function.InSlice(localDigests, result.RemoteDigest):
+		result.Status = upgradeUpToDate
+		result.LocalDigest = result.RemoteDigest
+	default:
+		result.Status = upgradeOutdated
+	}
+	return result
+}
+
+func matchPlatform(list []ocispec.Platform, platform ocispec.Platform) bool {
+	for _, item := range list {
+		if item.OS != platform.OS || item.Architecture != platform.Architecture {
+			continue
+		}
+		if item.Variant == "" || platform.Variant == "" || item.Variant == platform.Variant {
+			return true
+		}
+	}
+	return false
+}
+
+// Import 导入上传的 tar 包，container 为 true 时作为容器文件系统导入（docker import），否则作为 docker save 的镜像包导入
+func (a *Images) Import(c *gin.Context) (interface{}, error) {
 	var params dto.ImageImportDto
 	err := c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
+	}
+	if params.Tag != "" && params.Repository == "" {
+		return nil, errdefs.InvalidParameter(errors.New("repository is required when tag is set"))
 	}
+	if _, err = parsePlatform(params.Platform); err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
 
-	file, header, err := c.Request.FormFile("file")
-	fmt.Println(header.Filename)
-	// 如果导入的是容器的tar包
+	ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+	defer cancel()
+	var out io.ReadCloser
 	if params.Container {
-		//var params dto.ImportDTO
-		//err := c.ShouldBindBodyWithJSON(&params)
-		//if err != nil {
-		//	utils.ResError(c, http.StatusBadRequest, err.Error())
-		//}
-
+		out, err = sdk(c).ImageImport(ctx, image.ImportSource{Source: file, SourceName: "-"}, params.Repository, image.ImportOptions{
+			Tag:      params.Tag,
+			Message:  params.Message,
+			Changes:  params.Changes,
+			Platform: params.Platform,
+		})
 	} else {
-		// 如果导入的是镜像的tar包
-		reader := bufio.NewReader(file)
-		response, err := a.SDK.ImageLoad(c, reader, client.ImageLoadWithQuiet(false))
-		if err != nil {
-			utils.ResError(c, http.StatusInternalServerError, err.Error())
+		var response image.LoadResponse
+		response, err = sdk(c).ImageLoad(ctx, bufio.NewReader(file), client.ImageLoadWithQuiet(false))
+		out = response.Body
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := out.Close(); err != nil {
+			slog.Error("docker", "image import", err)
+		}
+	}()
+
+	// 导入容器时最后一条 status 是镜像 ID，导入镜像包时每个镜像输出一条 Loaded image
+	var images []string
+	streamProgress(c, ctx, cancel, out, func(msg *jsonmessage.JSONMessage) {
+		line := strings.TrimSpace(msg.Stream)
+		if value, ok := strings.CutPrefix(line, "Loaded image: "); ok {
+			images = append(images, value)
+		} else if value, ok := strings.CutPrefix(line, "Loaded image ID: "); ok {
+			images = append(images, value)
+		} else if strings.HasPrefix(msg.Status, "sha256:") {
+			images = append(images, msg.Status)
+		}
+	}, func() (interface{}, error) {
+		return gin.H{"images": images}, nil
+	})
+	return nil, nil
+}
+
+// Export 以附件下载 docker save 格式的 tar 包，可以直接通过 Import 导入
+func (a *Images) Export(c *gin.Context) (interface{}, error) {
+	var params dto.ImageExportDto
+	err := c.ShouldBindQuery(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	platform, err := parsePlatform(params.Platform)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	var opts []client.ImageSaveOption
+	if platform != nil {
+		opts = append(opts, client.ImageSaveWithPlatforms(*platform))
+	}
+
+	ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+	defer cancel()
+	out, err := sdk(c).ImageSave(ctx, params.Refs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	filename := "images.tar"
+	if len(params.Refs) == 1 {
+		filename = strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(params.Refs[0]) + ".tar"
+	}
+	var w io.WriteCloser
+	switch params.Compress {
+	case "gzip":
+		filename += ".gz"
+		c.Header("Content-Type", "application/gzip")
+		w = gzip.NewWriter(c.Writer)
+	case "zstd":
+		if w, err = zstd.NewWriter(c.Writer); err != nil {
+			return nil, err
+		}
+		filename += ".zst"
+		c.Header("Content-Type", "application/zstd")
+	default:
+		c.Header("Content-Type", "application/x-tar")
+		w = nopWriteCloser{c.Writer}
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Status(http.StatusOK)
+	if _, err = io.Copy(w, out); err != nil {
+		slog.Error("docker", "image export", err)
+	}
+	if err = w.Close(); err != nil {
+		slog.Error("docker", "image export", err)
+	}
+	return nil, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// pullImage 拉取镜像并等待完成，进度消息中的错误作为返回值
+func pullImage(ctx context.Context, cli docker.Engine, cfg *config.Config, credentials *docker.CredentialStore, ref, platform string) error {
+	pullRef := ref
+	if mirror, ok := mirrorReference(credentials, ref); ok {
+		pullRef = mirror
+	}
+	auth, err := registryAuth(cfg, credentials, pullRef, nil)
+	if err != nil {
+		return err
+	}
+	out, err := cli.ImagePull(ctx, pullRef, image.PullOptions{
+		Platform:     platform,
+		RegistryAuth: auth,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	if err = readProgress(out, nil); err != nil {
+		return err
+	}
+	return restoreMirrorTag(ctx, cli, pullRef, ref)
+}
+
+// readProgress 逐条解析 Docker 的 JSON 进度消息，消息中的错误作为返回值
+func readProgress(r io.Reader, fn func(msg *jsonmessage.JSONMessage) error) error {
+	decoder := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return msg.Error
 		}
-		// TODO 响应流数据
-		defer func() {
-			if response.Body.Close() != nil {
-				slog.Error("docker", "image import ", err)
+		if fn != nil {
+			if err := fn(&msg); err != nil {
+				return err
 			}
-		}()
-		io.Copy(c.Writer, response.Body)
+		}
+	}
+}
+
+// encodeRegistryAuth 把凭据编码为 RegistryAuth，未提供凭据时返回空字符串
+func encodeRegistryAuth(auth *dto.RegistryAuthDto) (string, error) {
+	if auth == nil || (auth.Username == "" && auth.IdentityToken == "") {
+		return "", nil
+	}
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		IdentityToken: auth.IdentityToken,
+		ServerAddress: auth.ServerAddress,
+	})
+}
+
+type layerProgress struct {
+	// 批量操作时标识所属的镜像
+	Image   string `json:"image,omitempty"`
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+}
+
+// Pull 拉取镜像，以 progress 事件推送每一层的进度，结束时在 done 事件中返回镜像 ID 和 digest
+func (a *Images) Pull(c *gin.Context) (interface{}, error) {
+	var params dto.ImagePullDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	if _, err = parsePlatform(params.Platform); err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	// 请求中指定了凭据时直接从源仓库拉取
+	pullRef := params.Reference
+	if mirror, ok := mirrorReference(a.Credentials, params.Reference); ok && params.Auth == nil {
+		pullRef = mirror
+	}
+	auth, err := registryAuth(a.Config, a.Credentials, pullRef, params.Auth)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+
+	ctx, cancel := a.Lifecycle.Bind(c.Request.Context())
+	defer cancel()
+	out, err := sdk(c).ImagePull(ctx, pullRef, image.PullOptions{
+		Platform:     params.Platform,
+		RegistryAuth: auth,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	var digest string
+	streamProgress(c, ctx, cancel, out, func(msg *jsonmessage.JSONMessage) {
+		if value, ok := strings.CutPrefix(msg.Status, "Digest: "); ok {
+			digest = value
+		}
+	}, func() (interface{}, error) {
+		if err := restoreMirrorTag(ctx, sdk(c), pullRef, params.Reference); err != nil {
+			return nil, err
+		}
+		info, err := sdk(c).ImageInspect(ctx, params.Reference)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{
+			"reference":    params.Reference,
+			"pulled_from":  pullRef,
+			"id":           info.ID,
+			"digest":       digest,
+			"repo_digests": info.RepoDigests,
+		}, nil
+	})
+	return nil, nil
+}
+
+// streamProgress 把 Docker 的 JSON 进度消息以 progress 事件推送给客户端，
+// 每条消息先交给 onMessage 处理，读取完成后以 summary 的返回值发送 done 事件
+func streamProgress(c *gin.Context, ctx context.Context, cancel context.CancelFunc, out io.Reader, onMessage func(msg *jsonmessage.JSONMessage), summary func() (interface{}, error)) {
+	sender, err := openStream(c, ctx, cancel)
+	if err != nil {
+		slog.Debug("docker progress", "upgrade", err)
+		return
+	}
+	defer func() {
+		_ = sender.Close()
+	}()
+
+	err = readProgress(out, func(msg *jsonmessage.JSONMessage) error {
+		onMessage(msg)
+		item := layerProgress{ID: msg.ID, Status: msg.Status}
+		if item.Status == "" {
+			item.Status = strings.TrimSpace(msg.Stream)
+		}
+		if item.Status == "" {
+			return nil
+		}
+		if msg.Progress != nil {
+			item.Current, item.Total = msg.Progress.Current, msg.Progress.Total
+		}
+		return sender.Send("progress", item)
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			_ = sender.Send(stream.EventError, err.Error())
+		}
+		return
+	}
+	data, err := summary()
+	if err != nil {
+		_ = sender.Send(stream.EventError, err.Error())
+		return
 	}
+	_ = sender.Send(stream.EventDone, data)
 }
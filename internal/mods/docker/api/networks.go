@@ -3,55 +3,62 @@ package api
 import (
 	"cyber-docker/internal/mods/docker/entity/dto"
 	"cyber-docker/pkg/function"
+	"cyber-docker/pkg/query"
 	"cyber-docker/pkg/utils"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
-	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/gin-gonic/gin"
-	"net/http"
 )
 
 type Network struct {
-	SDK *client.Client
 }
 
-func (a *Network) List(c *gin.Context) {
+// networkFields 网络没有大小和状态，搜索同时匹配驱动
+var networkFields = query.Fields[network.Summary]{
+	Name:    func(item network.Summary) string { return item.Name },
+	Created: func(item network.Summary) int64 { return item.Created.UnixNano() },
+	Labels:  func(item network.Summary) map[string]string { return item.Labels },
+	Text:    func(item network.Summary) []string { return []string{item.ID, item.Driver} },
+}
+
+func (a *Network) List(c *gin.Context) (interface{}, error) {
 	var params dto.NetworkListDto
 	err := c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
 	filter := filters.NewArgs()
 	if params.Name != "" {
 		filter.Add("name", params.Name)
 	}
 
-	networkList, err := a.SDK.NetworkList(c, network.ListOptions{
+	networkList, err := sdk(c).NetworkList(c, network.ListOptions{
 		Filters: filter,
 	})
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
+	}
+	page, total, err := query.Apply(networkList, params.Params, networkFields)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
 	}
-	utils.ResSuccess(c, networkList)
+	return utils.Page{Total: total, Data: page}, nil
 }
 
-func (a *Network) Inspect(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
-	}
-	networkInfo, err := a.SDK.NetworkInspect(c, id, network.InspectOptions{})
+func (a *Network) Inspect(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
-	utils.ResSuccess(c, networkInfo)
+	return sdk(c).NetworkInspect(c, id, network.InspectOptions{})
 }
 
-func (a *Network) Create(c *gin.Context) {
+func (a *Network) Create(c *gin.Context) (interface{}, error) {
 	var params dto.NetworkCreateDto
 	err := c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
 
 	option := network.CreateOptions{
@@ -81,49 +88,43 @@ func (a *Network) Create(c *gin.Context) {
 		})
 	}
 
-	response, err := a.SDK.NetworkCreate(c, params.Name, option)
-	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
-	}
-	utils.ResSuccess(c, response)
+	return sdk(c).NetworkCreate(c, params.Name, option)
 }
 
-func (a *Network) Delete(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+// Delete 先断开所有容器再删除网络
+func (a *Network) Delete(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
 	}
-	if networkInfo, err := a.SDK.NetworkInspect(c, id, network.InspectOptions{}); err == nil {
-		for _, item := range networkInfo.Containers {
-			err = a.SDK.NetworkDisconnect(c, id, item.Name, true)
-		}
-		if err != nil {
-			utils.ResError(c, http.StatusInternalServerError, err.Error())
-		}
-		err := a.SDK.NetworkRemove(c, id)
-		if err != nil {
-			utils.ResError(c, http.StatusInternalServerError, err.Error())
+	networkInfo, err := sdk(c).NetworkInspect(c, id, network.InspectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range networkInfo.Containers {
+		if err = sdk(c).NetworkDisconnect(c, id, item.Name, true); err != nil {
+			return nil, err
 		}
-	} else {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
 	}
-	utils.ResOK(c)
+	return nil, sdk(c).NetworkRemove(c, id)
 }
 
-func (a *Network) Connect(c *gin.Context) {
+func (a *Network) Connect(c *gin.Context) (interface{}, error) {
 	var params dto.NetworkConnectDto
 	err := c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
-
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
+	}
+	if err = checkContainerScope(c, params.ContainerName); err != nil {
+		return nil, err
 	}
 
 	// 关联网络时，重新退出加入
-	_ = a.SDK.NetworkDisconnect(c, id, params.ContainerName, true)
+	_ = sdk(c).NetworkDisconnect(c, id, params.ContainerName, true)
 	if params.ContainerAlise == nil {
 		params.ContainerAlise = make([]string, 0)
 	}
@@ -147,34 +148,26 @@ func (a *Network) Connect(c *gin.Context) {
 		endpointSetting.MacAddress = params.MacAddress
 	}
 
-	err = a.SDK.NetworkConnect(c, id, params.ContainerName, endpointSetting)
-	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
-	}
-	utils.ResOK(c)
+	return nil, sdk(c).NetworkConnect(c, id, params.ContainerName, endpointSetting)
 }
 
-func (a *Network) Disconnect(c *gin.Context) {
+func (a *Network) Disconnect(c *gin.Context) (interface{}, error) {
 	var params dto.NetworkDisconnectDto
 	err := c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
-	}
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+		return nil, errdefs.InvalidParameter(err)
 	}
-	err = a.SDK.NetworkDisconnect(c, id, params.ContainerName, false)
+	id, err := pathParam(c, "id")
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
+	}
+	if err = checkContainerScope(c, params.ContainerName); err != nil {
+		return nil, err
 	}
-	utils.ResOK(c)
+	return nil, sdk(c).NetworkDisconnect(c, id, params.ContainerName, false)
 }
 
-func (a *Network) Prune(c *gin.Context) {
-	_, err := a.SDK.NetworksPrune(c, filters.NewArgs())
-	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
-	}
-	utils.ResOK(c)
+func (a *Network) Prune(c *gin.Context) (interface{}, error) {
+	_, err := sdk(c).NetworksPrune(c, filters.NewArgs())
+	return nil, err
 }
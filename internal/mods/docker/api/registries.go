@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"cyber-docker/internal/config"
+	"cyber-docker/internal/mods/docker/entity/dto"
+	"cyber-docker/pkg/docker"
+	"errors"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+type Registries struct {
+	Credentials *docker.CredentialStore
+}
+
+// registryInfo 是返回给客户端的仓库凭据，不包含密码
+type registryInfo struct {
+	Host      string    `json:"host"`
+	Username  string    `json:"username"`
+	HasSecret bool      `json:"has_secret"`
+	Insecure  bool      `json:"insecure"`
+	Mirror    string    `json:"mirror"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func redactCredential(item docker.Credential) registryInfo {
+	return registryInfo{
+		Host:      item.Host,
+		Username:  item.Username,
+		HasSecret: item.Secret != "",
+		Insecure:  item.Insecure,
+		Mirror:    item.Mirror,
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: item.UpdatedAt,
+	}
+}
+
+func (a *Registries) List(c *gin.Context) (interface{}, error) {
+	list := make([]registryInfo, 0)
+	for _, item := range a.Credentials.List() {
+		list = append(list, redactCredential(item))
+	}
+	return list, nil
+}
+
+func (a *Registries) Inspect(c *gin.Context) (interface{}, error) {
+	item, err := a.Credentials.Get(c.Param("host"))
+	if err != nil {
+		return nil, registryError(err)
+	}
+	return redactCredential(item), nil
+}
+
+func (a *Registries) Create(c *gin.Context) (interface{}, error) {
+	var params dto.RegistryCreateDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	item, err := a.Credentials.Add(docker.Credential{
+		Host:     params.Host,
+		Username: params.Username,
+		Secret:   params.Secret,
+		Insecure: params.Insecure,
+		Mirror:   docker.NormalizeRegistryHost(params.Mirror),
+	})
+	if err != nil {
+		return nil, registryError(err)
+	}
+	return redactCredential(item), nil
+}
+
+func (a *Registries) Update(c *gin.Context) (interface{}, error) {
+	var params dto.RegistryUpdateDto
+	err := c.ShouldBindJSON(&params)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	item, err := a.Credentials.Update(docker.Credential{
+		Host:     c.Param("host"),
+		Username: params.Username,
+		Secret:   params.Secret,
+		Insecure: params.Insecure,
+		Mirror:   docker.NormalizeRegistryHost(params.Mirror),
+	}, params.Secret == "")
+	if err != nil {
+		return nil, registryError(err)
+	}
+	return redactCredential(item), nil
+}
+
+func (a *Registries) Delete(c *gin.Context) (interface{}, error) {
+	return nil, registryError(a.Credentials.Remove(c.Param("host")))
+}
+
+// Login 使用已保存的凭据通过当前端点的 Docker 守护进程登录仓库，只用于验证凭据是否有效
+func (a *Registries) Login(c *gin.Context) (interface{}, error) {
+	item, err := a.Credentials.Get(c.Param("host"))
+	if err != nil {
+		return nil, registryError(err)
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+	result, err := sdk(c).RegistryLogin(ctx, item.AuthConfig())
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"host":   item.Host,
+		"status": result.Status,
+	}, nil
+}
+
+func registryError(err error) error {
+	switch {
+	case errors.Is(err, docker.ErrCredentialNotFound):
+		return errdefs.NotFound(err)
+	case errors.Is(err, docker.ErrCredentialExists):
+		return errdefs.Conflict(err)
+	}
+	return err
+}
+
+// registryAuth 依次使用请求中的凭据、已保存的凭据和配置文件中的凭据，均未找到时返回空字符串
+func registryAuth(cfg *config.Config, credentials *docker.CredentialStore, ref string, explicit *dto.RegistryAuthDto) (string, error) {
+	if explicit != nil {
+		return encodeRegistryAuth(explicit)
+	}
+	host, err := docker.ReferenceHost(ref)
+	if err != nil {
+		return "", err
+	}
+	// 只保存了镜像加速等设置、没有密码的凭据使用配置文件中的账号
+	if item, err := credentials.Get(host); err == nil && item.Secret != "" {
+		return registry.EncodeAuthConfig(item.AuthConfig())
+	}
+	for _, item := range cfg.Docker.Registries {
+		if item.Host == host {
+			return encodeRegistryAuth(&dto.RegistryAuthDto{
+				Username:      item.Username,
+				Password:      item.Password,
+				IdentityToken: item.IdentityToken,
+				ServerAddress: item.Host,
+			})
+		}
+	}
+	return "", nil
+}
+
+// registryAuthConfigs 返回所有已知仓库的凭据，用于构建时拉取基础镜像
+func registryAuthConfigs(cfg *config.Config, credentials *docker.CredentialStore) map[string]registry.AuthConfig {
+	result := make(map[string]registry.AuthConfig)
+	for _, item := range cfg.Docker.Registries {
+		result[item.Host] = registry.AuthConfig{
+			Username:      item.Username,
+			Password:      item.Password,
+			IdentityToken: item.IdentityToken,
+			ServerAddress: item.Host,
+		}
+	}
+	for address, auth := range credentials.AuthConfigs() {
+		if auth.Password != "" || auth.IdentityToken != "" {
+			result[address] = auth
+		}
+	}
+	return result
+}
+
+// mirrorReference 仓库配置了镜像加速地址时返回加速地址上的同名引用
+func mirrorReference(credentials *docker.CredentialStore, ref string) (string, bool) {
+	item, ok := credentials.Resolve(ref)
+	if !ok || item.Mirror == "" {
+		return "", false
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", false
+	}
+	named = reference.TagNameOnly(named)
+	mirror := item.Mirror + "/" + reference.Path(named)
+	if digested, ok := named.(reference.Digested); ok {
+		return mirror + "@" + digested.Digest().String(), true
+	}
+	return mirror + ":" + named.(reference.Tagged).Tag(), true
+}
+
+// restoreMirrorTag 从加速地址拉取后把镜像标记为原来的引用，并删除加速地址的 tag
+func restoreMirrorTag(ctx context.Context, cli docker.Engine, pullRef, ref string) error {
+	if pullRef == ref {
+		return nil
+	}
+	if err := cli.ImageTag(ctx, pullRef, ref); err != nil {
+		return err
+	}
+	_, err := cli.ImageRemove(ctx, pullRef, image.RemoveOptions{})
+	return err
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"cyber-docker/pkg/identity"
+	"cyber-docker/pkg/utils"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+)
+
+// 标签范围只限制容器和存储卷，镜像和网络是共享资源，只按角色授权
+
+// scopeFilter 调用者受标签范围限制时添加 label 过滤条件
+func scopeFilter(c *gin.Context, args filters.Args) filters.Args {
+	if id := identity.FromContext(c); id != nil {
+		for key, value := range id.Labels {
+			args.Add("label", key+"="+value)
+		}
+	}
+	return args
+}
+
+// scopeLabels 创建资源时加上调用者的范围标签，使创建的资源仍在调用者的访问范围内
+func scopeLabels(c *gin.Context, labels map[string]string) map[string]string {
+	id := identity.FromContext(c)
+	if id == nil || len(id.Labels) == 0 {
+		return labels
+	}
+	result := make(map[string]string, len(labels)+len(id.Labels))
+	for key, value := range labels {
+		result[key] = value
+	}
+	for key, value := range id.Labels {
+		result[key] = value
+	}
+	return result
+}
+
+// checkCreateScope 受标签范围限制的调用者不能创建特权容器、添加 capability 或挂载宿主机目录，
+// 挂载的存储卷必须在访问范围内，否则可以借此访问范围外的资源
+func checkCreateScope(c *gin.Context, hostConfig *container.HostConfig) error {
+	id := identity.FromContext(c)
+	if id == nil || len(id.Labels) == 0 {
+		return nil
+	}
+	if hostConfig.Privileged {
+		return errdefs.Forbidden(errors.New("privileged containers are not allowed in your scope"))
+	}
+	if len(hostConfig.CapAdd) > 0 {
+		return errdefs.Forbidden(errors.New("cap_add is not allowed in your scope"))
+	}
+	for _, item := range hostConfig.Mounts {
+		switch item.Type {
+		case mount.TypeBind:
+			return errdefs.Forbidden(fmt.Errorf("bind mount %s is not allowed in your scope", item.Source))
+		case mount.TypeVolume:
+			if item.Source == "" {
+				continue
+			}
+			info, err := sdk(c).VolumeInspect(c, item.Source)
+			if err != nil && !errdefs.IsNotFound(err) {
+				return err
+			}
+			if err == nil && !id.InScope(info.Labels) {
+				return errdefs.Forbidden(fmt.Errorf("volume %s is outside your scope", item.Source))
+			}
+		}
+	}
+	return nil
+}
+
+// Scope 检查路由中 :id 指定的容器是否在调用者的访问范围内
+func (a *Containers) Scope(c *gin.Context) {
+	if err := checkContainerScope(c, c.Param("id")); err != nil {
+		utils.ResFail(c, err)
+		return
+	}
+	c.Next()
+}
+
+// checkContainerScope 容器不在访问范围内时返回错误，容器不存在时交给后续处理
+func checkContainerScope(c *gin.Context, containerID string) error {
+	id := identity.FromContext(c)
+	if containerID == "" || id == nil || len(id.Labels) == 0 {
+		return nil
+	}
+	info, err := sdk(c).ContainerInspect(c, containerID)
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Config == nil || !id.InScope(info.Config.Labels) {
+		return errdefs.Forbidden(fmt.Errorf("container %s is outside your scope", containerID))
+	}
+	return nil
+}
+
+// Scope 检查路由中 :id 指定的存储卷是否在调用者的访问范围内
+func (a *Volume) Scope(c *gin.Context) {
+	id := identity.FromContext(c)
+	name := c.Param("id")
+	if name == "" || id == nil || len(id.Labels) == 0 {
+		c.Next()
+		return
+	}
+	info, err := sdk(c).VolumeInspect(c, name)
+	if err != nil && !errdefs.IsNotFound(err) {
+		utils.ResFail(c, err)
+		return
+	}
+	if err == nil && !id.InScope(info.Labels) {
+		utils.ResFail(c, errdefs.Forbidden(fmt.Errorf("volume %s is outside your scope", name)))
+		return
+	}
+	c.Next()
+}
@@ -2,66 +2,103 @@ package api
 
 import (
 	"cyber-docker/internal/mods/docker/entity/dto"
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/query"
 	"cyber-docker/pkg/utils"
+	"fmt"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/volume"
-	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/gin-gonic/gin"
-	"net/http"
 	"strings"
+	"time"
 )
 
 type Volume struct {
-	SDK *client.Client
 }
 
-func (a *Volume) List(c *gin.Context) {
+// volumeFields 存储卷列表不包含大小，State 在查询时按需设置
+var volumeFields = query.Fields[*volume.Volume]{
+	Name: func(item *volume.Volume) string { return item.Name },
+	Created: func(item *volume.Volume) int64 {
+		created, _ := time.Parse(time.RFC3339, item.CreatedAt)
+		return created.UnixNano()
+	},
+	Labels: func(item *volume.Volume) map[string]string { return item.Labels },
+	Text:   func(item *volume.Volume) []string { return []string{item.Driver, item.Mountpoint} },
+}
+
+func (a *Volume) List(c *gin.Context) (interface{}, error) {
 	var params dto.VolumeListDto
 	err := c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
 
-	filter := filters.NewArgs()
+	filter := scopeFilter(c, filters.NewArgs())
 	if params.Name != "" {
 		filter.Add("name", params.Name)
 	}
-	volumeList, err := a.SDK.VolumeList(c, volume.ListOptions{
+	volumeList, err := sdk(c).VolumeList(c, volume.ListOptions{
 		Filters: filter,
 	})
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
-
-	utils.ResSuccess(c, gin.H{
-		"volumeList": volumeList.Volumes,
+	used, err := inUse(c, sdk(c), params.Name)
+	if err != nil {
+		return nil, err
+	}
+	fields := volumeFields
+	// 只有按状态排序或过滤时才查询所有容器的挂载
+	if params.SortBy == query.SortState || len(params.Status) > 0 {
+		mounted, err := mountedVolumes(c, sdk(c))
+		if err != nil {
+			return nil, err
+		}
+		fields.State = func(item *volume.Volume) string {
+			if mounted[item.Name] {
+				return "in-use"
+			}
+			return "unused"
+		}
+	}
+	page, total, err := query.Apply(volumeList.Volumes, params.Params, fields)
+	if err != nil {
+		return nil, errdefs.InvalidParameter(err)
+	}
+	return utils.Page{Total: total, Data: gin.H{
+		"volumeList": page,
 		"warning":    volumeList.Warnings,
-		"inUse":      inUse(c, a.SDK, params.Name),
-	})
+		"inUse":      used,
+	}}, nil
 }
 
-func (a *Volume) Inspect(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+func (a *Volume) Inspect(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
+	}
+	volumeInfo, err := sdk(c).VolumeInspect(c, id)
+	if err != nil {
+		return nil, err
 	}
-	var params dto.VolumeGetDto
-	volumeInfo, err := a.SDK.VolumeInspect(c, id)
+	used, err := inUse(c, sdk(c), volumeInfo.Name)
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
-	utils.ResSuccess(c, gin.H{
+	return gin.H{
 		"info":  volumeInfo,
-		"inUse": inUse(c, a.SDK, params.Name),
-	})
+		"inUse": used,
+	}, nil
 }
 
-func (a *Volume) Create(c *gin.Context) {
+func (a *Volume) Create(c *gin.Context) (interface{}, error) {
 	var params dto.VolumeCreateDto
 	err := c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
 	options := make(map[string]string)
 	switch params.Type {
@@ -75,47 +112,51 @@ func (a *Volume) Create(c *gin.Context) {
 		options["o"] = params.NfsUrl + "," + params.NfsOptions
 	case "other":
 		for _, row := range params.OtherOptions {
-			item := strings.Split(row, "\n")
-			options[item[0]] = item[1]
+			key, value, ok := strings.Cut(row, "\n")
+			if !ok {
+				return nil, errdefs.InvalidParameter(fmt.Errorf("invalid volume option %q", row))
+			}
+			options[key] = value
 		}
 	}
-	volumeInfo, err := a.SDK.VolumeCreate(c, volume.CreateOptions{
+	volumeInfo, err := sdk(c).VolumeCreate(c, volume.CreateOptions{
 		Driver:     params.Driver,
 		Name:       params.Name,
 		DriverOpts: options,
+		Labels:     scopeLabels(c, nil),
 	})
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
-	utils.ResSuccess(c, gin.H{
+	return gin.H{
 		"volumeInfo": volumeInfo,
-	})
+	}, nil
 }
 
-func (a *Volume) Prune(c *gin.Context) {
+func (a *Volume) Prune(c *gin.Context) (interface{}, error) {
 	var params dto.VolumePruneDto
 	err := c.ShouldBind(&params)
 	if err != nil {
-		utils.ResError(c, http.StatusBadRequest, err.Error())
+		return nil, errdefs.InvalidParameter(err)
 	}
-	filter := filters.NewArgs()
-	res, err := a.SDK.VolumesPrune(c, filter)
+	filter := scopeFilter(c, filters.NewArgs())
+	res, err := sdk(c).VolumesPrune(c, filter)
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
 	// 清理非匿名未使用卷
 	if params.All {
-		volumeList, err := a.SDK.VolumeList(c, volume.ListOptions{})
+		volumeList, err := sdk(c).VolumeList(c, volume.ListOptions{Filters: filter})
 		if err != nil {
-			utils.ResError(c, http.StatusInternalServerError, err.Error())
+			return nil, err
 		}
 		var unUseVolume []string
-		containerList, err := a.SDK.ContainerList(c, container.ListOptions{
+		containerList, err := sdk(c).ContainerList(c, container.ListOptions{
 			All:    true,
 			Latest: true,
 		})
 		if err != nil {
-			utils.ResError(c, http.StatusInternalServerError, err.Error())
+			return nil, err
 		}
 		for _, item := range volumeList.Volumes {
 			has := false
@@ -135,34 +176,47 @@ func (a *Volume) Prune(c *gin.Context) {
 		}
 
 		for _, item := range unUseVolume {
-			res.VolumesDeleted = append(res.VolumesDeleted, item)
-			err = a.SDK.VolumeRemove(c, item, false)
-			if err != nil {
-				utils.ResError(c, http.StatusInternalServerError, err.Error())
+			if err = sdk(c).VolumeRemove(c, item, false); err != nil {
+				return nil, err
 			}
+			res.VolumesDeleted = append(res.VolumesDeleted, item)
 		}
 	}
-	utils.ResSuccess(c, res)
+	return res, nil
 }
 
-func (a *Volume) Delete(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		utils.ResError(c, http.StatusBadRequest, "id is required")
+func (a *Volume) Delete(c *gin.Context) (interface{}, error) {
+	id, err := pathParam(c, "id")
+	if err != nil {
+		return nil, err
 	}
-	err := a.SDK.VolumeRemove(c, id, false)
+	return nil, sdk(c).VolumeRemove(c, id, false)
+}
+
+// mountedVolumes 返回被任意容器挂载的存储卷名称
+func mountedVolumes(c *gin.Context, client docker.Engine) (map[string]bool, error) {
+	containerList, err := client.ContainerList(c, container.ListOptions{All: true})
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
+	result := make(map[string]bool)
+	for _, item := range containerList {
+		for _, mount := range item.Mounts {
+			if mount.Name != "" {
+				result[mount.Name] = true
+			}
+		}
+	}
+	return result, nil
 }
 
-func inUse(c *gin.Context, client *client.Client, name string) []map[string]interface{} {
+func inUse(c *gin.Context, client docker.Engine, name string) ([]map[string]interface{}, error) {
 	containerList, err := client.ContainerList(c, container.ListOptions{
 		All:    true,
 		Latest: true,
 	})
 	if err != nil {
-		utils.ResError(c, http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
 
 	var inUseContainer []map[string]interface{}
@@ -179,6 +233,5 @@ func inUse(c *gin.Context, client *client.Client, name string) []map[string]inte
 			}
 		}
 	}
-	return inUseContainer
-
+	return inUseContainer, nil
 }
@@ -0,0 +1,262 @@
+package docker_test
+
+import (
+	"archive/tar"
+	"context"
+	"cyber-docker/pkg/docker/fake"
+	"encoding/json"
+	"errors"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+	"github.com/gorilla/websocket"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func inspectContainer(t *testing.T, e *fake.Engine, id string) container.InspectResponse {
+	t.Helper()
+	info, err := e.ContainerInspect(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestContainers(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		var created struct {
+			ID      string `json:"id"`
+			Pulled  bool   `json:"pulled"`
+			Started bool   `json:"started"`
+		}
+		s.ok("POST", prefix+"/containers", `{"image":"redis:7","name":"cache","start":true,"env":["A=1"],"ports":[{"container_port":"6379","host_port":"6379"}]}`, &created)
+		if created.Pulled || !created.Started {
+			t.Fatalf("created container = %+v", created)
+		}
+		if info := inspectContainer(t, e, "cache"); !info.State.Running {
+			t.Fatalf("created container state = %+v", info.State)
+		}
+		s.fail("POST", prefix+"/containers", `{"image":"redis:7","pull":"sometimes"}`, http.StatusBadRequest)
+		s.fail("POST", prefix+"/containers", `{"image":"missing:1","pull":"never"}`, http.StatusNotFound)
+
+		var list []container.Summary
+		s.ok("GET", prefix+"/containers?sort_by=name", "", &list)
+		if len(list) != 2 || list[0].Names[0] != "/cache" || list[1].Names[0] != "/web" {
+			t.Fatalf("containers = %+v", list)
+		}
+		s.ok("GET", prefix+"/containers?status=running", "", &list)
+		if len(list) != 1 || list[0].ID != created.ID {
+			t.Fatalf("running containers = %+v", list)
+		}
+
+		var info container.InspectResponse
+		s.ok("GET", prefix+"/containers/web", "", &info)
+		if info.Name != "/web" {
+			t.Fatalf("container = %+v", info)
+		}
+		s.fail("GET", prefix+"/containers/missing", "", http.StatusNotFound)
+
+		s.ok("PUT", prefix+"/containers/web", `{"name":"web2","restart_policy":{"name":"always"}}`, nil)
+		info = inspectContainer(t, e, "web2")
+		if info.HostConfig.RestartPolicy.Name != container.RestartPolicyAlways {
+			t.Fatalf("restart policy = %+v", info.HostConfig.RestartPolicy)
+		}
+
+		s.ok("PUT", prefix+"/containers/web2/snapshot:v1", "", nil)
+		if _, err := e.ImageInspect(context.Background(), "snapshot:v1"); err != nil {
+			t.Fatalf("committed image: %v", err)
+		}
+
+		s.ok("DELETE", prefix+"/containers/web2/x", `{"delete_volume":true}`, nil)
+		if _, err := e.ContainerInspect(context.Background(), "web2"); !errdefs.IsNotFound(err) {
+			t.Fatalf("deleted container: %v", err)
+		}
+		if _, err := e.VolumeInspect(context.Background(), "data"); !errdefs.IsNotFound(err) {
+			t.Fatalf("volume of deleted container: %v", err)
+		}
+
+		s.ok("PATCH", prefix+"/containers/cache/stat", "", nil)
+		var report container.PruneReport
+		s.ok("DELETE", prefix+"/containers", "", &report)
+		if len(report.ContainersDeleted) != 1 || report.ContainersDeleted[0] != created.ID {
+			t.Fatalf("pruned containers = %+v", report.ContainersDeleted)
+		}
+	})
+}
+
+func TestContainerLifecycle(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		s.ok("PUT", prefix+"/containers/web/stat", "", nil)
+		if !inspectContainer(t, e, "web").State.Running {
+			t.Fatal("container is not started")
+		}
+		s.ok("PATCH", prefix+"/containers/web/stat", "", nil)
+		if inspectContainer(t, e, "web").State.Running {
+			t.Fatal("container is not stopped")
+		}
+
+		var result struct {
+			State *container.State `json:"state"`
+		}
+		for _, action := range []struct {
+			body  string
+			state string
+		}{
+			{`{"action":"start"}`, "running"},
+			{`{"action":"pause"}`, "paused"},
+			{`{"action":"unpause"}`, "running"},
+			{`{"action":"restart","timeout":1}`, "running"},
+			{`{"action":"kill","signal":"SIGKILL"}`, "exited"},
+		} {
+			s.ok("POST", prefix+"/containers/web/actions", action.body, &result)
+			if result.State == nil || result.State.Status != action.state {
+				t.Fatalf("%s: state = %+v, want %s", action.body, result.State, action.state)
+			}
+		}
+		s.fail("POST", prefix+"/containers/web/actions", `{"action":"explode"}`, http.StatusBadRequest)
+		s.fail("POST", prefix+"/containers/missing/actions", `{"action":"start"}`, http.StatusNotFound)
+
+		if _, err := e.AddContainer("worker", "redis:7", &container.Config{Labels: map[string]string{"team": "search"}}, nil); err != nil {
+			t.Fatal(err)
+		}
+		var batch struct {
+			Total     int `json:"total"`
+			Succeeded int `json:"succeeded"`
+			Failed    int `json:"failed"`
+		}
+		s.ok("POST", prefix+"/containers/batch", `{"action":"start","ids":["web","worker","missing"]}`, &batch)
+		if batch.Total != 3 || batch.Succeeded != 2 || batch.Failed != 1 {
+			t.Fatalf("batch start = %+v", batch)
+		}
+		s.ok("POST", prefix+"/containers/batch", `{"action":"stop","labels":{"team":"search"},"timeout":1}`, &batch)
+		if batch.Total != 1 || batch.Succeeded != 1 {
+			t.Fatalf("batch stop = %+v", batch)
+		}
+		if inspectContainer(t, e, "worker").State.Running || !inspectContainer(t, e, "web").State.Running {
+			t.Fatal("batch stop affects containers outside the filter")
+		}
+		s.fail("POST", prefix+"/containers/batch", `{"action":"start"}`, http.StatusBadRequest)
+	})
+}
+
+func TestContainerOutput(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		s.ok("PUT", prefix+"/containers/web/stat", "", nil)
+		if err := e.AppendLog("web", true, "listening on :80"); err != nil {
+			t.Fatal(err)
+		}
+
+		var top container.TopResponse
+		s.ok("GET", prefix+"/containers/web/top", "", &top)
+		if len(top.Processes) == 0 {
+			t.Fatal("top returns no processes")
+		}
+
+		list := events(t, s.do("GET", prefix+"/containers/web/logs?tail=all", ""))
+		lastEvent(t, list)
+		found := false
+		for _, item := range list {
+			var line struct {
+				Stream string `json:"stream"`
+				Line   string `json:"line"`
+			}
+			if item.Event == "log" && json.Unmarshal(item.Data, &line) == nil && line.Stream == "stderr" && line.Line == "listening on :80" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("stderr log line is not streamed: %+v", list)
+		}
+		w := s.do("GET", prefix+"/containers/web/logs?format=text", "")
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "listening on :80") {
+			t.Fatalf("log download: status %d, body %s", w.Code, w.Body.String())
+		}
+		s.fail("GET", prefix+"/containers/web/logs?format=text&follow=true", "", http.StatusBadRequest)
+
+		w = s.do("GET", prefix+"/containers/web/file", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("export: status %d, body %s", w.Code, w.Body.String())
+		}
+		if _, err := tar.NewReader(w.Body).Next(); err != nil {
+			t.Fatalf("export is not a tar archive: %v", err)
+		}
+
+		// 统计数据是持续的流，请求在超时后结束
+		ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+		defer cancel()
+		w = s.serve(httptest.NewRequest("GET", prefix+"/containers/web/stat", nil).WithContext(ctx))
+		var stats container.StatsResponse
+		if err := json.NewDecoder(w.Body).Decode(&stats); err != nil || stats.Name != "/web" {
+			t.Fatalf("container stats = %+v, %v", stats, err)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 2500*time.Millisecond)
+		defer cancel()
+		list = events(t, s.serve(httptest.NewRequest("GET", prefix+"/containers/stats?interval=1", nil).WithContext(ctx)))
+		var snapshot struct {
+			Containers []struct {
+				Name string `json:"name"`
+			} `json:"containers"`
+		}
+		if len(list) == 0 || list[0].Event != "stats" || json.Unmarshal(list[0].Data, &snapshot) != nil {
+			t.Fatalf("stats events = %+v", list)
+		}
+		if len(snapshot.Containers) != 1 || snapshot.Containers[0].Name != "web" {
+			t.Fatalf("stats snapshot = %+v", snapshot)
+		}
+	})
+}
+
+func TestContainerExec(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		s.fail("GET", prefix+"/containers/web/exec", "", http.StatusBadRequest)
+		s.ok("PUT", prefix+"/containers/web/stat", "", nil)
+		if err := e.SetExecutables("web", "sh"); err != nil {
+			t.Fatal(err)
+		}
+
+		srv := httptest.NewServer(s.engine)
+		defer srv.Close()
+		url := "ws" + strings.TrimPrefix(srv.URL, "http") + prefix + "/containers/web/exec?rows=24&cols=80"
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err = conn.WriteMessage(websocket.BinaryMessage, []byte("echo hello\rexit\r")); err != nil {
+			t.Fatal(err)
+		}
+		var output strings.Builder
+		for {
+			kind, data, err := conn.ReadMessage()
+			if err != nil {
+				var closeErr *websocket.CloseError
+				if !errors.As(err, &closeErr) && !errors.Is(err, io.EOF) {
+					t.Fatalf("read: %v, output %q", err, output.String())
+				}
+				break
+			}
+			if kind == websocket.BinaryMessage {
+				output.Write(data)
+			}
+		}
+		if !strings.Contains(output.String(), "hello\r\n") {
+			t.Fatalf("exec output = %q", output.String())
+		}
+
+		if err = e.SetExecutables("web"); err != nil {
+			t.Fatal(err)
+		}
+		_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("exec without shell: %v", err)
+		}
+	})
+}
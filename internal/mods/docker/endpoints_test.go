@@ -0,0 +1,65 @@
+package docker_test
+
+import (
+	"cyber-docker/pkg/docker"
+	"net/http"
+	"testing"
+)
+
+func TestEndpoints(t *testing.T) {
+	s := newTestServer(t)
+
+	var list []docker.EndpointInfo
+	s.ok("GET", "/api/v1/endpoints", "", &list)
+	if len(list) != 2 || !list[0].Static || !list[1].Static {
+		t.Fatalf("config endpoints = %+v", list)
+	}
+
+	var info docker.EndpointInfo
+	s.ok("POST", "/api/v1/endpoints", `{"name":"staging","host":"tcp://10.0.0.3:2376","labels":{"env":"staging"}}`, &info)
+	if info.Name != "staging" || info.Static {
+		t.Fatalf("created endpoint = %+v", info)
+	}
+	s.fail("POST", "/api/v1/endpoints", `{"name":"staging","host":"tcp://10.0.0.3:2376"}`, http.StatusConflict)
+	s.fail("POST", "/api/v1/endpoints", `{"name":"bad","host":"tcp://10.0.0.4:2376","tls":{"ca_cert":"invalid"}}`, http.StatusBadRequest)
+
+	s.factory.Engine("down").Close()
+	s.fail("POST", "/api/v1/endpoints", `{"name":"down","host":"tcp://10.0.0.5:2376"}`, http.StatusServiceUnavailable)
+	s.fail("GET", "/api/v1/endpoints/down", "", http.StatusNotFound)
+
+	s.ok("GET", "/api/v1/endpoints?label=env=staging", "", &list)
+	if len(list) != 1 || list[0].Name != "staging" {
+		t.Fatalf("endpoints with label env=staging = %+v", list)
+	}
+	s.ok("GET", "/api/v1/endpoints/staging", "", &info)
+	s.ok("GET", "/api/v1/endpoints/staging/ping", "", &info)
+	if info.Status == nil || !info.Status.Healthy {
+		t.Fatalf("endpoint status after ping = %+v", info.Status)
+	}
+
+	// 修改端点会重新创建客户端，状态仍然保留在端点的引擎上
+	s.ok("POST", "/api/v1/endpoints/staging/volumes", `{"name":"kept"}`, nil)
+	s.ok("PUT", "/api/v1/endpoints/staging", `{"host":"tcp://10.0.0.6:2376"}`, &info)
+	if info.Host != "tcp://10.0.0.6:2376" {
+		t.Fatalf("updated endpoint = %+v", info)
+	}
+	s.ok("GET", "/api/v1/endpoints/staging/volumes/kept", "", nil)
+
+	for _, name := range []string{"local", remote} {
+		s.fail("PUT", "/api/v1/endpoints/"+name, `{"host":"tcp://10.0.0.7:2376"}`, http.StatusConflict)
+		s.fail("DELETE", "/api/v1/endpoints/"+name, "", http.StatusConflict)
+	}
+
+	s.ok("DELETE", "/api/v1/endpoints/staging", "", nil)
+	s.fail("DELETE", "/api/v1/endpoints/staging", "", http.StatusNotFound)
+	s.fail("GET", "/api/v1/endpoints/staging/ping", "", http.StatusNotFound)
+	s.fail("GET", "/api/v1/endpoints/staging/volumes", "", http.StatusNotFound)
+}
+
+func TestHealth(t *testing.T) {
+	s := newTestServer(t)
+	w := s.do("GET", "/api/v1/health", "")
+	if w.Code != http.StatusOK || w.Body.String() != `{"status":"ok"}` {
+		t.Fatalf("health: status %d, body %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+const (
+	BuildRunning   = "running"
+	BuildSucceeded = "succeeded"
+	BuildFailed    = "failed"
+)
+
+// BuildRecord 记录一次镜像构建的参数和结果
+type BuildRecord struct {
+	ID         string            `json:"id"`
+	Endpoint   string            `json:"endpoint"`
+	Tags       []string          `json:"tags"`
+	Target     string            `json:"target,omitempty"`
+	Platform   string            `json:"platform,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Status     string            `json:"status"`
+	ImageID    string            `json:"image_id,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
@@ -1,9 +1,17 @@
 package dto
 
+import "cyber-docker/pkg/query"
+
 type ContainerDto struct {
 	Sha string `json:"sha"`
 }
 
+// ContainerListDto 状态为 created、running、paused、restarting、exited、dead 等
+type ContainerListDto struct {
+	query.Params
+	Sha string `form:"sha"`
+}
+
 type ContainerUpdateDto struct {
 	ContainerDto
 	Name          string                  `json:"name"`
@@ -17,11 +25,162 @@ type ContainerRestartPolicy struct {
 
 type ContainerDeleteDto struct {
 	ContainerDto
-	DeleteVolume bool `json:"delete_volume" binding:"required"`
-	DeleteLink   bool `json:"delete_link" binding:"required"`
+	DeleteVolume bool `json:"delete_volume"`
+	DeleteLink   bool `json:"delete_link"`
 }
 
 type ContainerCommitDto struct {
 	ContainerDto
 	Name string `json:"name" binding:"required"`
 }
+
+type ContainerActionDto struct {
+	Action string `json:"action" binding:"required,oneof=start stop restart pause unpause kill wait"`
+	// stop、restart 等待容器退出的秒数，超时后强制结束，为空时使用容器的 StopTimeout；
+	// wait 最长等待的秒数，为空时等待 30 秒
+	Timeout *int `json:"timeout" binding:"omitempty,min=0,max=3600"`
+	// stop、restart、kill 发送的信号，例如 SIGTERM、SIGHUP、9，kill 为空时发送 SIGKILL
+	Signal string `json:"signal"`
+	// wait 等待的条件：not-running 容器未运行，next-exit 下一次退出，removed 容器被删除
+	Condition string `json:"condition" binding:"omitempty,oneof=not-running next-exit removed"`
+}
+
+// ContainerBatchDto 目标容器通过 ids 指定，或者通过 labels、name 过滤，两种方式不能同时使用
+type ContainerBatchDto struct {
+	Action string `json:"action" binding:"required,oneof=start stop restart delete update"`
+	// 容器 ID 或名称
+	IDs []string `json:"ids"`
+	// 例如 {"team": "payments"}，值为空时只要求存在该标签
+	Labels map[string]string `json:"labels"`
+	// 名称包含该字符串的容器
+	Name string `json:"name"`
+	// stop、restart 的超时秒数和信号
+	Timeout *int   `json:"timeout" binding:"omitempty,min=0,max=3600"`
+	Signal  string `json:"signal"`
+	// delete 是否同时删除存储卷和链接
+	DeleteVolume bool `json:"delete_volume"`
+	DeleteLink   bool `json:"delete_link"`
+	// update 设置的重启策略，update 时必填
+	RestartPolicy *ContainerRestartPolicy `json:"restart_policy,omitempty"`
+	// 同时操作的容器数量，默认为 4
+	Concurrency int `json:"concurrency" binding:"omitempty,min=1,max=32"`
+	// 为 true 时只返回目标容器，不执行操作
+	DryRun bool `json:"dry_run"`
+}
+
+// ContainerStatsDto 不指定过滤条件时订阅所有运行中的容器
+type ContainerStatsDto struct {
+	// 推送间隔秒数，默认为 2
+	Interval int `form:"interval" binding:"omitempty,min=1,max=60"`
+	// 容器 ID 或 ID 前缀
+	IDs []string `form:"id"`
+	// key 或 key=value
+	Label []string `form:"label"`
+	// 名称包含该字符串的容器
+	Name string `form:"name"`
+}
+
+type ContainerCreateDto struct {
+	Image string `json:"image" binding:"required"`
+	Name  string `json:"name"`
+	// 镜像不存在时是否拉取：missing 仅在本地不存在时拉取，always 总是拉取，never 不拉取
+	Pull       string   `json:"pull" binding:"omitempty,oneof=missing always never"`
+	Platform   string   `json:"platform"`
+	Start      bool     `json:"start"`
+	Cmd        []string `json:"cmd"`
+	Entrypoint []string `json:"entrypoint"`
+	// KEY=VALUE 格式
+	Env           []string                 `json:"env"`
+	Labels        map[string]string        `json:"labels"`
+	Hostname      string                   `json:"hostname"`
+	User          string                   `json:"user"`
+	WorkingDir    string                   `json:"working_dir"`
+	Ports         []ContainerPortDto       `json:"ports" binding:"dive"`
+	Mounts        []ContainerMountDto      `json:"mounts" binding:"dive"`
+	Networks      []ContainerNetworkDto    `json:"networks" binding:"dive"`
+	RestartPolicy *ContainerRestartPolicy  `json:"restart_policy,omitempty"`
+	Resources     *ContainerResourceDto    `json:"resources,omitempty"`
+	Healthcheck   *ContainerHealthcheckDto `json:"healthcheck,omitempty"`
+	CapAdd        []string                 `json:"cap_add"`
+	CapDrop       []string                 `json:"cap_drop"`
+	Privileged    bool                     `json:"privileged"`
+}
+
+type ContainerPortDto struct {
+	HostIP        string `json:"host_ip"`
+	HostPort      string `json:"host_port"`
+	ContainerPort string `json:"container_port" binding:"required"`
+	Protocol      string `json:"protocol" binding:"omitempty,oneof=tcp udp sctp"`
+}
+
+type ContainerMountDto struct {
+	Type string `json:"type" binding:"required,oneof=volume bind tmpfs"`
+	// volume 为卷名，为空时创建匿名卷；bind 为宿主机绝对路径
+	Source   string `json:"source"`
+	Target   string `json:"target" binding:"required"`
+	ReadOnly bool   `json:"read_only"`
+	// 仅 tmpfs 使用，单位字节
+	TmpfsSize int64  `json:"tmpfs_size"`
+	TmpfsMode uint32 `json:"tmpfs_mode"`
+}
+
+type ContainerNetworkDto struct {
+	Name    string   `json:"name" binding:"required"`
+	Aliases []string `json:"aliases"`
+	IpV4    string   `json:"ipV4"`
+	IpV6    string   `json:"ipV6"`
+}
+
+type ContainerResourceDto struct {
+	// CPU 核数，例如 1.5
+	Cpus      float64 `json:"cpus"`
+	CpuShares int64   `json:"cpu_shares"`
+	// 内存相关单位为字节
+	Memory            int64  `json:"memory"`
+	MemoryReservation int64  `json:"memory_reservation"`
+	MemorySwap        int64  `json:"memory_swap"`
+	PidsLimit         *int64 `json:"pids_limit"`
+}
+
+type ContainerHealthcheckDto struct {
+	// 例如 ["CMD-SHELL", "curl -f http://localhost/ || exit 1"]，["NONE"] 表示禁用镜像自带的检查
+	Test []string `json:"test" binding:"required"`
+	// 时间单位为秒
+	Interval    int `json:"interval"`
+	Timeout     int `json:"timeout"`
+	StartPeriod int `json:"start_period"`
+	Retries     int `json:"retries"`
+}
+
+type ContainerLogsDto struct {
+	Follow bool `json:"follow" form:"follow"`
+	// 行数或 all
+	Tail string `json:"tail" form:"tail"`
+	// RFC3339、Unix 时间戳或相对时长（例如 10m）
+	Since      string `json:"since" form:"since"`
+	Until      string `json:"until" form:"until"`
+	Timestamps bool   `json:"timestamps" form:"timestamps"`
+	Stdout     *bool  `json:"stdout" form:"stdout"`
+	Stderr     *bool  `json:"stderr" form:"stderr"`
+	// sse 逐行推送（WebSocket 升级请求忽略该参数），text、gzip 下载日志文件
+	Format string `json:"format" form:"format" binding:"omitempty,oneof=sse text gzip"`
+}
+
+type ContainerExecDto struct {
+	// 按顺序尝试的命令，例如 cmd=bash&cmd=sh，参数以空格分隔；为空时依次尝试 /bin/bash、/bin/sh
+	Cmd        []string `json:"cmd" form:"cmd"`
+	User       string   `json:"user" form:"user"`
+	Env        []string `json:"env" form:"env"`
+	WorkingDir string   `json:"working_dir" form:"working_dir"`
+	// 初始终端大小
+	Rows uint `json:"rows" form:"rows"`
+	Cols uint `json:"cols" form:"cols"`
+}
+
+// ContainerExecMessage 是终端 WebSocket 上客户端发送的文本消息，二进制消息直接作为输入
+type ContainerExecMessage struct {
+	Type string `json:"type" binding:"required,oneof=input resize"`
+	Data string `json:"data"`
+	Rows uint   `json:"rows"`
+	Cols uint   `json:"cols"`
+}
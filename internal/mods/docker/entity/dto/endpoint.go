@@ -0,0 +1,25 @@
+package dto
+
+type EndpointTLSDto struct {
+	CACert     string `json:"ca_cert"`
+	Cert       string `json:"cert"`
+	Key        string `json:"key"`
+	SkipVerify bool   `json:"skip_verify"`
+}
+
+type EndpointCreateDto struct {
+	Name   string            `json:"name" binding:"required"`
+	Host   string            `json:"host" binding:"required"`
+	TLS    *EndpointTLSDto   `json:"tls"`
+	Labels map[string]string `json:"labels"`
+}
+
+type EndpointUpdateDto struct {
+	Host   string            `json:"host" binding:"required"`
+	TLS    *EndpointTLSDto   `json:"tls"`
+	Labels map[string]string `json:"labels"`
+}
+
+type EndpointListDto struct {
+	Label string `json:"label" form:"label"`
+}
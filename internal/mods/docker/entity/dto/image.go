@@ -1,9 +1,16 @@
 package dto
 
+import "cyber-docker/pkg/query"
+
 type ImageDto struct {
 	Sha string `json:"sha" form:"sha"`
 }
 
+// ImageListDto 状态为 tagged 或 dangling
+type ImageListDto struct {
+	query.Params
+}
+
 type ImageGetDto struct {
 	Layer bool `json:"layer" form:"layer"`
 }
@@ -19,5 +26,82 @@ type ImagePruneDto struct {
 }
 
 type ImageImportDto struct {
-	Container bool `json:"container,omitempty" form:"container" binding:"required"`
+	// 为 true 时导入容器导出的文件系统 tar 包，否则导入 docker save 的镜像 tar 包
+	Container bool `json:"container,omitempty" form:"container"`
+	// 以下仅导入容器文件系统时使用
+	Repository string `json:"repository" form:"repository"`
+	Tag        string `json:"tag" form:"tag"`
+	Message    string `json:"message" form:"message"`
+	// Dockerfile 指令，例如 CMD ["nginx"]、ENV A=1、EXPOSE 80
+	Changes  []string `json:"changes" form:"changes"`
+	Platform string   `json:"platform" form:"platform"`
+}
+
+// RegistryAuthDto 镜像仓库凭据，IdentityToken 与用户名密码二选一
+type RegistryAuthDto struct {
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identity_token"`
+	ServerAddress string `json:"server_address"`
+}
+
+type ImagePullDto struct {
+	Reference string `json:"reference" binding:"required"`
+	// os[/arch[/variant]]，例如 linux/arm64
+	Platform string           `json:"platform"`
+	Auth     *RegistryAuthDto `json:"auth"`
+}
+
+type ImageCheckUpgradeDto struct {
+	// 为空时按仓库地址使用已保存的凭据
+	Auth *RegistryAuthDto `json:"auth"`
+}
+
+// ImageBuildDto 以 multipart 表单提交，构建上下文为上传的 context 文件（tar 或 tar.gz），
+// 或者内联的 dockerfile 加上 files 中上传的文件
+type ImageBuildDto struct {
+	Dockerfile string `form:"dockerfile"`
+	// 上下文中 Dockerfile 的路径，默认为 Dockerfile
+	DockerfilePath string   `form:"dockerfile_path"`
+	Tags           []string `form:"tags"`
+	// KEY=VALUE 格式
+	BuildArgs []string `form:"build_args"`
+	Labels    []string `form:"labels"`
+	Target    string   `form:"target"`
+	NoCache   bool     `form:"no_cache"`
+	Pull      bool     `form:"pull"`
+	Platform  string   `form:"platform"`
+}
+
+type ImageTagDto struct {
+	// 镜像 ID 或引用
+	Source string `json:"source" binding:"required"`
+	Tag    string `json:"tag" binding:"required"`
+}
+
+type ImageUntagDto struct {
+	Tag string `json:"tag" form:"tag" binding:"required"`
+}
+
+type ImagePushDto struct {
+	Reference string           `json:"reference" binding:"required"`
+	Auth      *RegistryAuthDto `json:"auth"`
+}
+
+// ImagePromoteDto 把多个镜像重新打上目标仓库的 tag 并推送
+type ImagePromoteDto struct {
+	Images []string `json:"images" binding:"required,min=1"`
+	// 目标仓库地址，例如 registry.example.com:5000
+	Registry string `json:"registry" binding:"required"`
+	// 目标命名空间，为空时沿用源镜像的路径
+	Namespace string           `json:"namespace"`
+	Auth      *RegistryAuthDto `json:"auth"`
+}
+
+type ImageExportDto struct {
+	Refs []string `form:"ref" binding:"required,min=1"`
+	// 压缩格式，默认不压缩
+	Compress string `form:"compress" binding:"omitempty,oneof=none gzip zstd"`
+	// os[/arch[/variant]]，只导出该平台的镜像
+	Platform string `form:"platform"`
 }
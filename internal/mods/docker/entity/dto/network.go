@@ -1,11 +1,14 @@
 package dto
 
+import "cyber-docker/pkg/query"
+
 type NetworkDto struct {
 	Sha string `json:"sha"`
 }
 
 type NetworkListDto struct {
 	NetworkDto
+	query.Params
 	Name string `json:"name" form:"name"`
 }
 
@@ -0,0 +1,18 @@
+package dto
+
+// RegistryCreateDto Username 为空时 Secret 作为 IdentityToken 使用
+type RegistryCreateDto struct {
+	Host     string `json:"host" binding:"required"`
+	Username string `json:"username"`
+	Secret   string `json:"secret"`
+	Insecure bool   `json:"insecure"`
+	Mirror   string `json:"mirror"`
+}
+
+// RegistryUpdateDto Secret 为空时保留原来的密码
+type RegistryUpdateDto struct {
+	Username string `json:"username"`
+	Secret   string `json:"secret"`
+	Insecure bool   `json:"insecure"`
+	Mirror   string `json:"mirror"`
+}
@@ -1,11 +1,15 @@
 package dto
 
+import "cyber-docker/pkg/query"
+
 type VolumeDto struct {
 	Sha string `json:"sha"`
 }
 
+// VolumeListDto 状态为 in-use 或 unused
 type VolumeListDto struct {
 	VolumeDto
+	query.Params
 	Name string `json:"name" form:"name"`
 }
 
@@ -0,0 +1,307 @@
+package docker_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"cyber-docker/pkg/docker/fake"
+	"encoding/json"
+	"github.com/docker/docker/api/types/image"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// multipartRequest 构造表单请求，files 的 key 为表单字段，值为文件名到内容的映射
+func multipartRequest(t *testing.T, path string, fields map[string][]string, files map[string]map[string][]byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for key, values := range fields {
+		for _, value := range values {
+			if err := w.WriteField(key, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for key, list := range files {
+		for name, content := range list {
+			part, err := w.CreateFormFile(key, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = part.Write(content)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func imageExists(e *fake.Engine, ref string) bool {
+	_, err := e.ImageInspect(context.Background(), ref)
+	return err == nil
+}
+
+func TestImages(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		var list []image.Summary
+		s.ok("GET", prefix+"/images?status=tagged&sort_by=size&order=desc", "", &list)
+		if len(list) != 2 || list[0].RepoTags[0] != "nginx:1.25" {
+			t.Fatalf("images = %+v", list)
+		}
+
+		var info struct {
+			Info  image.InspectResponse       `json:"info"`
+			Layer []image.HistoryResponseItem `json:"layer"`
+		}
+		s.ok("GET", prefix+"/images/nginx:1.25?layer=true", "", &info)
+		if info.Info.Config.Labels["tier"] != "web" || len(info.Layer) == 0 {
+			t.Fatalf("image = %+v", info)
+		}
+		s.fail("GET", prefix+"/images/missing:1", "", http.StatusNotFound)
+
+		s.ok("POST", prefix+"/images/tags", `{"source":"redis:7","tag":"cache:latest"}`, nil)
+		if !imageExists(e, "cache:latest") {
+			t.Fatal("tag is not added on the endpoint")
+		}
+		s.fail("POST", prefix+"/images/tags", `{"source":"redis:7","tag":"Invalid Tag"}`, http.StatusBadRequest)
+		s.ok("DELETE", prefix+"/images/tags?tag=cache:latest", "", nil)
+		if imageExists(e, "cache:latest") || !imageExists(e, "redis:7") {
+			t.Fatal("untag removes the wrong reference")
+		}
+		s.fail("DELETE", prefix+"/images/tags?tag=redis:7", "", http.StatusConflict)
+
+		// 被容器使用的镜像不能删除
+		s.fail("DELETE", prefix+"/images/nginx:1.25", "", http.StatusConflict)
+		s.ok("DELETE", prefix+"/images/redis:7", "", nil)
+		if imageExists(e, "redis:7") {
+			t.Fatal("image is not deleted")
+		}
+
+		if _, err := e.AddImage("unused:1", 300, nil); err != nil {
+			t.Fatal(err)
+		}
+		var pruned struct {
+			Count string `json:"count"`
+		}
+		s.ok("DELETE", prefix+"/images", "", &pruned)
+		if pruned.Count != "1" || imageExists(e, "unused:1") || !imageExists(e, "nginx:1.25") {
+			t.Fatalf("pruned images = %+v", pruned)
+		}
+	})
+}
+
+func TestImageRegistry(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		e.AddRemoteImage("postgres:16", 2000, nil)
+		list := events(t, s.do("POST", prefix+"/images/pull", `{"reference":"postgres:16"}`))
+		var pulled struct {
+			Reference string `json:"reference"`
+			Digest    string `json:"digest"`
+		}
+		if err := json.Unmarshal(lastEvent(t, list).Data, &pulled); err != nil || pulled.Digest == "" {
+			t.Fatalf("pull result = %+v, %v", pulled, err)
+		}
+		if !imageExists(e, "postgres:16") {
+			t.Fatal("image is not pulled on the endpoint")
+		}
+		s.fail("POST", prefix+"/images/pull", `{"reference":"missing:1"}`, http.StatusNotFound)
+
+		s.ok("POST", prefix+"/images/tags", `{"source":"nginx:1.25","tag":"registry.example.com/web:1"}`, nil)
+		list = events(t, s.do("POST", prefix+"/images/push", `{"reference":"registry.example.com/web:1"}`))
+		var pushed struct {
+			Digest string `json:"digest"`
+		}
+		if err := json.Unmarshal(lastEvent(t, list).Data, &pushed); err != nil || pushed.Digest == "" {
+			t.Fatalf("push result = %+v, %v", pushed, err)
+		}
+		s.fail("POST", prefix+"/images/push", `{"reference":"registry.example.com/missing:1"}`, http.StatusNotFound)
+
+		list = events(t, s.do("POST", prefix+"/images/promote", `{"images":["nginx:1.25","missing:1"],"registry":"registry.example.com","namespace":"prod"}`))
+		var promoted []struct {
+			Target string `json:"target"`
+			Digest string `json:"digest"`
+			Error  string `json:"error"`
+		}
+		if err := json.Unmarshal(lastEvent(t, list).Data, &promoted); err != nil {
+			t.Fatal(err)
+		}
+		if len(promoted) != 2 || promoted[0].Target != "registry.example.com/prod/nginx:1.25" || promoted[0].Digest == "" || promoted[1].Error == "" {
+			t.Fatalf("promote result = %+v", promoted)
+		}
+		if _, err := e.DistributionInspect(context.Background(), "registry.example.com/prod/nginx:1.25", ""); err != nil {
+			t.Fatalf("promoted image is not in the registry: %v", err)
+		}
+	})
+}
+
+func TestImageCheckUpgrade(t *testing.T) {
+	amd64 := ocispec.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := ocispec.Platform{OS: "linux", Architecture: "arm64"}
+	auth := `{"auth":{"username":"ci","password":"secret"}}`
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		type tagUpgrade struct {
+			Status       string `json:"status"`
+			LocalDigest  string `json:"local_digest"`
+			RemoteDigest string `json:"remote_digest"`
+			Message      string `json:"message"`
+		}
+		check := func(id, body string) tagUpgrade {
+			t.Helper()
+			var result struct {
+				Tags []tagUpgrade `json:"tags"`
+			}
+			s.ok("PUT", prefix+"/images/"+id, body, &result)
+			if len(result.Tags) != 1 {
+				t.Fatalf("upgrade tags = %+v", result.Tags)
+			}
+			return result.Tags[0]
+		}
+
+		e.AddRegistryUser("registry.example.com", "ci", "secret")
+		pulled := e.AddRemoteImage("registry.example.com/app:1", 2000, nil)
+		events(t, s.do("POST", prefix+"/images/pull", `{"reference":"registry.example.com/app:1",`+auth[1:]))
+		info, err := e.ImageInspect(context.Background(), "registry.example.com/app:1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := info.ID
+		if result := check(id, auth); result.Status != "up-to-date" || result.RemoteDigest != pulled {
+			t.Fatalf("after pull: %+v", result)
+		}
+		if result := check(id, ""); result.Status != "unknown" || result.Message == "" {
+			t.Fatalf("without credentials: %+v", result)
+		}
+
+		published := e.AddRemoteImage("registry.example.com/app:1", 2100, nil)
+		result := check(id, auth)
+		if result.Status != "outdated" || result.LocalDigest != pulled || result.RemoteDigest != published {
+			t.Fatalf("after publish: %+v", result)
+		}
+
+		// 多架构镜像中没有本地镜像的平台
+		if err = e.SetRemotePlatforms("registry.example.com/app:1", arm64); err != nil {
+			t.Fatal(err)
+		}
+		if result = check(id, auth); result.Status != "unknown" || !strings.Contains(result.Message, "linux/amd64 is not available") {
+			t.Fatalf("missing platform: %+v", result)
+		}
+		if err = e.SetRemotePlatforms("registry.example.com/app:1", arm64, amd64); err != nil {
+			t.Fatal(err)
+		}
+		if result = check(id, auth); result.Status != "outdated" {
+			t.Fatalf("manifest list: %+v", result)
+		}
+
+		if result = check("nginx:1.25", ""); result.Status != "unknown" {
+			t.Fatalf("image not in registry: %+v", result)
+		}
+	})
+}
+
+func TestImageBuild(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		dockerfile := "FROM nginx:1.25\nCOPY index.html /usr/share/nginx/html/\nCMD [\"nginx\"]\n"
+		req := multipartRequest(t, prefix+"/images/build",
+			map[string][]string{"dockerfile": {dockerfile}, "tags": {"site:1"}, "labels": {"team=web"}},
+			map[string]map[string][]byte{"files": {"index.html": []byte("<h1>hi</h1>")}})
+		list := events(t, s.serve(req))
+		var record struct {
+			ID      string `json:"id"`
+			Status  string `json:"status"`
+			ImageID string `json:"image_id"`
+		}
+		if err := json.Unmarshal(lastEvent(t, list).Data, &record); err != nil || record.Status != "succeeded" {
+			t.Fatalf("build record = %+v, %v", record, err)
+		}
+		if !imageExists(e, "site:1") {
+			t.Fatal("image is not built on the endpoint")
+		}
+
+		req = multipartRequest(t, prefix+"/images/build", nil, nil)
+		if w := s.serve(req); w.Code != http.StatusBadRequest {
+			t.Fatalf("build without context: status %d", w.Code)
+		}
+		req = multipartRequest(t, prefix+"/images/build",
+			map[string][]string{"dockerfile": {dockerfile}, "dockerfile_path": {"../Dockerfile"}, "tags": {"site:2"}}, nil)
+		if w := s.serve(req); w.Code != http.StatusBadRequest {
+			t.Fatalf("build with dockerfile outside the context: status %d", w.Code)
+		}
+		// 以 .. 开头的文件名仍在上下文目录中
+		req = multipartRequest(t, prefix+"/images/build",
+			map[string][]string{"dockerfile": {"FROM nginx:1.25\nCOPY ..index.html /usr/share/nginx/html/\n"}, "tags": {"site:2"}},
+			map[string]map[string][]byte{"files": {"..index.html": []byte("<h1>hi</h1>")}})
+		lastEvent(t, events(t, s.serve(req)))
+		if !imageExists(e, "site:2") {
+			t.Fatal("image with dotted file name is not built")
+		}
+
+		var builds []struct {
+			ID       string `json:"id"`
+			Endpoint string `json:"endpoint"`
+		}
+		s.ok("GET", prefix+"/images/builds", "", &builds)
+		if len(builds) != 2 || builds[1].ID != record.ID {
+			t.Fatalf("builds = %+v", builds)
+		}
+	})
+}
+
+func TestImageArchive(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		w := s.do("GET", prefix+"/images/export?ref=redis:7&compress=gzip", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("export: status %d, body %s", w.Code, w.Body.String())
+		}
+		if _, params, err := mime.ParseMediaType(w.Header().Get("Content-Disposition")); err != nil || params["filename"] != "redis_7.tar.gz" {
+			t.Fatalf("export disposition = %q", w.Header().Get("Content-Disposition"))
+		}
+		archive := w.Body.Bytes()
+		w = s.do("GET", prefix+"/images/export?ref=redis:7&compress=zstd", "")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zstd" || !bytes.HasPrefix(w.Body.Bytes(), []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+			t.Fatalf("zstd export: status %d, headers %v", w.Code, w.Header())
+		}
+		s.fail("GET", prefix+"/images/export?ref=missing:1", "", http.StatusNotFound)
+		s.fail("GET", prefix+"/images/export", "", http.StatusBadRequest)
+
+		s.ok("DELETE", prefix+"/images/redis:7", "", nil)
+		req := multipartRequest(t, prefix+"/images/file", nil, map[string]map[string][]byte{"file": {"redis.tar.gz": archive}})
+		lastEvent(t, events(t, s.serve(req)))
+		if !imageExists(e, "redis:7") {
+			t.Fatal("exported image is not imported")
+		}
+
+		rootfs, err := e.ContainerExport(context.Background(), "web")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rootfs)
+		_ = rootfs.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tar.NewReader(bytes.NewReader(content)).Next(); err != nil {
+			t.Fatal(err)
+		}
+		req = multipartRequest(t, prefix+"/images/file",
+			map[string][]string{"container": {"true"}, "repository": {"rootfs"}, "tag": {"v1"}, "changes": {`CMD ["nginx"]`}},
+			map[string]map[string][]byte{"file": {"rootfs.tar": content}})
+		lastEvent(t, events(t, s.serve(req)))
+		info, err := e.ImageInspect(context.Background(), "rootfs:v1")
+		if err != nil {
+			t.Fatalf("container filesystem is not imported: %v", err)
+		}
+		if len(info.Config.Cmd) != 1 || info.Config.Cmd[0] != "nginx" {
+			t.Fatalf("imported image cmd = %v", info.Config.Cmd)
+		}
+	})
+}
@@ -2,14 +2,18 @@ package docker
 
 import (
 	"cyber-docker/internal/mods/docker/api"
+	"cyber-docker/pkg/rbac"
+	"cyber-docker/pkg/utils"
 	"github.com/gin-gonic/gin"
 )
 
 type Docker struct {
+	EndpointApi  api.Endpoints
 	ImageApi     api.Images
 	ContainerApi api.Containers
 	NetworkApi   api.Network
 	VolumeApi    api.Volume
+	RegistryApi  api.Registries
 }
 
 func (a *Docker) RegisterV1Routers(v1 *gin.RouterGroup) {
@@ -19,48 +23,91 @@ func (a *Docker) RegisterV1Routers(v1 *gin.RouterGroup) {
 		})
 	})
 
+	endpoints := v1.Group("/endpoints")
+	{
+		endpoints.GET("", rbac.Require(rbac.EndpointsRead), utils.Handle(a.EndpointApi.List))
+		endpoints.POST("", rbac.Require(rbac.EndpointsManage), utils.Handle(a.EndpointApi.Create))
+		endpoints.GET("/:endpoint", rbac.Require(rbac.EndpointsRead), utils.Handle(a.EndpointApi.Inspect))
+		endpoints.PUT("/:endpoint", rbac.Require(rbac.EndpointsManage), utils.Handle(a.EndpointApi.Update))
+		endpoints.DELETE("/:endpoint", rbac.Require(rbac.EndpointsManage), utils.Handle(a.EndpointApi.Delete))
+		endpoints.GET("/:endpoint/ping", rbac.Require(rbac.EndpointsRead), utils.Handle(a.EndpointApi.Ping))
+	}
+
+	registries := v1.Group("/registries")
+	{
+		registries.GET("", rbac.Require(rbac.RegistriesRead), utils.Handle(a.RegistryApi.List))
+		registries.POST("", rbac.Require(rbac.RegistriesManage), utils.Handle(a.RegistryApi.Create))
+		registries.GET("/:host", rbac.Require(rbac.RegistriesRead), utils.Handle(a.RegistryApi.Inspect))
+		registries.PUT("/:host", rbac.Require(rbac.RegistriesManage), utils.Handle(a.RegistryApi.Update))
+		registries.DELETE("/:host", rbac.Require(rbac.RegistriesManage), utils.Handle(a.RegistryApi.Delete))
+	}
+
+	// 不带端点前缀的路由使用默认端点
+	a.registerResourceRouters(v1.Group("", a.EndpointApi.Select))
+	a.registerResourceRouters(endpoints.Group("/:endpoint", a.EndpointApi.Select))
+}
+
+func (a *Docker) registerResourceRouters(v1 *gin.RouterGroup) {
 	image := v1.Group("/images")
 	{
-		image.GET("", a.ImageApi.List)
-		image.GET("/:id", a.ImageApi.Inspect)
-		image.PUT("/:id", a.ImageApi.CheckUpgrade)
-		image.POST("/file", a.ImageApi.Import)
-		image.DELETE("", a.ImageApi.Prune)
-		image.DELETE("/:id", a.ImageApi.Delete)
+		image.GET("", rbac.Require(rbac.ImagesRead), utils.Handle(a.ImageApi.List))
+		image.GET("/:id", rbac.Require(rbac.ImagesRead), utils.Handle(a.ImageApi.Inspect))
+		image.PUT("/:id", rbac.Require(rbac.ImagesRead), utils.Handle(a.ImageApi.CheckUpgrade))
+		image.GET("/builds", rbac.Require(rbac.ImagesRead), utils.Handle(a.ImageApi.BuildList))
+		image.GET("/export", rbac.Require(rbac.ImagesExport), utils.Handle(a.ImageApi.Export))
+		image.POST("/build", rbac.Require(rbac.ImagesBuild), utils.Handle(a.ImageApi.Build))
+		image.POST("/pull", rbac.Require(rbac.ImagesPull), utils.Handle(a.ImageApi.Pull))
+		image.POST("/push", rbac.Require(rbac.ImagesPush), utils.Handle(a.ImageApi.Push))
+		image.POST("/promote", rbac.Require(rbac.ImagesTag, rbac.ImagesPush), utils.Handle(a.ImageApi.Promote))
+		image.POST("/tags", rbac.Require(rbac.ImagesTag), utils.Handle(a.ImageApi.Tag))
+		image.DELETE("/tags", rbac.Require(rbac.ImagesTag), utils.Handle(a.ImageApi.Untag))
+		image.POST("/file", rbac.Require(rbac.ImagesImport), utils.Handle(a.ImageApi.Import))
+		image.DELETE("", rbac.Require(rbac.ImagesPrune), utils.Handle(a.ImageApi.Prune))
+		image.DELETE("/:id", rbac.Require(rbac.ImagesDelete), utils.Handle(a.ImageApi.Delete))
 	}
 
-	containers := v1.Group("/containers")
+	containers := v1.Group("/containers", a.ContainerApi.Scope)
 	{
-		containers.GET("", a.ContainerApi.List)
-		containers.GET("/:id", a.ContainerApi.Inspect)
-		containers.GET("/:id/stat", a.ContainerApi.Stat)
-		containers.PUT("/:id/stat", a.ContainerApi.Start)
-		containers.PATCH("/:id/stat", a.ContainerApi.Stop)
-		containers.GET("/:id/top", a.ContainerApi.Top)
-		containers.PUT("/:id", a.ContainerApi.Update)
-		containers.PUT("/:id/:name", a.ContainerApi.Commit)
-		containers.GET("/:id/file", a.ContainerApi.Export)
-		containers.DELETE("", a.ContainerApi.Prune)
-		containers.DELETE("/:id/:name", a.ContainerApi.Delete)
+		containers.GET("", rbac.Require(rbac.ContainersRead), utils.Handle(a.ContainerApi.List))
+		containers.POST("", rbac.Require(rbac.ContainersCreate), utils.Handle(a.ContainerApi.Create))
+		containers.GET("/stats", rbac.Require(rbac.ContainersRead), utils.Handle(a.ContainerApi.StatsAll))
+		containers.GET("/:id", rbac.Require(rbac.ContainersRead), utils.Handle(a.ContainerApi.Inspect))
+		containers.GET("/:id/stat", rbac.Require(rbac.ContainersRead), utils.Handle(a.ContainerApi.Stat))
+		containers.PUT("/:id/stat", rbac.Require(rbac.ContainersStart), utils.Handle(a.ContainerApi.Start))
+		containers.PATCH("/:id/stat", rbac.Require(rbac.ContainersStop), utils.Handle(a.ContainerApi.Stop))
+		// 每个操作需要的权限不同，由 Action 和 Batch 自行检查
+		containers.POST("/batch", utils.Handle(a.ContainerApi.Batch))
+		containers.POST("/:id/actions", utils.Handle(a.ContainerApi.Action))
+		containers.GET("/:id/top", rbac.Require(rbac.ContainersRead), utils.Handle(a.ContainerApi.Top))
+		containers.GET("/:id/logs", rbac.Require(rbac.ContainersLogs), utils.Handle(a.ContainerApi.Logs))
+		containers.GET("/:id/exec", rbac.Require(rbac.ContainersExec), utils.Handle(a.ContainerApi.Exec))
+		containers.PUT("/:id", rbac.Require(rbac.ContainersUpdate), utils.Handle(a.ContainerApi.Update))
+		containers.PUT("/:id/:name", rbac.Require(rbac.ContainersCommit), utils.Handle(a.ContainerApi.Commit))
+		containers.GET("/:id/file", rbac.Require(rbac.ContainersExport), utils.Handle(a.ContainerApi.Export))
+		containers.DELETE("", rbac.Require(rbac.ContainersPrune), utils.Handle(a.ContainerApi.Prune))
+		containers.DELETE("/:id/:name", rbac.Require(rbac.ContainersDelete), utils.Handle(a.ContainerApi.Delete))
 	}
 
 	networks := v1.Group("/networks")
 	{
-		networks.GET("", a.NetworkApi.List)
-		networks.GET("/:id", a.NetworkApi.Inspect)
-		networks.POST("", a.NetworkApi.Create)
-		networks.PUT("/:id", a.NetworkApi.Connect)
-		networks.PATCH("/:id", a.NetworkApi.Disconnect)
-		networks.DELETE("", a.NetworkApi.Prune)
-		networks.DELETE("/:id", a.NetworkApi.Delete)
+		networks.GET("", rbac.Require(rbac.NetworksRead), utils.Handle(a.NetworkApi.List))
+		networks.GET("/:id", rbac.Require(rbac.NetworksRead), utils.Handle(a.NetworkApi.Inspect))
+		networks.POST("", rbac.Require(rbac.NetworksCreate), utils.Handle(a.NetworkApi.Create))
+		networks.PUT("/:id", rbac.Require(rbac.NetworksConnect), utils.Handle(a.NetworkApi.Connect))
+		networks.PATCH("/:id", rbac.Require(rbac.NetworksConnect), utils.Handle(a.NetworkApi.Disconnect))
+		networks.DELETE("", rbac.Require(rbac.NetworksPrune), utils.Handle(a.NetworkApi.Prune))
+		networks.DELETE("/:id", rbac.Require(rbac.NetworksDelete), utils.Handle(a.NetworkApi.Delete))
 	}
 
-	volumes := v1.Group("/volumes")
+	// 通过当前端点的 Docker 守护进程验证仓库凭据
+	v1.POST("/registries/:host/login", rbac.Require(rbac.RegistriesLogin), utils.Handle(a.RegistryApi.Login))
+
+	volumes := v1.Group("/volumes", a.VolumeApi.Scope)
 	{
-		volumes.GET("", a.VolumeApi.List)
-		volumes.GET("/:id", a.VolumeApi.Inspect)
-		volumes.POST("", a.VolumeApi.Create)
-		volumes.DELETE("", a.VolumeApi.Prune)
-		volumes.DELETE("/:id", a.VolumeApi.Delete)
+		volumes.GET("", rbac.Require(rbac.VolumesRead), utils.Handle(a.VolumeApi.List))
+		volumes.GET("/:id", rbac.Require(rbac.VolumesRead), utils.Handle(a.VolumeApi.Inspect))
+		volumes.POST("", rbac.Require(rbac.VolumesCreate), utils.Handle(a.VolumeApi.Create))
+		volumes.DELETE("", rbac.Require(rbac.VolumesPrune), utils.Handle(a.VolumeApi.Prune))
+		volumes.DELETE("/:id", rbac.Require(rbac.VolumesDelete), utils.Handle(a.VolumeApi.Delete))
 	}
 }
@@ -0,0 +1,263 @@
+package docker_test
+
+import (
+	"cyber-docker/internal/config"
+	dockermod "cyber-docker/internal/mods/docker"
+	"cyber-docker/internal/mods/docker/api"
+	"cyber-docker/internal/mods/docker/repo"
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/docker/fake"
+	"cyber-docker/pkg/i18n"
+	"cyber-docker/pkg/identity"
+	"cyber-docker/pkg/lifecycle"
+	"cyber-docker/pkg/rbac"
+	"cyber-docker/pkg/secret"
+	"cyber-docker/pkg/storage"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// remote 是配置文件中的第二个端点，通过 /endpoints/remote/... 访问
+const remote = "remote"
+
+var (
+	hitMutex sync.Mutex
+	// hits 记录测试访问过的路由，key 为 method + 路由模板
+	hits = make(map[string]bool)
+)
+
+// TestMain 完整运行时检查 RegisterV1Routers 注册的每个路由都被测试访问过
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	code := m.Run()
+	if code == 0 && flag.Lookup("test.run").Value.String() == "" {
+		if missing := untestedRoutes(); len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "routes without tests:\n  %s\n", strings.Join(missing, "\n  "))
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+func untestedRoutes() []string {
+	e := gin.New()
+	new(dockermod.Docker).RegisterV1Routers(e.Group("/api/v1"))
+	hitMutex.Lock()
+	defer hitMutex.Unlock()
+	var missing []string
+	for _, route := range e.Routes() {
+		if !hits[route.Method+" "+route.Path] {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+type testServer struct {
+	t        *testing.T
+	engine   *gin.Engine
+	factory  *fake.Factory
+	registry *docker.Registry
+	config   *config.Config
+	// identity 为请求的调用者，默认为管理员
+	identity *identity.Identity
+}
+
+// newTestServer 创建默认端点 local 和配置端点 remote，两个端点各有一个运行中的 web 容器
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	i18n.RegisterValidator()
+	db, err := storage.Open(filepath.Join(t.TempDir(), "data.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	cipher, err := secret.New("test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, err := docker.NewCredentialStore(&repo.CredentialRepository{DB: db}, cipher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory := fake.NewFactory()
+	registry, err := docker.NewRegistryWithFactory(factory.Create, &repo.EndpointRepository{DB: db, Cipher: cipher},
+		docker.Endpoint{Name: "local"}, docker.Endpoint{Name: remote, Host: "tcp://10.0.0.2:2376"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = registry.Close()
+	})
+	for _, name := range []string{"local", remote} {
+		seed(t, factory.Engine(name))
+	}
+
+	cfg := config.Default()
+	manager := lifecycle.New()
+	s := &testServer{
+		t:        t,
+		factory:  factory,
+		registry: registry,
+		config:   cfg,
+		identity: &identity.Identity{Username: "admin", Role: rbac.RoleAdmin},
+	}
+	module := &dockermod.Docker{
+		EndpointApi:  api.Endpoints{Registry: registry},
+		ImageApi:     api.Images{Config: cfg, Credentials: credentials, Lifecycle: manager, Builds: repo.NewBuildRepository(db)},
+		ContainerApi: api.Containers{Config: cfg, Credentials: credentials, Lifecycle: manager},
+		RegistryApi:  api.Registries{Credentials: credentials},
+	}
+	s.engine = gin.New()
+	s.engine.Use(i18n.Middleware(i18n.Default), func(c *gin.Context) {
+		hitMutex.Lock()
+		hits[c.Request.Method+" "+c.FullPath()] = true
+		hitMutex.Unlock()
+		identity.WithContext(c, s.identity)
+	})
+	module.RegisterV1Routers(s.engine.Group("/api/v1"))
+	return s
+}
+
+func seed(t *testing.T, e *fake.Engine) {
+	t.Helper()
+	if _, err := e.AddImage("nginx:1.25", 1000, map[string]string{"tier": "web"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddImage("redis:7", 500, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err := e.AddContainer("web", "nginx:1.25",
+		&container.Config{Cmd: []string{"nginx"}, Labels: map[string]string{"team": "payments"}},
+		&container.HostConfig{Binds: []string{"data:/data"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// prefixes 返回资源路由的两组前缀，不带端点时使用默认端点 local
+func prefixes() map[string]string {
+	return map[string]string{
+		"local": "/api/v1",
+		remote:  "/api/v1/endpoints/" + remote,
+	}
+}
+
+// forEachEndpoint 在两组前缀下分别运行 fn，并检查状态只落在对应端点上
+func forEachEndpoint(t *testing.T, fn func(t *testing.T, s *testServer, prefix string, e *fake.Engine)) {
+	for name, prefix := range prefixes() {
+		t.Run(name, func(t *testing.T) {
+			s := newTestServer(t)
+			fn(t, s, prefix, s.factory.Engine(name))
+		})
+	}
+}
+
+func (s *testServer) serve(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+	return w
+}
+
+// do 发送 JSON 请求，body 为空时不带请求体
+func (s *testServer) do(method, path, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return s.serve(req)
+}
+
+// ok 发送请求并要求返回 200，data 不为 nil 时解析响应中的 data
+func (s *testServer) ok(method, path, body string, data interface{}) {
+	s.t.Helper()
+	w := s.do(method, path, body)
+	if w.Code != http.StatusOK {
+		s.t.Fatalf("%s %s: status %d, body %s", method, path, w.Code, w.Body.String())
+	}
+	if data == nil {
+		return
+	}
+	var result struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	if !result.Success {
+		s.t.Fatalf("%s %s: %s", method, path, w.Body.String())
+	}
+	if err := json.Unmarshal(result.Data, data); err != nil {
+		s.t.Fatalf("%s %s: decode data: %v", method, path, err)
+	}
+}
+
+// fail 发送请求并要求返回 status
+func (s *testServer) fail(method, path, body string, status int) {
+	s.t.Helper()
+	w := s.do(method, path, body)
+	if w.Code != status {
+		s.t.Fatalf("%s %s: status %d, want %d, body %s", method, path, w.Code, status, w.Body.String())
+	}
+}
+
+type event struct {
+	Event string
+	Data  json.RawMessage
+}
+
+// events 解析 SSE 响应
+func events(t *testing.T, w *httptest.ResponseRecorder) []event {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body.String())
+	}
+	var list []event
+	for _, block := range strings.Split(w.Body.String(), "\n\n") {
+		var item event
+		for _, line := range strings.Split(block, "\n") {
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				item.Event = name
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok {
+				item.Data = json.RawMessage(data)
+			}
+		}
+		if item.Event != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// lastEvent 要求流以 done 事件结束
+func lastEvent(t *testing.T, list []event) event {
+	t.Helper()
+	if len(list) == 0 {
+		t.Fatal("no events")
+	}
+	last := list[len(list)-1]
+	if last.Event != "done" {
+		t.Fatalf("stream ends with %s: %s", last.Event, last.Data)
+	}
+	return last
+}
@@ -0,0 +1,51 @@
+package docker_test
+
+import (
+	"context"
+	"cyber-docker/pkg/docker/fake"
+	"github.com/docker/docker/api/types/network"
+	"net/http"
+	"testing"
+)
+
+func TestNetworks(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		var created network.CreateResponse
+		s.ok("POST", prefix+"/networks", `{"name":"backend","ipV4":{"subnet":"172.30.0.0/16","gateway":"172.30.0.1"}}`, &created)
+		if created.ID == "" {
+			t.Fatal("network id is empty")
+		}
+		if _, err := e.NetworkInspect(context.Background(), "backend", network.InspectOptions{}); err != nil {
+			t.Fatalf("network is not created on the endpoint: %v", err)
+		}
+
+		var list []network.Summary
+		s.ok("GET", prefix+"/networks?name=backend", "", &list)
+		if len(list) != 1 || list[0].Name != "backend" {
+			t.Fatalf("networks = %+v", list)
+		}
+
+		s.ok("PUT", prefix+"/networks/backend", `{"name":"backend","container_name":"web","containerAlise":["api"]}`, nil)
+		var info network.Inspect
+		s.ok("GET", prefix+"/networks/backend", "", &info)
+		if len(info.Containers) != 1 {
+			t.Fatalf("network containers after connect = %+v", info.Containers)
+		}
+		s.ok("PATCH", prefix+"/networks/backend", `{"container_name":"web"}`, nil)
+		var disconnected network.Inspect
+		s.ok("GET", prefix+"/networks/backend", "", &disconnected)
+		if len(disconnected.Containers) != 0 {
+			t.Fatalf("network containers after disconnect = %+v", disconnected.Containers)
+		}
+		s.fail("PUT", prefix+"/networks/backend", `{"name":"backend","container_name":"missing"}`, http.StatusNotFound)
+
+		// 删除前自动断开容器
+		s.ok("PUT", prefix+"/networks/backend", `{"name":"backend","container_name":"web"}`, nil)
+		s.ok("DELETE", prefix+"/networks/backend", "", nil)
+		s.fail("GET", prefix+"/networks/backend", "", http.StatusNotFound)
+
+		s.ok("POST", prefix+"/networks", `{"name":"unused"}`, nil)
+		s.ok("DELETE", prefix+"/networks", "", nil)
+		s.fail("GET", prefix+"/networks/unused", "", http.StatusNotFound)
+	})
+}
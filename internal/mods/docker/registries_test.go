@@ -0,0 +1,81 @@
+package docker_test
+
+import (
+	"cyber-docker/internal/config"
+	"cyber-docker/pkg/docker/fake"
+	"net/http"
+	"testing"
+)
+
+func TestRegistries(t *testing.T) {
+	s := newTestServer(t)
+
+	var info struct {
+		Host      string `json:"host"`
+		Username  string `json:"username"`
+		HasSecret bool   `json:"has_secret"`
+		Secret    string `json:"secret"`
+	}
+	s.ok("POST", "/api/v1/registries", `{"host":"https://registry.example.com/","username":"ci","secret":"s3cret"}`, &info)
+	if info.Host != "registry.example.com" || !info.HasSecret || info.Secret != "" {
+		t.Fatalf("created registry = %+v", info)
+	}
+	s.fail("POST", "/api/v1/registries", `{"host":"registry.example.com"}`, http.StatusConflict)
+
+	var list []struct {
+		Host string `json:"host"`
+	}
+	s.ok("GET", "/api/v1/registries", "", &list)
+	if len(list) != 1 {
+		t.Fatalf("registries = %+v", list)
+	}
+
+	// 密码为空时保留原来的密码
+	s.ok("PUT", "/api/v1/registries/registry.example.com", `{"username":"deploy"}`, &info)
+	if info.Username != "deploy" || !info.HasSecret {
+		t.Fatalf("updated registry = %+v", info)
+	}
+	s.ok("GET", "/api/v1/registries/registry.example.com", "", &info)
+	if info.Username != "deploy" {
+		t.Fatalf("registry = %+v", info)
+	}
+
+	s.ok("DELETE", "/api/v1/registries/registry.example.com", "", nil)
+	s.fail("GET", "/api/v1/registries/registry.example.com", "", http.StatusNotFound)
+	s.fail("PUT", "/api/v1/registries/registry.example.com", `{"username":"deploy"}`, http.StatusNotFound)
+	s.fail("DELETE", "/api/v1/registries/registry.example.com", "", http.StatusNotFound)
+}
+
+func TestRegistryLogin(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		e.AddRegistryUser("registry.example.com", "ci", "s3cret")
+		s.fail("POST", prefix+"/registries/registry.example.com/login", "", http.StatusNotFound)
+
+		s.ok("POST", "/api/v1/registries", `{"host":"registry.example.com","username":"ci","secret":"wrong"}`, nil)
+		s.fail("POST", prefix+"/registries/registry.example.com/login", "", http.StatusUnauthorized)
+
+		s.ok("PUT", "/api/v1/registries/registry.example.com", `{"username":"ci","secret":"s3cret"}`, nil)
+		var result struct {
+			Status string `json:"status"`
+		}
+		s.ok("POST", prefix+"/registries/registry.example.com/login", "", &result)
+		if result.Status != "Login Succeeded" {
+			t.Fatalf("login = %+v", result)
+		}
+	})
+}
+
+func TestRegistryConfigFallback(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		e.AddRegistryUser("registry.example.com", "ci", "s3cret")
+		e.AddRemoteImage("registry.example.com/app:1", 100, nil)
+		s.config.Docker.Registries = []config.Registry{{Host: "registry.example.com", Username: "ci", Password: "s3cret"}}
+
+		// 保存的凭据没有密码时使用配置文件中的账号
+		s.ok("POST", "/api/v1/registries", `{"host":"registry.example.com","insecure":true}`, nil)
+		lastEvent(t, events(t, s.do("POST", prefix+"/images/pull", `{"reference":"registry.example.com/app:1"}`)))
+
+		s.ok("PUT", "/api/v1/registries/registry.example.com", `{"username":"ci","secret":"wrong"}`, nil)
+		s.fail("POST", prefix+"/images/pull", `{"reference":"registry.example.com/app:1"}`, http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"cyber-docker/internal/mods/docker/entity"
+	"cyber-docker/pkg/storage"
+	"encoding/json"
+	"time"
+)
+
+const (
+	buildBucket = "builds"
+	// buildIndexBucket 记录构建 ID 对应的 key，用于更新进行中的构建
+	buildIndexBucket = "builds_index"
+	// maxBuildRecords 保留的构建记录数，超出时删除最早的记录
+	maxBuildRecords = 200
+)
+
+type BuildRepository interface {
+	Save(record entity.BuildRecord) error
+	// List 返回端点最近的构建记录，最新的在前，endpoint 为空时返回所有端点的记录
+	List(endpoint string) ([]entity.BuildRecord, error)
+}
+
+func NewBuildRepository(db *storage.DB) BuildRepository {
+	return &buildRepository{db: db}
+}
+
+type buildRepository struct {
+	db *storage.DB
+}
+
+// buildKey 以开始时间排序，同一时间开始的构建按 ID 区分
+func buildKey(record entity.BuildRecord) string {
+	return record.StartedAt.UTC().Format(time.RFC3339Nano) + "/" + record.ID
+}
+
+func (r *buildRepository) Save(record entity.BuildRecord) error {
+	return r.db.Update(func(tx *storage.Tx) error {
+		var key string
+		if err := tx.Get(buildIndexBucket, record.ID, &key); err == nil {
+			return tx.Put(buildBucket, key, record)
+		}
+		key = buildKey(record)
+		if err := tx.Put(buildIndexBucket, record.ID, key); err != nil {
+			return err
+		}
+		if err := tx.Put(buildBucket, key, record); err != nil {
+			return err
+		}
+		return r.prune(tx)
+	})
+}
+
+func (r *buildRepository) prune(tx *storage.Tx) error {
+	// 记录过期构建的 key 和 ID
+	expired := make(map[string]string)
+	count := 0
+	err := tx.ForEachReverse(buildBucket, func(key string, value []byte) error {
+		count++
+		if count <= maxBuildRecords {
+			return nil
+		}
+		var record entity.BuildRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			return err
+		}
+		expired[key] = record.ID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for key, id := range expired {
+		if err = tx.Delete(buildBucket, key); err != nil {
+			return err
+		}
+		if err = tx.Delete(buildIndexBucket, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *buildRepository) List(endpoint string) ([]entity.BuildRecord, error) {
+	result := make([]entity.BuildRecord, 0)
+	err := r.db.View(func(tx *storage.Tx) error {
+		return tx.ForEachReverse(buildBucket, func(key string, value []byte) error {
+			var record entity.BuildRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return err
+			}
+			if endpoint == "" || record.Endpoint == endpoint {
+				result = append(result, record)
+			}
+			return nil
+		})
+	})
+	return result, err
+}
@@ -0,0 +1,49 @@
+// Package repo 把 docker 模块的数据保存到 storage
+package repo
+
+import (
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/storage"
+	"encoding/json"
+	"errors"
+)
+
+const registryBucket = "registries"
+
+var _ docker.CredentialRepository = (*CredentialRepository)(nil)
+
+// CredentialRepository 以仓库地址为 key 保存镜像仓库凭据
+type CredentialRepository struct {
+	DB *storage.DB
+}
+
+func (r *CredentialRepository) List() ([]docker.Credential, error) {
+	list := make([]docker.Credential, 0)
+	err := r.DB.View(func(tx *storage.Tx) error {
+		return tx.ForEach(registryBucket, func(key string, value []byte) error {
+			var item docker.Credential
+			if err := json.Unmarshal(value, &item); err != nil {
+				return err
+			}
+			list = append(list, item)
+			return nil
+		})
+	})
+	return list, err
+}
+
+func (r *CredentialRepository) Save(item docker.Credential) error {
+	return r.DB.Update(func(tx *storage.Tx) error {
+		return tx.Put(registryBucket, item.Host, item)
+	})
+}
+
+func (r *CredentialRepository) Delete(host string) error {
+	err := r.DB.Update(func(tx *storage.Tx) error {
+		return tx.Delete(registryBucket, host)
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return docker.ErrCredentialNotFound
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package repo
+
+import (
+	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/secret"
+	"cyber-docker/pkg/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const endpointBucket = "endpoints"
+
+var _ docker.EndpointRepository = (*EndpointRepository)(nil)
+
+// EndpointRepository 以名称为 key 保存通过接口添加的端点，TLS 私钥加密保存
+type EndpointRepository struct {
+	DB     *storage.DB
+	Cipher *secret.Cipher
+}
+
+func (r *EndpointRepository) List() ([]docker.Endpoint, error) {
+	list := make([]docker.Endpoint, 0)
+	err := r.DB.View(func(tx *storage.Tx) error {
+		return tx.ForEach(endpointBucket, func(key string, value []byte) error {
+			var item docker.Endpoint
+			if err := json.Unmarshal(value, &item); err != nil {
+				return err
+			}
+			if item.TLS != nil && item.TLS.Key != "" {
+				tls := *item.TLS
+				var err error
+				if tls.Key, err = r.Cipher.Decrypt(tls.Key); err != nil {
+					return fmt.Errorf("endpoint %s: %w", item.Name, err)
+				}
+				item.TLS = &tls
+			}
+			list = append(list, item)
+			return nil
+		})
+	})
+	return list, err
+}
+
+func (r *EndpointRepository) Save(item docker.Endpoint) error {
+	if item.TLS != nil && item.TLS.Key != "" {
+		tls := *item.TLS
+		var err error
+		if tls.Key, err = r.Cipher.Encrypt(tls.Key); err != nil {
+			return err
+		}
+		item.TLS = &tls
+	}
+	return r.DB.Update(func(tx *storage.Tx) error {
+		return tx.Put(endpointBucket, item.Name, item)
+	})
+}
+
+func (r *EndpointRepository) Delete(name string) error {
+	err := r.DB.Update(func(tx *storage.Tx) error {
+		return tx.Delete(endpointBucket, name)
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return docker.ErrEndpointNotFound
+	}
+	return err
+}
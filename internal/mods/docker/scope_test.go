@@ -0,0 +1,209 @@
+package docker_test
+
+import (
+	"context"
+	"cyber-docker/pkg/docker/fake"
+	"cyber-docker/pkg/identity"
+	"cyber-docker/pkg/rbac"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"net/http"
+	"testing"
+)
+
+// seedScopes 添加属于 payments 和 billing 两个团队的容器和存储卷，seed 中的 web 容器属于 payments
+func seedScopes(t *testing.T, e *fake.Engine) {
+	t.Helper()
+	_, err := e.AddContainer("billing", "redis:7", &container.Config{Labels: map[string]string{"team": "billing"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, team := range map[string]string{"payments-data": "payments", "billing-data": "billing"} {
+		_, err = e.VolumeCreate(context.Background(), volume.CreateOptions{Name: name, Labels: map[string]string{"team": team}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScopedContainers(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		seedScopes(t, e)
+		var list []container.Summary
+		s.ok("GET", prefix+"/containers", "", &list)
+		if len(list) != 2 {
+			t.Fatalf("admin containers = %+v", list)
+		}
+
+		s.identity = &identity.Identity{Username: "ops", Role: rbac.RoleOperator, Labels: map[string]string{"team": "payments"}}
+		s.ok("GET", prefix+"/containers", "", &list)
+		if len(list) != 1 || list[0].Names[0] != "/web" {
+			t.Fatalf("scoped containers = %+v", list)
+		}
+		s.ok("GET", prefix+"/containers?all=true&sort_by=name", "", &list)
+		if len(list) != 1 {
+			t.Fatalf("scoped containers with all = %+v", list)
+		}
+		s.ok("GET", prefix+"/containers/web", "", nil)
+		s.fail("GET", prefix+"/containers/billing", "", http.StatusForbidden)
+		s.fail("PUT", prefix+"/containers/billing/stat", "", http.StatusForbidden)
+		s.fail("GET", prefix+"/containers/missing", "", http.StatusNotFound)
+
+		// 创建的容器带上调用者的范围标签
+		var created struct {
+			ID string `json:"id"`
+		}
+		s.ok("POST", prefix+"/containers", `{"image":"redis:7","name":"cache","labels":{"team":"billing"}}`, &created)
+		if labels := inspectContainer(t, e, created.ID).Config.Labels; labels["team"] != "payments" {
+			t.Fatalf("created container labels = %v", labels)
+		}
+		s.ok("GET", prefix+"/containers", "", &list)
+		if len(list) != 2 {
+			t.Fatalf("scoped containers after create = %+v", list)
+		}
+
+		s.identity = &identity.Identity{Username: "viewer", Role: rbac.RoleReadOnly, Labels: map[string]string{"team": "billing"}}
+		s.ok("GET", prefix+"/containers", "", &list)
+		if len(list) != 1 || list[0].Names[0] != "/billing" {
+			t.Fatalf("read-only scoped containers = %+v", list)
+		}
+		s.fail("PUT", prefix+"/containers/billing/stat", "", http.StatusForbidden)
+		s.fail("DELETE", prefix+"/containers", "", http.StatusForbidden)
+	})
+}
+
+func TestScopedVolumes(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		seedScopes(t, e)
+		var list volumeList
+		s.ok("GET", prefix+"/volumes", "", &list)
+		if len(list.VolumeList) != 3 {
+			t.Fatalf("admin volumes = %+v", list.VolumeList)
+		}
+
+		s.identity = &identity.Identity{Username: "ops", Role: rbac.RoleOperator, Labels: map[string]string{"team": "payments"}}
+		s.ok("GET", prefix+"/volumes", "", &list)
+		if len(list.VolumeList) != 1 || list.VolumeList[0].Name != "payments-data" {
+			t.Fatalf("scoped volumes = %+v", list.VolumeList)
+		}
+		s.ok("GET", prefix+"/volumes/payments-data", "", nil)
+		s.fail("GET", prefix+"/volumes/billing-data", "", http.StatusForbidden)
+		s.fail("GET", prefix+"/volumes/data", "", http.StatusForbidden)
+
+		s.ok("POST", prefix+"/volumes", `{"name":"cache"}`, nil)
+		info, err := e.VolumeInspect(context.Background(), "cache")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Labels["team"] != "payments" {
+			t.Fatalf("created volume labels = %v", info.Labels)
+		}
+		s.ok("GET", prefix+"/volumes?sort_by=name", "", &list)
+		if len(list.VolumeList) != 2 || list.VolumeList[0].Name != "cache" {
+			t.Fatalf("scoped volumes after create = %+v", list.VolumeList)
+		}
+		// operator 没有删除存储卷的权限
+		s.fail("DELETE", prefix+"/volumes/cache", "", http.StatusForbidden)
+	})
+}
+
+func TestScopedContainerDeleteVolume(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		seedScopes(t, e)
+		for name, team := range map[string]string{"payments-cache": "payments", "billing-cache": "billing"} {
+			_, err := e.VolumeCreate(context.Background(), volume.CreateOptions{Name: name, Labels: map[string]string{"team": team}})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		// 每个容器挂载一个范围内和一个范围外的存储卷
+		for name, sources := range map[string][2]string{"api": {"payments-data", "billing-data"}, "worker": {"payments-cache", "billing-cache"}} {
+			_, err := e.AddContainer(name, "redis:7", &container.Config{Labels: map[string]string{"team": "payments"}}, &container.HostConfig{
+				Mounts: []mount.Mount{
+					{Type: mount.TypeVolume, Source: sources[0], Target: "/data"},
+					{Type: mount.TypeVolume, Source: sources[1], Target: "/billing"},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		volumeExists := func(name string) bool {
+			_, err := e.VolumeInspect(context.Background(), name)
+			return err == nil
+		}
+
+		// operator 可以删除容器，但没有删除存储卷的权限
+		s.identity = &identity.Identity{Username: "ops", Role: rbac.RoleOperator, Labels: map[string]string{"team": "payments"}}
+		s.fail("DELETE", prefix+"/containers/api/x", `{"delete_volume":true}`, http.StatusForbidden)
+		s.fail("POST", prefix+"/containers/batch", `{"action":"delete","ids":["worker"],"delete_volume":true}`, http.StatusForbidden)
+		inspectContainer(t, e, "api")
+		inspectContainer(t, e, "worker")
+
+		// 范围外的存储卷保留
+		s.identity = &identity.Identity{Username: "lead", Role: rbac.RoleAdmin, Labels: map[string]string{"team": "payments"}}
+		s.ok("DELETE", prefix+"/containers/api/x", `{"delete_volume":true}`, nil)
+		if volumeExists("payments-data") || !volumeExists("billing-data") {
+			t.Fatal("delete removed the wrong volumes")
+		}
+		var batch struct {
+			Succeeded int `json:"succeeded"`
+		}
+		s.ok("POST", prefix+"/containers/batch", `{"action":"delete","ids":["worker"],"delete_volume":true}`, &batch)
+		if batch.Succeeded != 1 || volumeExists("payments-cache") || !volumeExists("billing-cache") {
+			t.Fatalf("batch delete removed the wrong volumes: %+v", batch)
+		}
+	})
+}
+
+func TestScopedContainerCreate(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		seedScopes(t, e)
+		s.identity = &identity.Identity{Username: "ops", Role: rbac.RoleOperator, Labels: map[string]string{"team": "payments"}}
+		for _, body := range []string{
+			`{"image":"redis:7","privileged":true}`,
+			`{"image":"redis:7","cap_add":["SYS_ADMIN"]}`,
+			`{"image":"redis:7","mounts":[{"type":"bind","source":"/","target":"/host"}]}`,
+			`{"image":"redis:7","mounts":[{"type":"volume","source":"billing-data","target":"/data"}]}`,
+			`{"image":"redis:7","mounts":[{"type":"volume","source":"data","target":"/data"}]}`,
+		} {
+			s.fail("POST", prefix+"/containers", body, http.StatusForbidden)
+		}
+		s.ok("POST", prefix+"/containers", `{"image":"redis:7","name":"cache","cap_drop":["NET_RAW"],"mounts":[{"type":"volume","source":"payments-data","target":"/data"},{"type":"tmpfs","target":"/tmp"}]}`, nil)
+		// 不存在的存储卷由 Docker 创建
+		s.ok("POST", prefix+"/containers", `{"image":"redis:7","name":"queue","mounts":[{"type":"volume","source":"payments-queue","target":"/data"}]}`, nil)
+
+		// 不受范围限制的调用者不检查
+		s.identity = &identity.Identity{Username: "admin", Role: rbac.RoleAdmin}
+		s.ok("POST", prefix+"/containers", `{"image":"redis:7","name":"tools","privileged":true,"mounts":[{"type":"bind","source":"/","target":"/host"}]}`, nil)
+	})
+}
+
+func TestScopedImageCheckUpgrade(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		seedScopes(t, e)
+		_, err := e.AddContainer("cache", "redis:7", &container.Config{Labels: map[string]string{"team": "payments"}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := e.ImageInspect(context.Background(), "redis:7")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result struct {
+			Containers []struct {
+				Names []string `json:"names"`
+			} `json:"containers"`
+		}
+		s.ok("PUT", prefix+"/images/"+info.ID, "", &result)
+		if len(result.Containers) != 2 {
+			t.Fatalf("admin containers = %+v", result.Containers)
+		}
+		s.identity = &identity.Identity{Username: "viewer", Role: rbac.RoleReadOnly, Labels: map[string]string{"team": "payments"}}
+		s.ok("PUT", prefix+"/images/"+info.ID, "", &result)
+		if len(result.Containers) != 1 || result.Containers[0].Names[0] != "/cache" {
+			t.Fatalf("scoped containers = %+v", result.Containers)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package docker_test
+
+import (
+	"context"
+	"cyber-docker/pkg/docker/fake"
+	"github.com/docker/docker/api/types/volume"
+	"net/http"
+	"testing"
+)
+
+type volumeList struct {
+	VolumeList []volume.Volume `json:"volumeList"`
+}
+
+func TestVolumes(t *testing.T) {
+	forEachEndpoint(t, func(t *testing.T, s *testServer, prefix string, e *fake.Engine) {
+		s.ok("POST", prefix+"/volumes", `{"name":"cache","type":"tmpfs","tmpfs_options":"size=64m"}`, nil)
+		if _, err := e.VolumeInspect(context.Background(), "cache"); err != nil {
+			t.Fatalf("volume is not created on the endpoint: %v", err)
+		}
+		s.fail("POST", prefix+"/volumes", `{"name":"bad","type":"other","otherOptions":["novalue"]}`, http.StatusBadRequest)
+
+		var list volumeList
+		s.ok("GET", prefix+"/volumes?sort_by=name", "", &list)
+		if len(list.VolumeList) != 2 || list.VolumeList[0].Name != "cache" || list.VolumeList[1].Name != "data" {
+			t.Fatalf("volumes = %+v", list.VolumeList)
+		}
+
+		var info struct {
+			Info  volume.Volume `json:"info"`
+			InUse []struct {
+				Name string `json:"name"`
+			} `json:"inUse"`
+		}
+		s.ok("GET", prefix+"/volumes/data", "", &info)
+		if len(info.InUse) != 1 || info.InUse[0].Name != "/web" {
+			t.Fatalf("volume data in use = %+v", info.InUse)
+		}
+		s.fail("GET", prefix+"/volumes/missing", "", http.StatusNotFound)
+
+		s.ok("DELETE", prefix+"/volumes/cache", "", nil)
+		s.fail("DELETE", prefix+"/volumes/cache", "", http.StatusNotFound)
+
+		// all 同时删除未使用的具名卷，被容器挂载的卷保留
+		s.ok("POST", prefix+"/volumes", `{"name":"orphan"}`, nil)
+		var report volume.PruneReport
+		s.ok("DELETE", prefix+"/volumes?all=true", "", &report)
+		if len(report.VolumesDeleted) != 1 || report.VolumesDeleted[0] != "orphan" {
+			t.Fatalf("pruned volumes = %+v", report.VolumesDeleted)
+		}
+		s.ok("GET", prefix+"/volumes/data", "", nil)
+	})
+}
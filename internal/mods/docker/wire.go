@@ -2,13 +2,17 @@ package docker
 
 import (
 	"cyber-docker/internal/mods/docker/api"
+	"cyber-docker/internal/mods/docker/repo"
 	"github.com/google/wire"
 )
 
 var Set = wire.NewSet(
 	wire.Struct(new(Docker), "*"),
+	wire.Struct(new(api.Endpoints), "*"),
 	wire.Struct(new(api.Images), "*"),
+	repo.NewBuildRepository,
 	wire.Struct(new(api.Containers), "*"),
 	wire.Struct(new(api.Network), "*"),
 	wire.Struct(new(api.Volume), "*"),
+	wire.Struct(new(api.Registries), "*"),
 )
@@ -1,7 +1,14 @@
 package mods
 
 import (
+	"cyber-docker/internal/config"
+	"cyber-docker/internal/mods/audit"
+	"cyber-docker/internal/mods/auth"
+	authrepo "cyber-docker/internal/mods/auth/repo"
 	"cyber-docker/internal/mods/docker"
+	"cyber-docker/internal/mods/system"
+	"cyber-docker/pkg/i18n"
+	"cyber-docker/pkg/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 )
@@ -11,16 +18,35 @@ const (
 )
 
 type Mods struct {
+	Config *config.Config
+	Audit  *audit.Audit
+	Auth   *auth.Auth
 	Docker *docker.Docker
+	System *system.System
+}
+
+// Migrations 返回所有模块的数据迁移，按执行顺序排列
+func Migrations(cfg *config.Config) []storage.Migration {
+	var result []storage.Migration
+	result = append(result, authrepo.Migrations(cfg.Auth)...)
+	return result
 }
 
 var Set = wire.NewSet(
 	wire.Struct(new(Mods), "*"),
+	audit.Set,
+	auth.Set,
 	docker.Set,
+	system.Set,
 )
 
 func (a *Mods) RegisterRouters(e *gin.Engine) {
 	gAPI := e.Group(apiPrefix)
 	v1 := gAPI.Group("v1")
+	i18n.RegisterValidator()
+	v1.Use(i18n.Middleware(a.Config.HTTP.Locale), a.Audit.Record, a.Auth.Authenticate)
+	a.Auth.RegisterV1Routers(v1)
+	a.Audit.RegisterV1Routers(v1)
 	a.Docker.RegisterV1Routers(v1)
+	a.System.RegisterV1Routers(v1)
 }
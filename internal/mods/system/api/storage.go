@@ -0,0 +1,33 @@
+package api
+
+import (
+	"cyber-docker/internal/config"
+	"cyber-docker/pkg/storage"
+	"cyber-docker/pkg/utils"
+	"fmt"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type Storage struct {
+	Config *config.Config
+	DB     *storage.DB
+}
+
+// Backup 在线下载数据库快照，恢复需要停止服务后执行 restore 命令
+func (a *Storage) Backup(c *gin.Context) {
+	if err := a.Config.Security.CheckBackup(); err != nil {
+		utils.ResFail(c, errdefs.Conflict(err))
+		return
+	}
+	filename := fmt.Sprintf("cyber-docker-%s.db", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+	if _, err := a.DB.Backup(c.Writer); err != nil {
+		slog.Error("system", "backup", err)
+	}
+}
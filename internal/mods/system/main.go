@@ -0,0 +1,18 @@
+package system
+
+import (
+	"cyber-docker/internal/mods/system/api"
+	"cyber-docker/pkg/rbac"
+	"github.com/gin-gonic/gin"
+)
+
+type System struct {
+	StorageApi api.Storage
+}
+
+func (a *System) RegisterV1Routers(v1 *gin.RouterGroup) {
+	system := v1.Group("/system")
+	{
+		system.GET("/backup", rbac.Require(rbac.SystemBackup), a.StorageApi.Backup)
+	}
+}
@@ -0,0 +1,11 @@
+package system
+
+import (
+	"cyber-docker/internal/mods/system/api"
+	"github.com/google/wire"
+)
+
+var Set = wire.NewSet(
+	wire.Struct(new(System), "*"),
+	wire.Struct(new(api.Storage), "*"),
+)
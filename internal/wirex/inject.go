@@ -1,17 +1,37 @@
 package wirex
 
 import (
+	"cyber-docker/internal/config"
 	"cyber-docker/internal/mods"
 	"cyber-docker/pkg/container/di"
 	"cyber-docker/pkg/docker"
-	"github.com/docker/docker/client"
+	"cyber-docker/pkg/lifecycle"
+	"cyber-docker/pkg/storage"
 )
 
 type Injector struct {
 	*mods.Mods
-	Client *client.Client
+	Config    *config.Config
+	Registry  *docker.Registry
+	Lifecycle *lifecycle.Manager
 }
 
-func GetDockerClient(dic *di.Container) *client.Client {
-	return docker.ClientFrom(dic.Get)
+func GetConfig(dic *di.Container) *config.Config {
+	return config.From(dic.Get)
+}
+
+func GetDockerRegistry(dic *di.Container) *docker.Registry {
+	return docker.RegistryFrom(dic.Get)
+}
+
+func GetCredentialStore(dic *di.Container) *docker.CredentialStore {
+	return docker.CredentialStoreFrom(dic.Get)
+}
+
+func GetStorage(dic *di.Container) *storage.DB {
+	return storage.From(dic.Get)
+}
+
+func GetLifecycle(dic *di.Container) *lifecycle.Manager {
+	return lifecycle.From(dic.Get)
 }
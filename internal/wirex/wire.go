@@ -14,7 +14,11 @@ import (
 
 func BuildInjector(dic *di.Container) (*Injector, error) {
 	wire.Build(
-		GetDockerClient,
+		GetConfig,
+		GetDockerRegistry,
+		GetCredentialStore,
+		GetStorage,
+		GetLifecycle,
 		wire.NewSet(wire.Struct(new(Injector), "*")),
 		mods.Set,
 	) // end
@@ -8,39 +8,109 @@ package wirex
 
 import (
 	"cyber-docker/internal/mods"
+	"cyber-docker/internal/mods/audit"
+	"cyber-docker/internal/mods/audit/api"
+	"cyber-docker/internal/mods/audit/repo"
+	"cyber-docker/internal/mods/auth"
+	api2 "cyber-docker/internal/mods/auth/api"
+	repo2 "cyber-docker/internal/mods/auth/repo"
 	"cyber-docker/internal/mods/docker"
-	"cyber-docker/internal/mods/docker/api"
+	api3 "cyber-docker/internal/mods/docker/api"
+	repo3 "cyber-docker/internal/mods/docker/repo"
+	"cyber-docker/internal/mods/system"
+	api4 "cyber-docker/internal/mods/system/api"
 	"cyber-docker/pkg/container/di"
 )
 
 // Injectors from wire.go:
 
 func BuildInjector(dic *di.Container) (*Injector, error) {
-	client := GetDockerClient(dic)
-	images := api.Images{
-		SDK: client,
+	config := GetConfig(dic)
+	db := GetStorage(dic)
+	auditRepository := repo.NewAuditRepository(db, config)
+	apiAudit := api.Audit{
+		Records: auditRepository,
 	}
-	containers := api.Containers{
-		SDK: client,
+	auditAudit := &audit.Audit{
+		AuditApi: apiAudit,
 	}
-	network := api.Network{
-		SDK: client,
+	tokens, err := api2.NewTokens(config, db)
+	if err != nil {
+		return nil, err
 	}
-	volume := api.Volume{
-		SDK: client,
+	userRepository := repo2.NewUserRepository(db)
+	sessionRepository := repo2.NewSessionRepository(db)
+	apiTokenRepository := repo2.NewAPITokenRepository(db)
+	apiAuth := api2.Auth{
+		Config:    config,
+		Tokens:    tokens,
+		Users:     userRepository,
+		Sessions:  sessionRepository,
+		APITokens: apiTokenRepository,
+	}
+	users := api2.Users{
+		Users:     userRepository,
+		Sessions:  sessionRepository,
+		APITokens: apiTokenRepository,
+	}
+	apiTokens := api2.APITokens{
+		APITokens: apiTokenRepository,
+	}
+	authAuth := &auth.Auth{
+		AuthApi:     apiAuth,
+		UserApi:     users,
+		APITokenApi: apiTokens,
+	}
+	registry := GetDockerRegistry(dic)
+	endpoints := api3.Endpoints{
+		Registry: registry,
+	}
+	credentialStore := GetCredentialStore(dic)
+	manager := GetLifecycle(dic)
+	buildRepository := repo3.NewBuildRepository(db)
+	images := api3.Images{
+		Config:      config,
+		Credentials: credentialStore,
+		Lifecycle:   manager,
+		Builds:      buildRepository,
+	}
+	containers := api3.Containers{
+		Config:      config,
+		Credentials: credentialStore,
+		Lifecycle:   manager,
+	}
+	network := api3.Network{}
+	volume := api3.Volume{}
+	registries := api3.Registries{
+		Credentials: credentialStore,
 	}
 	dockerDocker := &docker.Docker{
+		EndpointApi:  endpoints,
 		ImageApi:     images,
 		ContainerApi: containers,
 		NetworkApi:   network,
 		VolumeApi:    volume,
+		RegistryApi:  registries,
+	}
+	storage := api4.Storage{
+		Config: config,
+		DB:     db,
+	}
+	systemSystem := &system.System{
+		StorageApi: storage,
 	}
 	modsMods := &mods.Mods{
+		Config: config,
+		Audit:  auditAudit,
+		Auth:   authAuth,
 		Docker: dockerDocker,
+		System: systemSystem,
 	}
 	injector := &Injector{
-		Mods:   modsMods,
-		Client: client,
+		Mods:      modsMods,
+		Config:    config,
+		Registry:  registry,
+		Lifecycle: manager,
 	}
 	return injector, nil
 }
@@ -3,26 +3,129 @@ package main
 import (
 	"context"
 	"cyber-docker/internal/bootstrap"
+	"cyber-docker/internal/config"
+	"cyber-docker/internal/mods/docker/repo"
 	"cyber-docker/pkg/container/di"
 	"cyber-docker/pkg/docker"
+	"cyber-docker/pkg/lifecycle"
+	"cyber-docker/pkg/secret"
+	"cyber-docker/pkg/storage"
+	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
 )
 
 func main() {
-	fmt.Println("Hello, World!")
-	client, err := docker.NewDockerClientFromHost("tcp://192.168.31.100:54321")
+	configPath := flag.String("c", "", "config file path (yaml or toml)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-c config] [serve | backup <file> | restore <file>]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
 	if err != nil {
 		panic(err)
 	}
+	switch flag.Arg(0) {
+	case "", "serve":
+		err = serve(cfg)
+	case "backup", "restore":
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if flag.Arg(0) == "backup" {
+			err = bootstrap.Backup(cfg, flag.Arg(1))
+		} else {
+			err = bootstrap.Restore(cfg, flag.Arg(1))
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+func serve(cfg *config.Config) error {
+	db, err := bootstrap.OpenStorage(cfg)
+	if err != nil {
+		return err
+	}
+	cipher, err := newCipher(cfg)
+	if err != nil {
+		return err
+	}
+	registry, err := newDockerRegistry(cfg, &repo.EndpointRepository{DB: db, Cipher: cipher})
+	if err != nil {
+		return err
+	}
+	credentials, err := docker.NewCredentialStore(&repo.CredentialRepository{DB: db}, cipher)
+	if err != nil {
+		return err
+	}
+	lc := lifecycle.New()
+	lc.AddCloser("storage", db)
+	lc.AddCloser("docker clients", registry)
+
 	dic := di.NewContainer(di.ServiceConstructorMap{
-		docker.ClientName: func(get di.Get) interface{} {
-			return client
+		lifecycle.Name: func(get di.Get) interface{} {
+			return lc
+		},
+		config.Name: func(get di.Get) interface{} {
+			return cfg
+		},
+		storage.Name: func(get di.Get) interface{} {
+			return db
+		},
+		docker.RegistryName: func(get di.Get) interface{} {
+			return registry
+		},
+		docker.CredentialStoreName: func(get di.Get) interface{} {
+			return credentials
 		},
 	})
-	ctx := context.Background()
+	return bootstrap.Run(context.Background(), dic)
+}
 
-	err = bootstrap.Run(ctx, dic)
-	if err != nil {
-		panic(err)
+// newDockerRegistry 配置文件中的端点之外，还会加载通过接口添加并保存的端点
+func newDockerRegistry(cfg *config.Config, endpointRepo docker.EndpointRepository) (*docker.Registry, error) {
+	endpoints := make([]docker.Endpoint, 0, len(cfg.Docker.Endpoints))
+	for _, item := range cfg.Docker.Endpoints {
+		endpoint := docker.Endpoint{
+			Name:   item.Name,
+			Host:   item.Host,
+			Labels: item.Labels,
+		}
+		if item.TLS != nil {
+			endpoint.TLS = &docker.EndpointTLS{
+				CACert:     item.TLS.CACert,
+				Cert:       item.TLS.Cert,
+				Key:        item.TLS.Key,
+				SkipVerify: item.TLS.SkipVerify,
+			}
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	defaultEndpoint := docker.Endpoint{
+		Name: cfg.Docker.Name,
+		Host: cfg.Docker.Host,
+	}
+	return docker.NewRegistry(endpointRepo, defaultEndpoint, endpoints...)
+}
+
+// newCipher 创建加密仓库密码和端点私钥的 Cipher，未配置密钥时（开启了 generate_key）使用数据目录下自动生成的密钥
+func newCipher(cfg *config.Config) (*secret.Cipher, error) {
+	key := cfg.Security.SecretKey
+	if key == "" && cfg.Security.GenerateKey {
+		var err error
+		key, err = secret.LoadOrCreateKey(filepath.Join(cfg.Storage.Dir, "secret.key"))
+		if err != nil {
+			return nil, err
+		}
 	}
+	return secret.New(key)
 }
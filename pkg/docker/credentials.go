@@ -0,0 +1,231 @@
+package docker
+
+import (
+	"cyber-docker/pkg/container/di"
+	"cyber-docker/pkg/secret"
+	"errors"
+	"fmt"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	CredentialStoreName = di.TypeInstanceToName(CredentialStore{})
+
+	ErrCredentialNotFound = errors.New("registry credential not found")
+	ErrCredentialExists   = errors.New("registry credential already exists")
+
+	registryHostPattern = regexp.MustCompile(`^[a-zA-Z0-9.-]+(:[0-9]+)?$`)
+)
+
+func CredentialStoreFrom(get di.Get) *CredentialStore {
+	return get(CredentialStoreName).(*CredentialStore)
+}
+
+// Credential 是一个镜像仓库的登录凭据
+type Credential struct {
+	// Host 仓库地址，例如 registry.example.com:5000，Docker Hub 为 docker.io
+	Host     string `json:"host"`
+	Username string `json:"username,omitempty"`
+	// Secret 为密码或访问令牌，Username 为空时作为 IdentityToken 使用
+	Secret string `json:"secret,omitempty"`
+	// Insecure 仅作记录，是否允许 HTTP 访问由 Docker 守护进程的 insecure-registries 决定
+	Insecure bool `json:"insecure"`
+	// Mirror 拉取该仓库的镜像时改为从此地址拉取
+	Mirror    string    `json:"mirror,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (c Credential) Validate() error {
+	if !registryHostPattern.MatchString(c.Host) {
+		return errdefs.InvalidParameter(fmt.Errorf("invalid registry host %q", c.Host))
+	}
+	if c.Mirror != "" && !registryHostPattern.MatchString(c.Mirror) {
+		return errdefs.InvalidParameter(fmt.Errorf("invalid registry mirror %q", c.Mirror))
+	}
+	return nil
+}
+
+func (c Credential) AuthConfig() registry.AuthConfig {
+	auth := registry.AuthConfig{ServerAddress: c.Host}
+	if c.Host == "docker.io" {
+		auth.ServerAddress = "https://index.docker.io/v1/"
+	}
+	if c.Username == "" {
+		auth.IdentityToken = c.Secret
+	} else {
+		auth.Username, auth.Password = c.Username, c.Secret
+	}
+	return auth
+}
+
+// NormalizeRegistryHost 去掉协议和路径，index.docker.io 等 Docker Hub 地址统一为 docker.io
+func NormalizeRegistryHost(value string) string {
+	value = strings.TrimSpace(value)
+	if _, rest, ok := strings.Cut(value, "://"); ok {
+		value = rest
+	}
+	value, _, _ = strings.Cut(value, "/")
+	switch value {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return "docker.io"
+	}
+	return value
+}
+
+// ReferenceHost 返回镜像引用所在的仓库地址
+func ReferenceHost(ref string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", err
+	}
+	return reference.Domain(named), nil
+}
+
+// CredentialRepository 持久化凭据，保存的 Secret 已加密
+type CredentialRepository interface {
+	List() ([]Credential, error)
+	Save(item Credential) error
+	Delete(host string) error
+}
+
+// CredentialStore 在内存中缓存镜像仓库凭据，Secret 加密后通过 CredentialRepository 保存
+type CredentialStore struct {
+	mutex       sync.RWMutex
+	repo        CredentialRepository
+	cipher      *secret.Cipher
+	credentials map[string]Credential
+}
+
+func NewCredentialStore(repo CredentialRepository, cipher *secret.Cipher) (*CredentialStore, error) {
+	s := &CredentialStore{
+		repo:        repo,
+		cipher:      cipher,
+		credentials: make(map[string]Credential),
+	}
+	list, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range list {
+		if item.Secret, err = cipher.Decrypt(item.Secret); err != nil {
+			return nil, fmt.Errorf("registry %s: %w", item.Host, err)
+		}
+		s.credentials[item.Host] = item
+	}
+	return s, nil
+}
+
+func (s *CredentialStore) List() []Credential {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	list := make([]Credential, 0, len(s.credentials))
+	for _, item := range s.credentials {
+		list = append(list, item)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Host < list[j].Host
+	})
+	return list
+}
+
+func (s *CredentialStore) Get(host string) (Credential, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	item, ok := s.credentials[NormalizeRegistryHost(host)]
+	if !ok {
+		return Credential{}, ErrCredentialNotFound
+	}
+	return item, nil
+}
+
+func (s *CredentialStore) Add(item Credential) (Credential, error) {
+	item.Host = NormalizeRegistryHost(item.Host)
+	if err := item.Validate(); err != nil {
+		return Credential{}, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.credentials[item.Host]; ok {
+		return Credential{}, ErrCredentialExists
+	}
+	item.CreatedAt = time.Now()
+	item.UpdatedAt = item.CreatedAt
+	return item, s.save(item)
+}
+
+// Update 更新凭据，keepSecret 为 true 时保留原来的 Secret
+func (s *CredentialStore) Update(item Credential, keepSecret bool) (Credential, error) {
+	item.Host = NormalizeRegistryHost(item.Host)
+	if err := item.Validate(); err != nil {
+		return Credential{}, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	old, ok := s.credentials[item.Host]
+	if !ok {
+		return Credential{}, ErrCredentialNotFound
+	}
+	if keepSecret {
+		item.Secret = old.Secret
+	}
+	item.CreatedAt = old.CreatedAt
+	item.UpdatedAt = time.Now()
+	return item, s.save(item)
+}
+
+func (s *CredentialStore) Remove(host string) error {
+	host = NormalizeRegistryHost(host)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.credentials[host]; !ok {
+		return ErrCredentialNotFound
+	}
+	if err := s.repo.Delete(host); err != nil {
+		return err
+	}
+	delete(s.credentials, host)
+	return nil
+}
+
+// Resolve 按镜像引用的仓库地址查找凭据
+func (s *CredentialStore) Resolve(ref string) (Credential, bool) {
+	host, err := ReferenceHost(ref)
+	if err != nil {
+		return Credential{}, false
+	}
+	item, err := s.Get(host)
+	return item, err == nil
+}
+
+// AuthConfigs 返回所有仓库的凭据，用于构建镜像时拉取基础镜像
+func (s *CredentialStore) AuthConfigs() map[string]registry.AuthConfig {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	result := make(map[string]registry.AuthConfig, len(s.credentials))
+	for _, item := range s.credentials {
+		auth := item.AuthConfig()
+		result[auth.ServerAddress] = auth
+	}
+	return result
+}
+
+func (s *CredentialStore) save(item Credential) error {
+	encrypted := item
+	var err error
+	if encrypted.Secret, err = s.cipher.Encrypt(item.Secret); err != nil {
+		return err
+	}
+	if err = s.repo.Save(encrypted); err != nil {
+		return err
+	}
+	s.credentials[item.Host] = item
+	return nil
+}
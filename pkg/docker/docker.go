@@ -1,14 +1,33 @@
 package docker
 
 import (
-	"cyber-docker/pkg/container/di"
+	"crypto/tls"
 	"github.com/docker/docker/client"
+	"github.com/gin-gonic/gin"
+	"net/http"
 )
 
-var ClientName = di.TypeInstanceToName(client.Client{})
+const (
+	contextClientKey   = "docker.client"
+	contextEndpointKey = "docker.endpoint"
+)
+
+// WithContextClient 保存当前请求所选端点的客户端
+func WithContextClient(c *gin.Context, cli Engine) {
+	c.Set(contextClientKey, cli)
+}
+
+func ContextClient(c *gin.Context) Engine {
+	return c.MustGet(contextClientKey).(Engine)
+}
+
+// WithContextEndpoint 保存当前请求所选端点的名称
+func WithContextEndpoint(c *gin.Context, name string) {
+	c.Set(contextEndpointKey, name)
+}
 
-func ClientFrom(get di.Get) *client.Client {
-	return get(ClientName).(*client.Client)
+func ContextEndpoint(c *gin.Context) string {
+	return c.GetString(contextEndpointKey)
 }
 
 func NewDockerClientFromEnv() (*client.Client, error) {
@@ -16,7 +35,16 @@ func NewDockerClientFromEnv() (*client.Client, error) {
 	return cli, err
 }
 
-func NewDockerClientFromHost(host string) (*client.Client, error) {
-	cli, err := client.NewClientWithOpts(client.WithHost(host), client.WithAPIVersionNegotiation())
+// NewDockerClientFromHost 连接 host 处的守护进程，tlsConfig 为 nil 时不使用 TLS
+func NewDockerClientFromHost(host string, tlsConfig *tls.Config) (*client.Client, error) {
+	var opts []client.Opt
+	if tlsConfig != nil {
+		// 需要在 WithHost 之前设置，由 WithHost 配置 transport 的拨号方式
+		opts = append(opts, client.WithHTTPClient(&http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		}))
+	}
+	opts = append(opts, client.WithHost(host), client.WithAPIVersionNegotiation())
+	cli, err := client.NewClientWithOpts(opts...)
 	return cli, err
 }
@@ -0,0 +1,269 @@
+package fake
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+type instruction struct {
+	cmd  string
+	args string
+}
+
+type buildStage struct {
+	name  string
+	steps []instruction
+}
+
+// parseDockerfile 按 FROM 划分构建阶段，处理注释和行尾的续行符
+func parseDockerfile(content string) ([]buildStage, error) {
+	var stages []buildStage
+	var line string
+	for _, raw := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(raw)
+		if line == "" && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+			continue
+		}
+		if strings.HasSuffix(trimmed, "\\") {
+			line += strings.TrimSuffix(trimmed, "\\") + " "
+			continue
+		}
+		line += trimmed
+		cmd, args, _ := strings.Cut(line, " ")
+		line = ""
+		step := instruction{cmd: strings.ToUpper(cmd), args: strings.TrimSpace(args)}
+		if step.cmd == "FROM" {
+			stage := buildStage{}
+			fields := strings.Fields(step.args)
+			if len(fields) == 3 && strings.EqualFold(fields[1], "AS") {
+				stage.name = fields[2]
+			}
+			stages = append(stages, stage)
+		} else if len(stages) == 0 {
+			return nil, invalidParameter("dockerfile parse error: no build stage in current context")
+		}
+		stages[len(stages)-1].steps = append(stages[len(stages)-1].steps, step)
+	}
+	if len(stages) == 0 {
+		return nil, invalidParameter("the Dockerfile cannot be empty")
+	}
+	return stages, nil
+}
+
+// copyConfig 复制镜像配置，避免构建时修改基础镜像的切片和 map
+func copyConfig(c *container.Config) container.Config {
+	result := *c
+	result.Env = append([]string(nil), c.Env...)
+	result.Cmd = append([]string(nil), c.Cmd...)
+	result.Entrypoint = append([]string(nil), c.Entrypoint...)
+	if c.ExposedPorts != nil {
+		result.ExposedPorts = make(nat.PortSet, len(c.ExposedPorts))
+		for k, v := range c.ExposedPorts {
+			result.ExposedPorts[k] = v
+		}
+	}
+	if c.Volumes != nil {
+		result.Volumes = make(map[string]struct{}, len(c.Volumes))
+		for k, v := range c.Volumes {
+			result.Volumes[k] = v
+		}
+	}
+	return result
+}
+
+func readContext(r io.Reader) (map[string][]byte, error) {
+	input, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(input)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, invalidParameter("invalid build context: %v", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		buf, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, invalidParameter("invalid build context: %v", err)
+		}
+		files[path.Clean(header.Name)] = buf
+	}
+}
+
+// ImageBuild 以经典构建器的输出格式模拟构建。FROM 的镜像在本地不存在时从模拟仓库拉取，
+// RUN、COPY、ADD 只输出步骤，CMD、ENV 等指令写入镜像配置，构建失败时错误在输出流中返回
+func (e *Engine) ImageBuild(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error) {
+	files, err := readContext(buildContext)
+	if err != nil {
+		return types.ImageBuildResponse{}, err
+	}
+	platform, err := parsePlatform(options.Platform)
+	if err != nil {
+		return types.ImageBuildResponse{}, err
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	response := types.ImageBuildResponse{Body: io.NopCloser(&out), OSType: "linux"}
+	fail := func(format string, args ...interface{}) (types.ImageBuildResponse, error) {
+		msg := fmt.Sprintf(format, args...)
+		_ = encoder.Encode(map[string]interface{}{"errorDetail": map[string]string{"message": msg}, "error": msg})
+		return response, nil
+	}
+
+	name := options.Dockerfile
+	if name == "" {
+		name = "Dockerfile"
+	}
+	dockerfile, ok := files[path.Clean(name)]
+	if !ok {
+		return fail("Cannot locate specified Dockerfile: %s", name)
+	}
+	stages, err := parseDockerfile(string(dockerfile))
+	if err != nil {
+		return fail("%s", err.Error())
+	}
+	if options.Target != "" {
+		found := false
+		for i, stage := range stages {
+			if stage.name == options.Target {
+				stages, found = stages[:i+1], true
+				break
+			}
+		}
+		if !found {
+			return fail("failed to reach build target %s in Dockerfile", options.Target)
+		}
+	}
+	var steps []instruction
+	for _, stage := range stages {
+		steps = append(steps, stage.steps...)
+	}
+
+	args := make(map[string]string)
+	consumed := make(map[string]bool)
+	expand := func(value string) string {
+		return os.Expand(value, func(key string) string { return args[key] })
+	}
+	type stageState struct {
+		base   *fakeImage
+		config container.Config
+		size   int64
+	}
+	built := make(map[string]stageState)
+	stageIndex := 0
+	var base *fakeImage
+	var config container.Config
+	var size int64
+	for i, step := range steps {
+		// 进入下一个阶段前记录当前阶段的结果，供 FROM <stage> 使用
+		if step.cmd == "FROM" && i > 0 {
+			if name := stages[stageIndex].name; name != "" {
+				built[name] = stageState{base: base, config: config, size: size}
+			}
+			stageIndex++
+		}
+		_ = encoder.Encode(map[string]string{"stream": fmt.Sprintf("Step %d/%d : %s %s\n", i+1, len(steps), step.cmd, step.args)})
+		value := expand(step.args)
+		switch step.cmd {
+		case "FROM":
+			fields := strings.Fields(value)
+			if state, ok := built[fields[0]]; ok {
+				base, config, size = state.base, state.config, state.size
+				break
+			}
+			if fields[0] == "scratch" {
+				base, config, size = nil, container.Config{}, 0
+				break
+			}
+			base, err = e.findImage(fields[0])
+			if err != nil || options.PullParent {
+				remote, rerr := e.findRemote(fields[0])
+				if rerr != nil {
+					if err != nil {
+						return fail("pull access denied for %s, repository does not exist or may require 'docker login'", fields[0])
+					}
+				} else {
+					base = e.pullRemote(fields[0], remote)
+				}
+			}
+			config = copyConfig(base.config)
+			config.Labels = copyLabels(base.labels)
+			size = base.size
+		case "ARG":
+			key, def, _ := strings.Cut(value, "=")
+			args[key] = def
+			if v, ok := options.BuildArgs[key]; ok && v != nil {
+				args[key] = *v
+				consumed[key] = true
+			}
+		case "RUN", "COPY", "ADD":
+			if step.cmd != "RUN" && !strings.Contains(value, "--from=") {
+				fields := strings.Fields(value)
+				for _, src := range fields[:max(len(fields)-1, 0)] {
+					if _, ok := files[path.Clean(src)]; !ok && !strings.ContainsAny(src, "*?") && src != "." {
+						return fail("COPY failed: file not found in build context or excluded by .dockerignore: stat %s: file does not exist", src)
+					}
+				}
+			}
+			size += 1024
+		default:
+			if err := applyChange(&config, step.cmd+" "+value); err != nil {
+				return fail("%s", err.Error())
+			}
+		}
+		_ = encoder.Encode(map[string]string{"stream": fmt.Sprintf(" ---> %s\n", shortID(e.newID("layer")))})
+	}
+
+	var unused []string
+	for key := range options.BuildArgs {
+		if !consumed[key] {
+			unused = append(unused, key)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		_ = encoder.Encode(map[string]string{"stream": fmt.Sprintf("[Warning] One or more build-args %v were not consumed\n", unused)})
+	}
+
+	labels := copyLabels(config.Labels)
+	for k, v := range options.Labels {
+		labels[k] = v
+	}
+	config.Labels = nil
+	img := e.addImage(size, labels, &config)
+	if base != nil {
+		img.parent = base.id
+	}
+	img.platform = platform
+	_ = encoder.Encode(map[string]interface{}{"aux": map[string]string{"ID": img.id}})
+	_ = encoder.Encode(map[string]string{"stream": fmt.Sprintf("Successfully built %s\n", shortID(img.id))})
+	for _, tag := range options.Tags {
+		if err := e.tag(img, tag); err != nil {
+			return fail("%s", err.Error())
+		}
+		_ = encoder.Encode(map[string]string{"stream": fmt.Sprintf("Successfully tagged %s\n", normalizeRef(tag))})
+	}
+	return response, nil
+}
@@ -0,0 +1,632 @@
+package fake
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	stateCreated = "created"
+	stateRunning = "running"
+	statePaused  = "paused"
+	stateExited  = "exited"
+)
+
+type fakeContainer struct {
+	id       string
+	name     string
+	image    string
+	imageID  string
+	created  time.Time
+	state    string
+	pid      int
+	exitCode int
+	// exits 为容器退出的次数
+	exits      int
+	startedAt  time.Time
+	finishedAt time.Time
+	config     *container.Config
+	hostConfig *container.HostConfig
+	mounts     []container.MountPoint
+	networks   map[string]*network.EndpointSettings
+	logs       []logEntry
+	// executables 为 nil 时 exec 可以运行任意命令
+	executables []string
+}
+
+func (c *fakeContainer) status(now time.Time) string {
+	switch c.state {
+	case stateRunning:
+		return "Up " + humanDuration(now.Sub(c.startedAt))
+	case statePaused:
+		return "Up " + humanDuration(now.Sub(c.startedAt)) + " (Paused)"
+	case stateExited:
+		return fmt.Sprintf("Exited (%d) %s ago", c.exitCode, humanDuration(now.Sub(c.finishedAt)))
+	default:
+		return "Created"
+	}
+}
+
+func humanDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "Less than a minute"
+	case d < time.Hour:
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "0001-01-01T00:00:00Z"
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// AddContainer 以 created 状态创建容器，镜像必须已存在，未指定网络时连接到 bridge
+func (e *Engine) AddContainer(name, imageRef string, config *container.Config, hostConfig *container.HostConfig) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.addContainer(name, imageRef, config, hostConfig, nil)
+}
+
+func (e *Engine) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	if config == nil || config.Image == "" {
+		return container.CreateResponse{}, invalidParameter("config cannot be empty in order to create a container")
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	id, err := e.addContainer(containerName, config.Image, config, hostConfig, networkingConfig)
+	if err != nil {
+		return container.CreateResponse{}, err
+	}
+	return container.CreateResponse{ID: id, Warnings: []string{}}, nil
+}
+
+func (e *Engine) addContainer(name, imageRef string, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig) (string, error) {
+	img, err := e.findImage(imageRef)
+	if err != nil {
+		return "", err
+	}
+	id := e.newID("container")
+	if name == "" {
+		name = "container_" + shortID(id)
+	}
+	name = strings.TrimPrefix(name, "/")
+	if _, err := e.findContainer(name); err == nil {
+		return "", conflict("Conflict. The container name \"/%s\" is already in use", name)
+	}
+	if config == nil {
+		config = &container.Config{}
+	}
+	config.Image = imageRef
+	if config.Labels == nil {
+		config.Labels = make(map[string]string)
+	}
+	if hostConfig == nil {
+		hostConfig = &container.HostConfig{}
+	}
+	if hostConfig.NetworkMode == "" {
+		hostConfig.NetworkMode = "bridge"
+	}
+	c := &fakeContainer{
+		id:         id,
+		name:       name,
+		image:      imageRef,
+		imageID:    img.id,
+		created:    e.Now(),
+		state:      stateCreated,
+		config:     config,
+		hostConfig: hostConfig,
+		networks:   make(map[string]*network.EndpointSettings),
+	}
+	for _, item := range hostConfig.Mounts {
+		if item.Type == mount.TypeBind && !strings.HasPrefix(item.Source, "/") {
+			return "", invalidParameter("invalid mount config for type \"bind\": invalid mount path: '%s' mount path must be absolute", item.Source)
+		}
+		mp := container.MountPoint{
+			Type:        item.Type,
+			Source:      item.Source,
+			Destination: item.Target,
+			RW:          !item.ReadOnly,
+		}
+		if item.Type == mount.TypeVolume {
+			v := e.ensureVolume(item.Source)
+			mp.Name = v.Name
+			mp.Source = v.Mountpoint
+			mp.Driver = v.Driver
+		}
+		c.mounts = append(c.mounts, mp)
+	}
+	for _, item := range hostConfig.Binds {
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 {
+			return "", invalidParameter("invalid volume specification: '%s'", item)
+		}
+		mp := container.MountPoint{Type: mount.TypeBind, Source: parts[0], Destination: parts[1], RW: true}
+		if len(parts) > 2 && strings.Contains(parts[2], "ro") {
+			mp.RW = false
+		}
+		if !strings.HasPrefix(parts[0], "/") {
+			v := e.ensureVolume(parts[0])
+			mp.Type, mp.Name, mp.Source, mp.Driver = mount.TypeVolume, v.Name, v.Mountpoint, v.Driver
+		}
+		c.mounts = append(c.mounts, mp)
+	}
+	var endpoints map[string]*network.EndpointSettings
+	if networkingConfig != nil {
+		endpoints = networkingConfig.EndpointsConfig
+	}
+	networks := make(map[*fakeNetwork]*network.EndpointSettings)
+	if mode := string(hostConfig.NetworkMode); mode != "none" && mode != "host" && !strings.HasPrefix(mode, "container:") {
+		n, err := e.findNetwork(mode)
+		if err != nil {
+			return "", err
+		}
+		networks[n] = &network.EndpointSettings{}
+	}
+	for name, settings := range endpoints {
+		n, err := e.findNetwork(name)
+		if err != nil {
+			return "", err
+		}
+		if settings == nil {
+			settings = &network.EndpointSettings{}
+		}
+		networks[n] = settings
+	}
+	e.containers[id] = c
+	for n, settings := range networks {
+		e.connect(n, c, settings)
+	}
+	return id, nil
+}
+
+// findContainer 按完整 ID、ID 前缀或名称查找
+func (e *Engine) findContainer(ref string) (*fakeContainer, error) {
+	if ref == "" {
+		return nil, notFound("container", ref)
+	}
+	if c, ok := e.containers[ref]; ok {
+		return c, nil
+	}
+	name := strings.TrimPrefix(ref, "/")
+	var found *fakeContainer
+	for _, c := range e.containers {
+		if c.name == name {
+			return c, nil
+		}
+		if strings.HasPrefix(c.id, ref) {
+			if found != nil {
+				return nil, invalidParameter("multiple IDs found with provided prefix: %s", ref)
+			}
+			found = c
+		}
+	}
+	if found == nil {
+		return nil, notFound("container", ref)
+	}
+	return found, nil
+}
+
+func (e *Engine) sortedContainers() []*fakeContainer {
+	list := make([]*fakeContainer, 0, len(e.containers))
+	for _, c := range e.containers {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].created.Equal(list[j].created) {
+			return list[i].id > list[j].id
+		}
+		return list[i].created.After(list[j].created)
+	})
+	return list
+}
+
+func (e *Engine) ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	now := e.Now()
+	result := make([]container.Summary, 0)
+	for _, c := range e.sortedContainers() {
+		if !options.All && c.state != stateRunning && c.state != statePaused && len(options.Filters.Get("status")) == 0 {
+			continue
+		}
+		if !matchPrefix(options.Filters, "id", c.id) ||
+			!matchContains(options.Filters, "name", "/"+c.name) ||
+			!matchLabels(options.Filters, c.config.Labels) {
+			continue
+		}
+		if statuses := options.Filters.Get("status"); len(statuses) > 0 && !containsString(statuses, c.state) {
+			continue
+		}
+		if ancestors := options.Filters.Get("ancestor"); len(ancestors) > 0 && !containsString(ancestors, c.image) && !containsString(ancestors, c.imageID) {
+			continue
+		}
+		result = append(result, e.summary(c, now))
+		if options.Limit > 0 && len(result) >= options.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) summary(c *fakeContainer, now time.Time) container.Summary {
+	item := container.Summary{
+		ID:      c.id,
+		Names:   []string{"/" + c.name},
+		Image:   c.image,
+		ImageID: c.imageID,
+		Command: strings.Join(append(c.config.Entrypoint, c.config.Cmd...), " "),
+		Created: c.created.Unix(),
+		Labels:  copyLabels(c.config.Labels),
+		State:   c.state,
+		Status:  c.status(now),
+		Mounts:  append([]container.MountPoint{}, c.mounts...),
+		NetworkSettings: &container.NetworkSettingsSummary{
+			Networks: e.endpoints(c),
+		},
+	}
+	item.HostConfig.NetworkMode = string(c.hostConfig.NetworkMode)
+	return item
+}
+
+func (e *Engine) endpoints(c *fakeContainer) map[string]*network.EndpointSettings {
+	result := make(map[string]*network.EndpointSettings, len(c.networks))
+	for name, item := range c.networks {
+		settings := *item
+		result[name] = &settings
+	}
+	return result
+}
+
+func (e *Engine) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return container.InspectResponse{}, err
+	}
+	return e.inspect(c), nil
+}
+
+func (e *Engine) inspect(c *fakeContainer) container.InspectResponse {
+	config := *c.config
+	config.Labels = copyLabels(c.config.Labels)
+	hostConfig := *c.hostConfig
+	var path string
+	var args []string
+	if cmd := append(append([]string{}, c.config.Entrypoint...), c.config.Cmd...); len(cmd) > 0 {
+		path, args = cmd[0], cmd[1:]
+	}
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:      c.id,
+			Created: formatTime(c.created),
+			Path:    path,
+			Args:    args,
+			State: &container.State{
+				Status:     c.state,
+				Running:    c.state == stateRunning || c.state == statePaused,
+				Paused:     c.state == statePaused,
+				Pid:        c.pid,
+				ExitCode:   c.exitCode,
+				StartedAt:  formatTime(c.startedAt),
+				FinishedAt: formatTime(c.finishedAt),
+			},
+			Image:      c.imageID,
+			Name:       "/" + c.name,
+			Driver:     "overlay2",
+			Platform:   "linux",
+			HostConfig: &hostConfig,
+		},
+		Mounts: append([]container.MountPoint{}, c.mounts...),
+		Config: &config,
+		NetworkSettings: &container.NetworkSettings{
+			Networks: e.endpoints(c),
+		},
+	}
+}
+
+func (e *Engine) ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	switch c.state {
+	case stateRunning:
+		return nil
+	case statePaused:
+		return conflict("cannot start a paused container, try unpause instead")
+	}
+	c.state = stateRunning
+	c.exitCode = 0
+	c.pid = 1000 + e.seq
+	c.startedAt = e.Now()
+	c.appendLog(c.startedAt, false, "container started: "+strings.Join(append(append([]string{}, c.config.Entrypoint...), c.config.Cmd...), " "))
+	return nil
+}
+
+func (e *Engine) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if options.Signal != "" {
+		if _, _, err = parseSignal(options.Signal); err != nil {
+			return err
+		}
+	}
+	e.stop(c, 0)
+	return nil
+}
+
+func (e *Engine) stop(c *fakeContainer, exitCode int) {
+	if c.state != stateRunning && c.state != statePaused {
+		return
+	}
+	c.state = stateExited
+	c.exitCode = exitCode
+	c.exits++
+	c.pid = 0
+	c.finishedAt = e.Now()
+	c.appendLog(c.finishedAt, false, "container exited with code "+strconv.Itoa(exitCode))
+	e.notify()
+}
+
+// ContainerStats 每秒输出一条统计数据，stream 为 false 时只输出一条
+func (e *Engine) ContainerStats(ctx context.Context, containerID string, stream bool) (container.StatsResponseReader, error) {
+	e.mutex.Lock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		e.mutex.Unlock()
+		return container.StatsResponseReader{}, err
+	}
+	id, name := c.id, c.name
+	limit := uint64(c.hostConfig.Memory)
+	e.mutex.Unlock()
+	if limit == 0 {
+		limit = 2 << 30
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		encoder := json.NewEncoder(writer)
+		var previous container.StatsResponse
+		for tick := uint64(1); ; tick++ {
+			e.mutex.Lock()
+			running := c.state == stateRunning
+			e.mutex.Unlock()
+			stats := container.StatsResponse{
+				Name:        "/" + name,
+				ID:          id,
+				Read:        e.Now(),
+				PreRead:     previous.Read,
+				PreCPUStats: previous.CPUStats,
+				NumProcs:    0,
+			}
+			if running {
+				stats.CPUStats = container.CPUStats{
+					CPUUsage:    container.CPUUsage{TotalUsage: tick * 50_000_000},
+					SystemUsage: tick * 1_000_000_000,
+					OnlineCPUs:  2,
+				}
+				stats.MemoryStats = container.MemoryStats{Usage: 64<<20 + tick<<20, Limit: limit}
+				stats.PidsStats = container.PidsStats{Current: 3}
+				stats.Networks = map[string]container.NetworkStats{
+					"eth0": {RxBytes: tick * 1024, TxBytes: tick * 512, RxPackets: tick * 8, TxPackets: tick * 4},
+				}
+				stats.BlkioStats = container.BlkioStats{IoServiceBytesRecursive: []container.BlkioStatEntry{
+					{Major: 8, Op: "read", Value: tick * 4096},
+					{Major: 8, Op: "write", Value: tick * 2048},
+				}}
+			}
+			if err := encoder.Encode(stats); err != nil || !stream {
+				_ = writer.CloseWithError(err)
+				return
+			}
+			previous = stats
+			select {
+			case <-ctx.Done():
+				_ = writer.CloseWithError(ctx.Err())
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+	return container.StatsResponseReader{Body: reader, OSType: "linux"}, nil
+}
+
+func (e *Engine) ContainerTop(ctx context.Context, containerID string, arguments []string) (container.TopResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return container.TopResponse{}, err
+	}
+	if c.state != stateRunning && c.state != statePaused {
+		return container.TopResponse{}, conflict("container %s is not running", c.id)
+	}
+	command := strings.Join(append(append([]string{}, c.config.Entrypoint...), c.config.Cmd...), " ")
+	return container.TopResponse{
+		Titles: []string{"UID", "PID", "PPID", "C", "STIME", "TTY", "TIME", "CMD"},
+		Processes: [][]string{
+			{"root", fmt.Sprintf("%d", c.pid), "1", "0", c.startedAt.Format("15:04"), "?", "00:00:00", command},
+		},
+	}, nil
+}
+
+func (e *Engine) ContainerUpdate(ctx context.Context, containerID string, updateConfig container.UpdateConfig) (container.UpdateResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return container.UpdateResponse{}, err
+	}
+	if updateConfig.RestartPolicy.Name != "" {
+		c.hostConfig.RestartPolicy = updateConfig.RestartPolicy
+	}
+	r := updateConfig.Resources
+	if r.Memory != 0 {
+		c.hostConfig.Memory = r.Memory
+	}
+	if r.NanoCPUs != 0 {
+		c.hostConfig.NanoCPUs = r.NanoCPUs
+	}
+	if r.CPUShares != 0 {
+		c.hostConfig.CPUShares = r.CPUShares
+	}
+	if r.PidsLimit != nil {
+		c.hostConfig.PidsLimit = r.PidsLimit
+	}
+	return container.UpdateResponse{Warnings: []string{}}, nil
+}
+
+func (e *Engine) ContainerRename(ctx context.Context, containerID, newContainerName string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimPrefix(newContainerName, "/")
+	if name == "" {
+		return invalidParameter("Neither old nor new names may be empty")
+	}
+	if other, err := e.findContainer(name); err == nil && other != c {
+		return conflict("Conflict. The container name \"/%s\" is already in use by container %q", name, other.id)
+	}
+	c.name = name
+	for _, item := range c.networks {
+		if n, err := e.findNetwork(item.NetworkID); err == nil {
+			if res, ok := n.Containers[c.id]; ok {
+				res.Name = name
+				n.Containers[c.id] = res
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Engine) ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if c.state == stateRunning || c.state == statePaused {
+		if !options.Force {
+			return conflict("cannot remove container \"/%s\": container is running: stop the container before removing or force remove", c.name)
+		}
+		e.stop(c, 137)
+	}
+	e.removeContainer(c, options.RemoveVolumes)
+	return nil
+}
+
+func (e *Engine) removeContainer(c *fakeContainer, removeVolumes bool) {
+	for _, item := range c.networks {
+		if n, err := e.findNetwork(item.NetworkID); err == nil {
+			delete(n.Containers, c.id)
+		}
+	}
+	delete(e.containers, c.id)
+	e.notify()
+	if removeVolumes {
+		for _, item := range c.mounts {
+			if v, ok := e.volumes[item.Name]; ok && v.anonymous && !e.volumeInUse(v.Name) {
+				delete(e.volumes, v.Name)
+			}
+		}
+	}
+}
+
+// ContainerExport 返回只包含容器元数据文件的 tar 包
+func (e *Engine) ContainerExport(ctx context.Context, containerID string) (io.ReadCloser, error) {
+	e.mutex.Lock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		e.mutex.Unlock()
+		return nil, err
+	}
+	content, _ := json.Marshal(e.inspect(c))
+	e.mutex.Unlock()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	_ = tw.WriteHeader(&tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0o755, ModTime: e.Now()})
+	_ = tw.WriteHeader(&tar.Header{Name: "etc/fake-container.json", Mode: 0o644, Size: int64(len(content)), ModTime: e.Now()})
+	_, _ = tw.Write(content)
+	_ = tw.Close()
+	return io.NopCloser(&buf), nil
+}
+
+func (e *Engine) ContainerCommit(ctx context.Context, containerID string, options container.CommitOptions) (container.CommitResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return container.CommitResponse{}, err
+	}
+	base := e.images[c.imageID]
+	size := int64(0)
+	if base != nil {
+		size = base.size
+	}
+	config := *c.config
+	if options.Config != nil {
+		config = *options.Config
+	}
+	img := e.addImage(size+1024, config.Labels, &config)
+	img.parent = c.imageID
+	img.comment = options.Comment
+	if options.Reference != "" {
+		if err := e.tag(img, options.Reference); err != nil {
+			return container.CommitResponse{}, err
+		}
+	}
+	return container.CommitResponse{ID: img.id}, nil
+}
+
+func (e *Engine) ContainersPrune(ctx context.Context, pruneFilters filters.Args) (container.PruneReport, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	report := container.PruneReport{ContainersDeleted: []string{}}
+	for _, c := range e.sortedContainers() {
+		if c.state == stateRunning || c.state == statePaused || !matchLabels(pruneFilters, c.config.Labels) {
+			continue
+		}
+		e.removeContainer(c, false)
+		report.ContainersDeleted = append(report.ContainersDeleted, c.id)
+	}
+	return report, nil
+}
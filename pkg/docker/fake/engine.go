@@ -0,0 +1,199 @@
+// Package fake 提供一个内存中的 Docker 引擎，模拟容器、镜像、网络和存储卷的状态变化，
+// 用于在没有 Docker 守护进程的情况下运行接口层
+package fake
+
+import (
+	"context"
+	"crypto/sha256"
+	"cyber-docker/pkg/docker"
+	"encoding/hex"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/errdefs"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ docker.Engine = (*Engine)(nil)
+
+const APIVersion = "1.48"
+
+type Engine struct {
+	mutex      sync.Mutex
+	seq        int
+	closed     bool
+	containers map[string]*fakeContainer
+	images     map[string]*fakeImage
+	networks   map[string]*fakeNetwork
+	volumes    map[string]*fakeVolume
+	remote     map[string]*remoteImage
+	execs      map[string]*fakeExec
+	// registryUsers 记录需要认证的仓库地址及其用户名和密码
+	registryUsers map[string]map[string]string
+	// changed 在容器退出或删除时关闭并替换，用于唤醒 ContainerWait
+	changed chan struct{}
+	// Now 返回当前时间，可替换以获得确定的时间戳
+	Now func() time.Time
+}
+
+// New 创建一个只包含 bridge、host、none 三个预置网络的引擎
+func New() *Engine {
+	e := &Engine{
+		containers:    make(map[string]*fakeContainer),
+		images:        make(map[string]*fakeImage),
+		networks:      make(map[string]*fakeNetwork),
+		volumes:       make(map[string]*fakeVolume),
+		remote:        make(map[string]*remoteImage),
+		execs:         make(map[string]*fakeExec),
+		registryUsers: make(map[string]map[string]string),
+		changed:       make(chan struct{}),
+		Now:           time.Now,
+	}
+	for _, item := range []struct{ name, driver string }{
+		{"bridge", "bridge"},
+		{"host", "host"},
+		{"none", "null"},
+	} {
+		e.addNetwork(item.name, item.driver, nil, true)
+	}
+	return e
+}
+
+// Factory 为每个端点名称维护一个引擎，可用于 docker.NewRegistryWithFactory。
+// 端点修改后 Registry 会关闭旧客户端并重新创建，重新创建的客户端仍然访问同一份状态
+type Factory struct {
+	mutex   sync.Mutex
+	engines map[string]*Engine
+}
+
+func NewFactory() *Factory {
+	return &Factory{engines: make(map[string]*Engine)}
+}
+
+// Engine 返回端点对应的引擎，不存在时创建，用于在测试中准备数据和检查状态
+func (f *Factory) Engine(name string) *Engine {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	e, ok := f.engines[name]
+	if !ok {
+		e = New()
+		f.engines[name] = e
+	}
+	return e
+}
+
+func (f *Factory) Create(endpoint docker.Endpoint) (docker.Engine, error) {
+	return &sharedClient{Engine: f.Engine(endpoint.Name)}, nil
+}
+
+// sharedClient 是共享引擎的一个客户端，Close 只关闭该客户端，不影响引擎的状态
+type sharedClient struct {
+	*Engine
+	closed atomic.Bool
+}
+
+func (c *sharedClient) Ping(ctx context.Context) (types.Ping, error) {
+	if c.closed.Load() {
+		return types.Ping{}, errdefs.Unavailable(fmt.Errorf("client is closed"))
+	}
+	return c.Engine.Ping(ctx)
+}
+
+func (c *sharedClient) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+func (e *Engine) Ping(ctx context.Context) (types.Ping, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.closed {
+		return types.Ping{}, errdefs.Unavailable(fmt.Errorf("engine is closed"))
+	}
+	return types.Ping{APIVersion: APIVersion, OSType: "linux"}, nil
+}
+
+func (e *Engine) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.closed = true
+	return nil
+}
+
+// newID 生成 64 位十六进制 ID
+func (e *Engine) newID(kind string) string {
+	e.seq++
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%d/%d", kind, e.seq, e.Now().UnixNano())))
+	return hex.EncodeToString(sum[:])
+}
+
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+func notFound(kind, id string) error {
+	return errdefs.NotFound(fmt.Errorf("No such %s: %s", kind, id))
+}
+
+func conflict(format string, args ...interface{}) error {
+	return errdefs.Conflict(fmt.Errorf(format, args...))
+}
+
+func invalidParameter(format string, args ...interface{}) error {
+	return errdefs.InvalidParameter(fmt.Errorf(format, args...))
+}
+
+// matchLabels 实现 label=key 和 label=key=value 过滤
+func matchLabels(args filters.Args, labels map[string]string) bool {
+	for _, item := range args.Get("label") {
+		key, value, hasValue := strings.Cut(item, "=")
+		actual, ok := labels[key]
+		if !ok || (hasValue && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchPrefix(args filters.Args, key, value string) bool {
+	values := args.Get(key)
+	if len(values) == 0 {
+		return true
+	}
+	for _, item := range values {
+		if strings.HasPrefix(value, item) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchContains(args filters.Args, key string, value ...string) bool {
+	values := args.Get(key)
+	if len(values) == 0 {
+		return true
+	}
+	for _, item := range values {
+		for _, v := range value {
+			if strings.Contains(v, item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	result := make(map[string]string, len(labels))
+	for k, v := range labels {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,195 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"io"
+	"net"
+	"path"
+	"strings"
+)
+
+type fakeExec struct {
+	id        string
+	container *fakeContainer
+	config    container.ExecOptions
+	started   bool
+	running   bool
+	exitCode  int
+	pid       int
+	height    uint
+	width     uint
+}
+
+// SetExecutables 限制容器中 exec 可以运行的命令，用于模拟没有 bash 的镜像
+func (e *Engine) SetExecutables(containerID string, names ...string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	c.executables = append([]string{}, names...)
+	return nil
+}
+
+func (c *fakeContainer) executable(name string) bool {
+	return c.executables == nil || containsString(c.executables, path.Base(name))
+}
+
+func (e *Engine) findExec(execID string) (*fakeExec, error) {
+	if ex, ok := e.execs[execID]; ok {
+		return ex, nil
+	}
+	return nil, notFound("exec instance", execID)
+}
+
+func (e *Engine) ContainerExecCreate(ctx context.Context, containerID string, options container.ExecOptions) (container.ExecCreateResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return container.ExecCreateResponse{}, err
+	}
+	switch c.state {
+	case statePaused:
+		return container.ExecCreateResponse{}, conflict("container %s is paused, unpause the container before exec", c.id)
+	case stateRunning:
+	default:
+		return container.ExecCreateResponse{}, conflict("container %s is not running", c.id)
+	}
+	if len(options.Cmd) == 0 {
+		return container.ExecCreateResponse{}, invalidParameter("no exec command specified")
+	}
+	ex := &fakeExec{id: e.newID("exec"), container: c, config: options}
+	if options.ConsoleSize != nil {
+		ex.height, ex.width = options.ConsoleSize[0], options.ConsoleSize[1]
+	}
+	e.execs[ex.id] = ex
+	return container.ExecCreateResponse{ID: ex.id}, nil
+}
+
+// ContainerExecAttach 启动 exec 并返回连接，模拟的 shell 会回显输入、支持 echo 和 exit
+func (e *Engine) ContainerExecAttach(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	ex, err := e.findExec(execID)
+	if err != nil {
+		return types.HijackedResponse{}, err
+	}
+	if ex.started {
+		return types.HijackedResponse{}, conflict("exec %s has already been started", ex.id)
+	}
+	if ex.container.state != stateRunning {
+		return types.HijackedResponse{}, conflict("container %s is not running", ex.container.id)
+	}
+	ex.started = true
+	if config.ConsoleSize != nil {
+		ex.height, ex.width = config.ConsoleSize[0], config.ConsoleSize[1]
+	}
+
+	server, client := net.Pipe()
+	var out io.Writer = server
+	mediaType := "application/vnd.docker.raw-stream"
+	if !ex.config.Tty {
+		out = stdcopy.NewStdWriter(server, stdcopy.Stdout)
+		mediaType = "application/vnd.docker.multiplexed-stream"
+	}
+	name := ex.config.Cmd[0]
+	if !ex.container.executable(name) {
+		ex.exitCode = 126
+		go func() {
+			_, _ = fmt.Fprintf(out, "OCI runtime exec failed: exec failed: unable to start container process: exec: %q: executable file not found in $PATH: unknown\r\n", name)
+			_ = server.Close()
+		}()
+		return types.NewHijackedResponse(client, mediaType), nil
+	}
+	ex.running = true
+	ex.pid = 2000 + e.seq
+	go e.runShell(ex, server, out)
+	return types.NewHijackedResponse(client, mediaType), nil
+}
+
+func (e *Engine) runShell(ex *fakeExec, conn net.Conn, out io.Writer) {
+	exitCode := 0
+	defer func() {
+		e.mutex.Lock()
+		ex.running = false
+		ex.exitCode = exitCode
+		ex.pid = 0
+		e.mutex.Unlock()
+		_ = conn.Close()
+	}()
+	prompt := "# "
+	if _, err := io.WriteString(out, prompt); err != nil {
+		return
+	}
+	var line bytes.Buffer
+	buf := make([]byte, 1024)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, b := range buf[:n] {
+			if b != '\r' && b != '\n' {
+				line.WriteByte(b)
+				if ex.config.Tty {
+					_, _ = out.Write([]byte{b})
+				}
+				continue
+			}
+			if ex.config.Tty {
+				_, _ = io.WriteString(out, "\r\n")
+			}
+			fields := strings.Fields(line.String())
+			line.Reset()
+			switch {
+			case len(fields) == 0:
+			case fields[0] == "exit":
+				return
+			case fields[0] == "echo":
+				_, _ = io.WriteString(out, strings.Join(fields[1:], " ")+"\r\n")
+			default:
+				_, _ = fmt.Fprintf(out, "%s: %s: not found\r\n", path.Base(ex.config.Cmd[0]), fields[0])
+			}
+			if _, err := io.WriteString(out, prompt); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (e *Engine) ContainerExecResize(ctx context.Context, execID string, options container.ResizeOptions) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	ex, err := e.findExec(execID)
+	if err != nil {
+		return err
+	}
+	if !ex.running {
+		return conflict("exec %s is not running", ex.id)
+	}
+	ex.height, ex.width = options.Height, options.Width
+	return nil
+}
+
+func (e *Engine) ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	ex, err := e.findExec(execID)
+	if err != nil {
+		return container.ExecInspect{}, err
+	}
+	return container.ExecInspect{
+		ExecID:      ex.id,
+		ContainerID: ex.container.id,
+		Running:     ex.running,
+		ExitCode:    ex.exitCode,
+		Pid:         ex.pid,
+	}, nil
+}
@@ -0,0 +1,404 @@
+package fake
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+type fakeImage struct {
+	id      string
+	tags    []string
+	digests []string
+	size    int64
+	created time.Time
+	labels  map[string]string
+	parent  string
+	comment string
+	config  *container.Config
+	// platform 为 nil 时为 linux/amd64
+	platform *ocispec.Platform
+}
+
+// normalizeRef 为没有 tag 的引用补充 :latest
+func normalizeRef(ref string) string {
+	if strings.Contains(ref, "@") {
+		return ref
+	}
+	if strings.LastIndex(ref, ":") <= strings.LastIndex(ref, "/") {
+		return ref + ":latest"
+	}
+	return ref
+}
+
+// AddImage 添加一个带 tag 的镜像并返回镜像 ID
+func (e *Engine) AddImage(ref string, size int64, labels map[string]string) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	img := e.addImage(size, labels, &container.Config{Cmd: []string{"sh"}})
+	if err := e.tag(img, ref); err != nil {
+		delete(e.images, img.id)
+		return "", err
+	}
+	return img.id, nil
+}
+
+func (e *Engine) addImage(size int64, labels map[string]string, config *container.Config) *fakeImage {
+	img := &fakeImage{
+		id:      "sha256:" + e.newID("image"),
+		size:    size,
+		created: e.Now(),
+		labels:  copyLabels(labels),
+		config:  config,
+	}
+	e.images[img.id] = img
+	return img
+}
+
+// tag 把引用指向 img，原来持有该引用的镜像会失去这个 tag
+func (e *Engine) tag(img *fakeImage, ref string) error {
+	if ref == "" || strings.HasPrefix(ref, "sha256:") {
+		return invalidParameter("invalid reference format: %q", ref)
+	}
+	ref = normalizeRef(ref)
+	for _, other := range e.images {
+		if other != img {
+			other.tags = removeString(other.tags, ref)
+		}
+	}
+	if !containsString(img.tags, ref) {
+		img.tags = append(img.tags, ref)
+		sort.Strings(img.tags)
+	}
+	return nil
+}
+
+func removeString(list []string, value string) []string {
+	result := list[:0]
+	for _, item := range list {
+		if item != value {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// findImage 按 ID、短 ID、tag 或 digest 查找
+func (e *Engine) findImage(ref string) (*fakeImage, error) {
+	if ref == "" {
+		return nil, notFound("image", ref)
+	}
+	if img, ok := e.images[ref]; ok {
+		return img, nil
+	}
+	if img, ok := e.images["sha256:"+ref]; ok {
+		return img, nil
+	}
+	normalized := normalizeRef(ref)
+	for _, img := range e.images {
+		if containsString(img.tags, normalized) || containsString(img.digests, ref) {
+			return img, nil
+		}
+	}
+	if len(ref) >= 4 && !strings.ContainsAny(ref, ":/@") {
+		for _, img := range e.images {
+			if strings.HasPrefix(strings.TrimPrefix(img.id, "sha256:"), ref) {
+				return img, nil
+			}
+		}
+	}
+	return nil, notFound("image", ref)
+}
+
+func (e *Engine) imageContainers(img *fakeImage) []*fakeContainer {
+	var result []*fakeContainer
+	for _, c := range e.sortedContainers() {
+		if c.imageID == img.id {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func (e *Engine) ImageList(ctx context.Context, options image.ListOptions) ([]image.Summary, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	list := make([]*fakeImage, 0, len(e.images))
+	for _, img := range e.images {
+		list = append(list, img)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].created.Equal(list[j].created) {
+			return list[i].id > list[j].id
+		}
+		return list[i].created.After(list[j].created)
+	})
+
+	result := make([]image.Summary, 0)
+	for _, img := range list {
+		if !matchLabels(options.Filters, img.labels) {
+			continue
+		}
+		dangling := len(img.tags) == 0
+		if values := options.Filters.Get("dangling"); len(values) > 0 {
+			want := values[0] == "true" || values[0] == "1"
+			if want != dangling {
+				continue
+			}
+		}
+		if refs := options.Filters.Get("reference"); len(refs) > 0 && !matchReference(refs, img.tags) {
+			continue
+		}
+		item := image.Summary{
+			Containers:  -1,
+			Created:     img.created.Unix(),
+			ID:          img.id,
+			Labels:      copyLabels(img.labels),
+			ParentID:    img.parent,
+			RepoDigests: append([]string{}, img.digests...),
+			RepoTags:    append([]string{}, img.tags...),
+			SharedSize:  -1,
+			Size:        img.size,
+		}
+		if options.ContainerCount {
+			item.Containers = int64(len(e.imageContainers(img)))
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func matchReference(refs []string, tags []string) bool {
+	for _, ref := range refs {
+		for _, tag := range tags {
+			repo, _, _ := strings.Cut(tag, ":")
+			if tag == normalizeRef(ref) || repo == ref {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *Engine) ImageInspect(ctx context.Context, imageID string, inspectOpts ...client.ImageInspectOption) (image.InspectResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	img, err := e.findImage(imageID)
+	if err != nil {
+		return image.InspectResponse{}, err
+	}
+	config := *img.config
+	config.Labels = copyLabels(img.labels)
+	platform := ocispec.Platform{OS: "linux", Architecture: "amd64"}
+	if img.platform != nil {
+		platform = *img.platform
+	}
+	return image.InspectResponse{
+		ID:           img.id,
+		RepoTags:     append([]string{}, img.tags...),
+		RepoDigests:  append([]string{}, img.digests...),
+		Parent:       img.parent,
+		Comment:      img.comment,
+		Created:      formatTime(img.created),
+		Config:       &config,
+		Architecture: platform.Architecture,
+		Variant:      platform.Variant,
+		Os:           platform.OS,
+		Size:         img.size,
+		RootFS: image.RootFS{
+			Type:   "layers",
+			Layers: []string{"sha256:" + strings.TrimPrefix(img.id, "sha256:")},
+		},
+	}, nil
+}
+
+func (e *Engine) ImageHistory(ctx context.Context, imageID string, historyOpts ...client.ImageHistoryOption) ([]image.HistoryResponseItem, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	img, err := e.findImage(imageID)
+	if err != nil {
+		return nil, err
+	}
+	var result []image.HistoryResponseItem
+	for img != nil {
+		result = append(result, image.HistoryResponseItem{
+			ID:        img.id,
+			Created:   img.created.Unix(),
+			CreatedBy: "/bin/sh -c #(nop) fake layer",
+			Size:      img.size,
+			Tags:      append([]string{}, img.tags...),
+			Comment:   img.comment,
+		})
+		img = e.images[img.parent]
+	}
+	return result, nil
+}
+
+func (e *Engine) ImageRemove(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	img, err := e.findImage(imageID)
+	if err != nil {
+		return nil, err
+	}
+	var result []image.DeleteResponse
+	byTag := containsString(img.tags, normalizeRef(imageID))
+	if byTag && len(img.tags) > 1 {
+		img.tags = removeString(img.tags, normalizeRef(imageID))
+		return []image.DeleteResponse{{Untagged: normalizeRef(imageID)}}, nil
+	}
+	if !byTag && len(img.tags) > 1 && !options.Force {
+		return nil, conflict("conflict: unable to delete %s (must be forced) - image is referenced in multiple repositories", shortID(img.id))
+	}
+	for _, c := range e.imageContainers(img) {
+		if c.state == stateRunning || c.state == statePaused {
+			return nil, conflict("conflict: unable to delete %s (cannot be forced) - image is being used by running container %s", shortID(img.id), shortID(c.id))
+		}
+		if !options.Force {
+			return nil, conflict("conflict: unable to delete %s (must be forced) - image is being used by stopped container %s", shortID(img.id), shortID(c.id))
+		}
+	}
+	for _, tag := range img.tags {
+		result = append(result, image.DeleteResponse{Untagged: tag})
+	}
+	for _, other := range e.images {
+		if other.parent == img.id {
+			other.parent = ""
+		}
+	}
+	delete(e.images, img.id)
+	result = append(result, image.DeleteResponse{Deleted: img.id})
+	return result, nil
+}
+
+type loadManifest struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+// ImageLoad 读取 docker save 格式 tar 包（可以压缩）中的 manifest.json，每个条目登记为一个镜像，
+// 镜像 ID 和配置取自条目的配置文件，镜像已存在时只更新 tag
+func (e *Engine) ImageLoad(ctx context.Context, input io.Reader, loadOpts ...client.ImageLoadOption) (image.LoadResponse, error) {
+	reader, err := decompress(input)
+	if err != nil {
+		return image.LoadResponse{}, err
+	}
+	var manifests []loadManifest
+	configs := make(map[string][]byte)
+	var total int64
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return image.LoadResponse{}, invalidParameter("invalid tar archive: %v", err)
+		}
+		total += header.Size
+		switch {
+		case header.Name == "manifest.json":
+			if err := json.NewDecoder(tr).Decode(&manifests); err != nil {
+				return image.LoadResponse{}, invalidParameter("invalid manifest.json: %v", err)
+			}
+		case strings.HasSuffix(header.Name, ".json"):
+			buf, err := io.ReadAll(tr)
+			if err != nil {
+				return image.LoadResponse{}, invalidParameter("invalid tar archive: %v", err)
+			}
+			configs[header.Name] = buf
+		}
+	}
+	if len(manifests) == 0 {
+		return image.LoadResponse{}, invalidParameter("no manifest.json found in archive")
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	for _, item := range manifests {
+		img := e.loadImage(item, configs[item.Config], total/int64(len(manifests)))
+		for _, tag := range item.RepoTags {
+			if err := e.tag(img, tag); err != nil {
+				return image.LoadResponse{}, err
+			}
+			_ = encoder.Encode(map[string]string{"stream": fmt.Sprintf("Loaded image: %s\n", normalizeRef(tag))})
+		}
+		if len(item.RepoTags) == 0 {
+			_ = encoder.Encode(map[string]string{"stream": fmt.Sprintf("Loaded image ID: %s\n", img.id)})
+		}
+	}
+	return image.LoadResponse{Body: io.NopCloser(&out), JSON: true}, nil
+}
+
+func (e *Engine) loadImage(item loadManifest, content []byte, size int64) *fakeImage {
+	var saved savedConfig
+	_ = json.Unmarshal(content, &saved)
+	id := ""
+	if name := strings.TrimSuffix(path.Base(item.Config), ".json"); len(name) == 64 {
+		id = "sha256:" + name
+	}
+	if img, ok := e.images[id]; ok {
+		return img
+	}
+	config := saved.Config
+	if config == nil {
+		config = &container.Config{Cmd: []string{"sh"}}
+	}
+	labels := config.Labels
+	config.Labels = nil
+	img := e.addImage(size, labels, config)
+	if saved.Architecture != "" {
+		img.platform = &ocispec.Platform{OS: saved.OS, Architecture: saved.Architecture, Variant: saved.Variant}
+	}
+	if id != "" {
+		delete(e.images, img.id)
+		img.id = id
+		e.images[id] = img
+	}
+	return img
+}
+
+// ImagesPrune 默认只清理悬空镜像，dangling=false 时清理所有未被容器使用的镜像
+func (e *Engine) ImagesPrune(ctx context.Context, pruneFilters filters.Args) (image.PruneReport, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	all := false
+	if values := pruneFilters.Get("dangling"); len(values) > 0 {
+		all = values[0] == "false" || values[0] == "0"
+	}
+	report := image.PruneReport{ImagesDeleted: []image.DeleteResponse{}}
+	for id, img := range e.images {
+		if (!all && len(img.tags) > 0) || len(e.imageContainers(img)) > 0 || !matchLabels(pruneFilters, img.labels) {
+			continue
+		}
+		for _, tag := range img.tags {
+			report.ImagesDeleted = append(report.ImagesDeleted, image.DeleteResponse{Untagged: tag})
+		}
+		report.ImagesDeleted = append(report.ImagesDeleted, image.DeleteResponse{Deleted: id})
+		report.SpaceReclaimed += uint64(img.size)
+		delete(e.images, id)
+	}
+	return report, nil
+}
+
+// BuildCachePrune 没有构建缓存可清理
+func (e *Engine) BuildCachePrune(ctx context.Context, opts types.BuildCachePruneOptions) (*types.BuildCachePruneReport, error) {
+	return &types.BuildCachePruneReport{CachesDeleted: []string{}}, nil
+}
@@ -0,0 +1,169 @@
+package fake
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/go-connections/nat"
+	"github.com/klauspost/compress/zstd"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"io"
+	"strings"
+)
+
+// ImageImport 从容器文件系统 tar 包（可以 gzip 压缩）创建镜像，changes 支持常用的 Dockerfile 指令
+func (e *Engine) ImageImport(ctx context.Context, source image.ImportSource, ref string, options image.ImportOptions) (io.ReadCloser, error) {
+	if source.SourceName != "-" || source.Source == nil {
+		return nil, invalidParameter("only importing from the request body is supported")
+	}
+	platform, err := parsePlatform(options.Platform)
+	if err != nil {
+		return nil, err
+	}
+	config := &container.Config{}
+	for _, change := range options.Changes {
+		if err := applyChange(config, change); err != nil {
+			return nil, err
+		}
+	}
+
+	input, err := decompress(source.Source)
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	tr := tar.NewReader(input)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, invalidParameter("invalid tar archive: %v", err)
+		}
+		size += header.Size
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	img := e.addImage(size, config.Labels, config)
+	img.comment = options.Message
+	img.platform = platform
+	if ref != "" {
+		if options.Tag != "" {
+			ref += ":" + options.Tag
+		}
+		if err := e.tag(img, ref); err != nil {
+			delete(e.images, img.id)
+			return nil, err
+		}
+	}
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	_ = encoder.Encode(map[string]interface{}{"status": "Importing", "progressDetail": map[string]int64{"current": size}})
+	_ = encoder.Encode(map[string]string{"status": img.id})
+	return io.NopCloser(&out), nil
+}
+
+// parsePlatform 解析 os/arch[/variant]，为空时返回 nil
+func parsePlatform(value string) (*ocispec.Platform, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parts := strings.Split(value, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, invalidParameter("invalid platform %q", value)
+	}
+	platform := &ocispec.Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		platform.Variant = parts[2]
+	}
+	return platform, nil
+}
+
+// decompress 识别 gzip、zstd 压缩的 tar 包，未压缩时原样返回
+func decompress(r io.Reader) (io.Reader, error) {
+	reader := bufio.NewReader(r)
+	magic, _ := reader.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, invalidParameter("invalid gzip archive: %v", err)
+		}
+		return gz, nil
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(reader)
+		if err != nil {
+			return nil, invalidParameter("invalid zstd archive: %v", err)
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return reader, nil
+}
+
+func applyChange(config *container.Config, change string) error {
+	instruction, args, _ := strings.Cut(strings.TrimSpace(change), " ")
+	args = strings.TrimSpace(args)
+	switch strings.ToUpper(instruction) {
+	case "CMD":
+		config.Cmd = parseCommand(args)
+	case "ENTRYPOINT":
+		config.Entrypoint = parseCommand(args)
+	case "ENV":
+		key, value, ok := strings.Cut(args, "=")
+		if !ok {
+			key, value, _ = strings.Cut(args, " ")
+		}
+		config.Env = append(config.Env, key+"="+strings.TrimSpace(value))
+	case "LABEL":
+		key, value, _ := strings.Cut(args, "=")
+		if config.Labels == nil {
+			config.Labels = make(map[string]string)
+		}
+		config.Labels[key] = strings.Trim(value, `"`)
+	case "EXPOSE":
+		if config.ExposedPorts == nil {
+			config.ExposedPorts = make(nat.PortSet)
+		}
+		for _, item := range strings.Fields(args) {
+			proto, port := nat.SplitProtoPort(item)
+			p, err := nat.NewPort(proto, port)
+			if err != nil {
+				return invalidParameter("invalid EXPOSE %q: %v", item, err)
+			}
+			config.ExposedPorts[p] = struct{}{}
+		}
+	case "VOLUME":
+		if config.Volumes == nil {
+			config.Volumes = make(map[string]struct{})
+		}
+		for _, item := range parseCommand(args) {
+			config.Volumes[item] = struct{}{}
+		}
+	case "WORKDIR":
+		config.WorkingDir = args
+	case "USER":
+		config.User = args
+	case "STOPSIGNAL":
+		config.StopSignal = args
+	default:
+		return invalidParameter("%s is not a valid change command", instruction)
+	}
+	return nil
+}
+
+// parseCommand 支持 JSON 数组和 shell 两种格式
+func parseCommand(args string) []string {
+	var list []string
+	if err := json.Unmarshal([]byte(args), &list); err == nil {
+		return list
+	}
+	return []string{"/bin/sh", "-c", args}
+}
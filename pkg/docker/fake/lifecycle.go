@@ -0,0 +1,172 @@
+package fake
+
+import (
+	"context"
+	"github.com/docker/docker/api/types/container"
+	"strconv"
+	"strings"
+)
+
+// signals 模拟引擎支持的信号，INT、QUIT、KILL、TERM 会让容器以 128+信号值 退出，其他信号只记录日志
+var signals = map[string]int{
+	"HUP":   1,
+	"INT":   2,
+	"QUIT":  3,
+	"KILL":  9,
+	"USR1":  10,
+	"USR2":  12,
+	"TERM":  15,
+	"WINCH": 28,
+}
+
+func parseSignal(signal string) (string, int, error) {
+	name := strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	if n, ok := signals[name]; ok {
+		return "SIG" + name, n, nil
+	}
+	if n, err := strconv.Atoi(signal); err == nil {
+		for key, value := range signals {
+			if value == n {
+				return "SIG" + key, n, nil
+			}
+		}
+	}
+	return "", 0, invalidParameter("Invalid signal: %s", signal)
+}
+
+func terminates(n int) bool {
+	return n == signals["INT"] || n == signals["QUIT"] || n == signals["KILL"] || n == signals["TERM"]
+}
+
+// notify 唤醒所有等待容器状态变化的 ContainerWait，调用时必须持有锁
+func (e *Engine) notify() {
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+func (e *Engine) ContainerRestart(ctx context.Context, containerID string, options container.StopOptions) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if options.Signal != "" {
+		if _, _, err = parseSignal(options.Signal); err != nil {
+			return err
+		}
+	}
+	e.stop(c, 0)
+	c.state = stateRunning
+	c.exitCode = 0
+	c.pid = 1000 + e.seq
+	c.startedAt = e.Now()
+	c.appendLog(c.startedAt, false, "container restarted")
+	return nil
+}
+
+func (e *Engine) ContainerPause(ctx context.Context, containerID string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	switch c.state {
+	case statePaused:
+		return conflict("Container %s is already paused", c.id)
+	case stateRunning:
+		c.state = statePaused
+		return nil
+	}
+	return conflict("Container %s is not running", c.id)
+}
+
+func (e *Engine) ContainerUnpause(ctx context.Context, containerID string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if c.state != statePaused {
+		return conflict("Container %s is not paused", c.id)
+	}
+	c.state = stateRunning
+	return nil
+}
+
+// ContainerKill signal 为空时发送 SIGKILL
+func (e *Engine) ContainerKill(ctx context.Context, containerID, signal string) error {
+	if signal == "" {
+		signal = "SIGKILL"
+	}
+	name, n, err := parseSignal(signal)
+	if err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if c.state != stateRunning && c.state != statePaused {
+		return conflict("Cannot kill container: %s: Container %s is not running", containerID, c.id)
+	}
+	if !terminates(n) {
+		c.appendLog(e.Now(), false, "received signal "+name)
+		return nil
+	}
+	e.stop(c, 128+n)
+	return nil
+}
+
+// ContainerWait 的 not-running 条件在容器未运行时立即返回，next-exit 等待下一次退出，removed 等待容器被删除
+func (e *Engine) ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	resultC := make(chan container.WaitResponse, 1)
+	errC := make(chan error, 1)
+	switch condition {
+	case "", container.WaitConditionNotRunning, container.WaitConditionNextExit, container.WaitConditionRemoved:
+	default:
+		errC <- invalidParameter("invalid condition: %q", condition)
+		return resultC, errC
+	}
+
+	e.mutex.Lock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		e.mutex.Unlock()
+		errC <- err
+		return resultC, errC
+	}
+	exits := c.exits
+	e.mutex.Unlock()
+	go func() {
+		for {
+			e.mutex.Lock()
+			var done bool
+			switch condition {
+			case container.WaitConditionNextExit:
+				done = c.exits > exits
+			case container.WaitConditionRemoved:
+				done = e.containers[c.id] != c
+			default:
+				done = c.state != stateRunning && c.state != statePaused
+			}
+			exitCode, changed := c.exitCode, e.changed
+			e.mutex.Unlock()
+			if done {
+				resultC <- container.WaitResponse{StatusCode: int64(exitCode)}
+				return
+			}
+			select {
+			case <-ctx.Done():
+				errC <- ctx.Err()
+				return
+			case <-changed:
+			}
+		}
+	}()
+	return resultC, errC
+}
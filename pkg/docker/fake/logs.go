@@ -0,0 +1,143 @@
+package fake
+
+import (
+	"context"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"io"
+	"strconv"
+	"time"
+)
+
+type logEntry struct {
+	time   time.Time
+	stderr bool
+	line   string
+}
+
+func (c *fakeContainer) appendLog(t time.Time, stderr bool, line string) {
+	c.logs = append(c.logs, logEntry{time: t, stderr: stderr, line: line})
+}
+
+// AppendLog 向容器追加一行日志，正在跟随日志的读取方会收到该行
+func (e *Engine) AppendLog(containerID string, stderr bool, line string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	c.appendLog(e.Now(), stderr, line)
+	return nil
+}
+
+// parseLogTime 支持 RFC3339、Unix 时间戳和相对时长（例如 10m）
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, invalidParameter("invalid time value %q", value)
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), nil
+}
+
+// ContainerLogs 输出与 Docker 相同的多路复用格式，TTY 容器输出原始数据
+func (e *Engine) ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return nil, err
+	}
+	now := e.Now()
+	since, err := parseLogTime(options.Since, now)
+	if err != nil {
+		return nil, err
+	}
+	until, err := parseLogTime(options.Until, now)
+	if err != nil {
+		return nil, err
+	}
+	tail := -1
+	if options.Tail != "" && options.Tail != "all" {
+		if tail, err = strconv.Atoi(options.Tail); err != nil {
+			return nil, invalidParameter("invalid tail value %q", options.Tail)
+		}
+	}
+	match := func(entry logEntry) bool {
+		if (entry.stderr && !options.ShowStderr) || (!entry.stderr && !options.ShowStdout) {
+			return false
+		}
+		return (since.IsZero() || !entry.time.Before(since)) && (until.IsZero() || !entry.time.After(until))
+	}
+	var backlog []logEntry
+	for _, entry := range c.logs {
+		if match(entry) {
+			backlog = append(backlog, entry)
+		}
+	}
+	if tail >= 0 && len(backlog) > tail {
+		backlog = backlog[len(backlog)-tail:]
+	}
+	offset := len(c.logs)
+	tty := c.config.Tty
+
+	reader, writer := io.Pipe()
+	write := func(entry logEntry) error {
+		line := entry.line + "\n"
+		if options.Timestamps {
+			line = entry.time.UTC().Format(time.RFC3339Nano) + " " + line
+		}
+		var out io.Writer = writer
+		if !tty {
+			stream := stdcopy.Stdout
+			if entry.stderr {
+				stream = stdcopy.Stderr
+			}
+			out = stdcopy.NewStdWriter(writer, stream)
+		}
+		_, err := io.WriteString(out, line)
+		return err
+	}
+	go func() {
+		for _, entry := range backlog {
+			if err := write(entry); err != nil {
+				return
+			}
+		}
+		for options.Follow && until.IsZero() {
+			select {
+			case <-ctx.Done():
+				_ = writer.CloseWithError(ctx.Err())
+				return
+			case <-time.After(200 * time.Millisecond):
+			}
+			e.mutex.Lock()
+			entries := append([]logEntry{}, c.logs[offset:]...)
+			offset = len(c.logs)
+			_, exists := e.containers[c.id]
+			running := c.state == stateRunning || c.state == statePaused
+			e.mutex.Unlock()
+			for _, entry := range entries {
+				if match(entry) {
+					if err := write(entry); err != nil {
+						return
+					}
+				}
+			}
+			if !exists || !running {
+				break
+			}
+		}
+		_ = writer.Close()
+	}()
+	return reader, nil
+}
@@ -0,0 +1,252 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"sort"
+	"strings"
+)
+
+type fakeNetwork struct {
+	network.Inspect
+	predefined bool
+	subnet     int
+	nextIP     int
+}
+
+func (e *Engine) addNetwork(name, driver string, labels map[string]string, predefined bool) *fakeNetwork {
+	n := &fakeNetwork{
+		Inspect: network.Inspect{
+			Name:       name,
+			ID:         e.newID("network"),
+			Created:    e.Now(),
+			Scope:      "local",
+			Driver:     driver,
+			Containers: make(map[string]network.EndpointResource),
+			Options:    make(map[string]string),
+			Labels:     copyLabels(labels),
+			IPAM:       network.IPAM{Driver: "default", Options: map[string]string{}},
+		},
+		predefined: predefined,
+		subnet:     17 + len(e.networks),
+		nextIP:     2,
+	}
+	if driver == "bridge" {
+		n.IPAM.Config = []network.IPAMConfig{{
+			Subnet:  fmt.Sprintf("172.%d.0.0/16", n.subnet),
+			Gateway: fmt.Sprintf("172.%d.0.1", n.subnet),
+		}}
+	}
+	e.networks[n.ID] = n
+	return n
+}
+
+func (e *Engine) findNetwork(ref string) (*fakeNetwork, error) {
+	if n, ok := e.networks[ref]; ok {
+		return n, nil
+	}
+	for _, n := range e.networks {
+		if n.Name == ref {
+			return n, nil
+		}
+	}
+	if ref != "" {
+		for _, n := range e.networks {
+			if strings.HasPrefix(n.ID, ref) {
+				return n, nil
+			}
+		}
+	}
+	return nil, errdefs.NotFound(fmt.Errorf("network %s not found", ref))
+}
+
+func (e *Engine) inspectNetwork(n *fakeNetwork) network.Inspect {
+	result := n.Inspect
+	result.Containers = make(map[string]network.EndpointResource, len(n.Containers))
+	for id, item := range n.Containers {
+		result.Containers[id] = item
+	}
+	result.Labels = copyLabels(n.Labels)
+	return result
+}
+
+func (e *Engine) NetworkList(ctx context.Context, options network.ListOptions) ([]network.Summary, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	result := make([]network.Summary, 0)
+	for _, n := range e.networks {
+		if !matchContains(options.Filters, "name", n.Name) ||
+			!matchPrefix(options.Filters, "id", n.ID) ||
+			!matchLabels(options.Filters, n.Labels) {
+			continue
+		}
+		if drivers := options.Filters.Get("driver"); len(drivers) > 0 && !containsString(drivers, n.Driver) {
+			continue
+		}
+		item := e.inspectNetwork(n)
+		// 与 Docker 一致，列表中不返回容器信息
+		item.Containers = map[string]network.EndpointResource{}
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func (e *Engine) NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	n, err := e.findNetwork(networkID)
+	if err != nil {
+		return network.Inspect{}, err
+	}
+	return e.inspectNetwork(n), nil
+}
+
+func (e *Engine) NetworkCreate(ctx context.Context, name string, options network.CreateOptions) (network.CreateResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if name == "" {
+		return network.CreateResponse{}, invalidParameter("network name is required")
+	}
+	for _, n := range e.networks {
+		if n.Name == name {
+			return network.CreateResponse{}, conflict("network with name %s already exists", name)
+		}
+	}
+	driver := options.Driver
+	if driver == "" {
+		driver = "bridge"
+	}
+	n := e.addNetwork(name, driver, options.Labels, false)
+	n.Internal = options.Internal
+	n.Attachable = options.Attachable
+	if options.EnableIPv6 != nil {
+		n.EnableIPv6 = *options.EnableIPv6
+	}
+	for k, v := range options.Options {
+		n.Options[k] = v
+	}
+	if options.IPAM != nil && len(options.IPAM.Config) > 0 {
+		n.IPAM.Config = append([]network.IPAMConfig{}, options.IPAM.Config...)
+	}
+	return network.CreateResponse{ID: n.ID}, nil
+}
+
+func (e *Engine) NetworkConnect(ctx context.Context, networkID, containerID string, config *network.EndpointSettings) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	n, err := e.findNetwork(networkID)
+	if err != nil {
+		return err
+	}
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if n.Driver == "host" || n.Driver == "null" {
+		return errdefs.Forbidden(fmt.Errorf("container cannot be disconnected from host network or connected to host network"))
+	}
+	if _, ok := c.networks[n.Name]; ok {
+		return errdefs.Forbidden(fmt.Errorf("endpoint with name %s already exists in network %s", c.name, n.Name))
+	}
+	if config == nil {
+		config = &network.EndpointSettings{}
+	}
+	e.connect(n, c, config)
+	return nil
+}
+
+func (e *Engine) connect(n *fakeNetwork, c *fakeContainer, config *network.EndpointSettings) {
+	settings := *config
+	settings.NetworkID = n.ID
+	settings.EndpointID = e.newID("endpoint")
+	if settings.IPAMConfig != nil && settings.IPAMConfig.IPv4Address != "" {
+		settings.IPAddress = settings.IPAMConfig.IPv4Address
+	} else if n.Driver == "bridge" {
+		settings.IPAddress = fmt.Sprintf("172.%d.0.%d", n.subnet, n.nextIP)
+		n.nextIP++
+	}
+	if settings.IPAddress != "" {
+		settings.IPPrefixLen = 16
+		settings.Gateway = fmt.Sprintf("172.%d.0.1", n.subnet)
+	}
+	if settings.IPAMConfig != nil {
+		settings.GlobalIPv6Address = settings.IPAMConfig.IPv6Address
+	}
+	if settings.MacAddress == "" {
+		settings.MacAddress = fmt.Sprintf("02:42:ac:%02x:00:%02x", n.subnet, n.nextIP%256)
+	}
+	c.networks[n.Name] = &settings
+	n.Containers[c.id] = network.EndpointResource{
+		Name:        c.name,
+		EndpointID:  settings.EndpointID,
+		MacAddress:  settings.MacAddress,
+		IPv4Address: settings.IPAddress,
+		IPv6Address: settings.GlobalIPv6Address,
+	}
+}
+
+func (e *Engine) NetworkDisconnect(ctx context.Context, networkID, containerID string, force bool) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	n, err := e.findNetwork(networkID)
+	if err != nil {
+		return err
+	}
+	c, err := e.findContainer(containerID)
+	if err != nil {
+		// 强制断开时允许容器已被删除
+		if force {
+			for id, item := range n.Containers {
+				if item.Name == strings.TrimPrefix(containerID, "/") || id == containerID {
+					delete(n.Containers, id)
+					return nil
+				}
+			}
+		}
+		return err
+	}
+	if _, ok := c.networks[n.Name]; !ok {
+		return errdefs.Forbidden(fmt.Errorf("container %s is not connected to network %s", c.id, n.Name))
+	}
+	delete(c.networks, n.Name)
+	delete(n.Containers, c.id)
+	return nil
+}
+
+func (e *Engine) NetworkRemove(ctx context.Context, networkID string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	n, err := e.findNetwork(networkID)
+	if err != nil {
+		return err
+	}
+	if n.predefined {
+		return errdefs.Forbidden(fmt.Errorf("%s is a pre-defined network and cannot be removed", n.Name))
+	}
+	if len(n.Containers) > 0 {
+		return errdefs.Forbidden(fmt.Errorf("error while removing network: network %s id %s has active endpoints", n.Name, n.ID))
+	}
+	delete(e.networks, n.ID)
+	return nil
+}
+
+func (e *Engine) NetworksPrune(ctx context.Context, pruneFilters filters.Args) (network.PruneReport, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	report := network.PruneReport{NetworksDeleted: []string{}}
+	for id, n := range e.networks {
+		if n.predefined || len(n.Containers) > 0 || !matchLabels(pruneFilters, n.Labels) {
+			continue
+		}
+		delete(e.networks, id)
+		report.NetworksDeleted = append(report.NetworksDeleted, n.Name)
+	}
+	sort.Strings(report.NetworksDeleted)
+	return report, nil
+}
@@ -0,0 +1,259 @@
+package fake
+
+import (
+	"context"
+	"crypto/sha256"
+	"cyber-docker/pkg/docker"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"io"
+	"strings"
+)
+
+// remoteImage 是模拟镜像仓库中的一个 tag
+type remoteImage struct {
+	digest string
+	size   int64
+	labels map[string]string
+	// platforms 不为空时该 tag 是多架构的 manifest list
+	platforms []ocispec.Platform
+}
+
+func repository(ref string) string {
+	ref = normalizeRef(ref)
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[:i]
+	}
+	return ref[:strings.LastIndex(ref, ":")]
+}
+
+// registryHost 返回引用所在的仓库地址，没有地址前缀时为 docker.io
+func registryHost(ref string) string {
+	first, _, ok := strings.Cut(ref, "/")
+	if ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return first
+	}
+	return "docker.io"
+}
+
+// AddRegistryUser 添加仓库用户，添加后访问该仓库的镜像必须提供有效凭据
+func (e *Engine) AddRegistryUser(host, username, password string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.registryUsers[host] == nil {
+		e.registryUsers[host] = make(map[string]string)
+	}
+	e.registryUsers[host][username] = password
+}
+
+// authorize 校验 base64 编码的 registry.AuthConfig，IdentityToken 与密码相同即视为有效
+func (e *Engine) authorize(ref, encodedAuth string) error {
+	host := registryHost(ref)
+	users, ok := e.registryUsers[host]
+	if !ok {
+		return nil
+	}
+	denied := errdefs.Unauthorized(fmt.Errorf("Head \"https://%s/v2/%s/manifests\": unauthorized: authentication required", host, strings.TrimPrefix(repository(ref), host+"/")))
+	if encodedAuth == "" {
+		return denied
+	}
+	auth, err := registry.DecodeAuthConfig(encodedAuth)
+	if err != nil {
+		return err
+	}
+	password, ok := users[auth.Username]
+	if !ok || (auth.Password != password && auth.IdentityToken != password) {
+		return denied
+	}
+	return nil
+}
+
+// RegistryLogin 校验模拟仓库的用户名和密码，未添加用户的仓库接受任意凭据
+func (e *Engine) RegistryLogin(ctx context.Context, auth registry.AuthConfig) (registry.AuthenticateOKBody, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	host := docker.NormalizeRegistryHost(auth.ServerAddress)
+	if host == "" {
+		host = "docker.io"
+	}
+	if users, ok := e.registryUsers[host]; ok {
+		password, ok := users[auth.Username]
+		if !ok || (auth.Password != password && auth.IdentityToken != password) {
+			return registry.AuthenticateOKBody{}, errdefs.Unauthorized(fmt.Errorf("Error response from daemon: Get \"https://%s/v2/\": unauthorized: incorrect username or password", host))
+		}
+	}
+	return registry.AuthenticateOKBody{Status: "Login Succeeded"}, nil
+}
+
+// AddRemoteImage 在模拟仓库中发布镜像并返回 manifest digest，重复发布同一引用会得到新的 digest
+func (e *Engine) AddRemoteImage(ref string, size int64, labels map[string]string) string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	sum := sha256.Sum256([]byte(e.newID("manifest")))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	e.remote[normalizeRef(ref)] = &remoteImage{digest: digest, size: size, labels: copyLabels(labels)}
+	return digest
+}
+
+// SetRemotePlatforms 把模拟仓库中的 tag 变为包含指定平台的 manifest list
+func (e *Engine) SetRemotePlatforms(ref string, platforms ...ocispec.Platform) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	remote, err := e.findRemote(ref)
+	if err != nil {
+		return err
+	}
+	remote.platforms = append([]ocispec.Platform{}, platforms...)
+	return nil
+}
+
+// DistributionInspect 返回模拟仓库中 tag 的 manifest 描述
+func (e *Engine) DistributionInspect(ctx context.Context, imageRef, encodedRegistryAuth string) (registry.DistributionInspect, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if err := e.authorize(imageRef, encodedRegistryAuth); err != nil {
+		return registry.DistributionInspect{}, err
+	}
+	remote, err := e.findRemote(imageRef)
+	if err != nil {
+		return registry.DistributionInspect{}, err
+	}
+	result := registry.DistributionInspect{
+		Descriptor: ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.Digest(remote.digest),
+			Size:      remote.size,
+		},
+		Platforms: []ocispec.Platform{{OS: "linux", Architecture: "amd64"}},
+	}
+	if len(remote.platforms) > 0 {
+		result.Descriptor.MediaType = ocispec.MediaTypeImageIndex
+		result.Platforms = append([]ocispec.Platform{}, remote.platforms...)
+	}
+	return result, nil
+}
+
+func (e *Engine) findRemote(ref string) (*remoteImage, error) {
+	if r, ok := e.remote[normalizeRef(ref)]; ok {
+		return r, nil
+	}
+	return nil, errdefs.NotFound(fmt.Errorf("manifest for %s not found: manifest unknown: manifest unknown", normalizeRef(ref)))
+}
+
+// pullRemote 把模拟仓库中的镜像添加到本地
+func (e *Engine) pullRemote(ref string, remote *remoteImage) *fakeImage {
+	ref = normalizeRef(ref)
+	img := e.addImage(remote.size, remote.labels, &container.Config{Cmd: []string{"sh"}})
+	img.digests = []string{repository(ref) + "@" + remote.digest}
+	_ = e.tag(img, ref)
+	return img
+}
+
+// ImagePull 从模拟仓库拉取镜像，输出与 Docker 相同格式的 JSON 进度消息
+func (e *Engine) ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if err := e.authorize(refStr, options.RegistryAuth); err != nil {
+		return nil, err
+	}
+	remote, err := e.findRemote(refStr)
+	if err != nil {
+		return nil, err
+	}
+	ref := normalizeRef(refStr)
+	repo := repository(ref)
+	tag := strings.TrimPrefix(ref, repo+":")
+	repoDigest := repo + "@" + remote.digest
+
+	messages := []map[string]interface{}{{"status": "Pulling from " + repo, "id": tag}}
+	if img, err := e.findImage(ref); err == nil && containsString(img.digests, repoDigest) {
+		messages = append(messages,
+			map[string]interface{}{"status": "Digest: " + remote.digest},
+			map[string]interface{}{"status": "Status: Image is up to date for " + ref},
+		)
+	} else {
+		layer := shortID(remote.digest)
+		messages = append(messages,
+			map[string]interface{}{"status": "Pulling fs layer", "id": layer},
+			map[string]interface{}{"status": "Downloading", "id": layer, "progressDetail": map[string]int64{"current": remote.size / 2, "total": remote.size}},
+			map[string]interface{}{"status": "Downloading", "id": layer, "progressDetail": map[string]int64{"current": remote.size, "total": remote.size}},
+			map[string]interface{}{"status": "Download complete", "id": layer},
+			map[string]interface{}{"status": "Pull complete", "id": layer},
+			map[string]interface{}{"status": "Digest: " + remote.digest},
+			map[string]interface{}{"status": "Status: Downloaded newer image for " + ref},
+		)
+		e.pullRemote(ref, remote)
+	}
+	return streamMessages(ctx, messages), nil
+}
+
+func (e *Engine) ImageTag(ctx context.Context, source, target string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	img, err := e.findImage(source)
+	if err != nil {
+		return err
+	}
+	return e.tag(img, target)
+}
+
+// ImagePush 把本地带 tag 的镜像发布到模拟仓库，每次推送生成新的 manifest digest
+func (e *Engine) ImagePush(ctx context.Context, refStr string, options image.PushOptions) (io.ReadCloser, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	ref := normalizeRef(refStr)
+	img, err := e.findImage(ref)
+	if err != nil || !containsString(img.tags, ref) {
+		return nil, errdefs.NotFound(fmt.Errorf("An image does not exist locally with the tag: %s", repository(ref)))
+	}
+	if err := e.authorize(ref, options.RegistryAuth); err != nil {
+		return nil, err
+	}
+	repo := repository(ref)
+	tag := strings.TrimPrefix(ref, repo+":")
+	sum := sha256.Sum256([]byte(e.newID("manifest")))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	e.remote[ref] = &remoteImage{digest: digest, size: img.size, labels: copyLabels(img.labels)}
+	repoDigest := repo + "@" + digest
+	if !containsString(img.digests, repoDigest) {
+		img.digests = append(img.digests, repoDigest)
+	}
+
+	layer := shortID(img.id)
+	messages := []map[string]interface{}{
+		{"status": "The push refers to repository [" + repo + "]"},
+		{"status": "Preparing", "id": layer},
+		{"status": "Pushing", "id": layer, "progressDetail": map[string]int64{"current": img.size / 2, "total": img.size}},
+		{"status": "Pushing", "id": layer, "progressDetail": map[string]int64{"current": img.size, "total": img.size}},
+		{"status": "Pushed", "id": layer},
+		{"status": fmt.Sprintf("%s: digest: %s size: %d", tag, digest, 528)},
+		{"progressDetail": map[string]interface{}{}, "aux": map[string]interface{}{"Tag": tag, "Digest": digest, "Size": 528}},
+	}
+	return streamMessages(ctx, messages), nil
+}
+
+// streamMessages 逐条输出 JSON 消息，ctx 取消时以错误结束
+func streamMessages(ctx context.Context, messages []map[string]interface{}) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		encoder := json.NewEncoder(writer)
+		for _, item := range messages {
+			if ctx.Err() != nil {
+				_ = writer.CloseWithError(ctx.Err())
+				return
+			}
+			if err := encoder.Encode(item); err != nil {
+				return
+			}
+		}
+		_ = writer.Close()
+	}()
+	return reader
+}
@@ -0,0 +1,67 @@
+package fake
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"io"
+	"strings"
+)
+
+// savedConfig 是 docker save 包中镜像配置文件的一部分
+type savedConfig struct {
+	Architecture string            `json:"architecture"`
+	OS           string            `json:"os"`
+	Variant      string            `json:"variant,omitempty"`
+	Created      string            `json:"created"`
+	Config       *container.Config `json:"config"`
+}
+
+// ImageSave 输出 docker save 格式的 tar 包，按 tag 引用的镜像带上该 tag，按 ID 引用的不带 tag。
+// 平台选项是 SDK 的私有类型，这里无法读取，总是导出完整镜像
+func (e *Engine) ImageSave(ctx context.Context, imageIDs []string, saveOpts ...client.ImageSaveOption) (io.ReadCloser, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	var manifests []loadManifest
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	written := make(map[string]bool)
+	for _, ref := range imageIDs {
+		img, err := e.findImage(ref)
+		if err != nil {
+			return nil, err
+		}
+		hex := strings.TrimPrefix(img.id, "sha256:")
+		item := loadManifest{Config: hex + ".json", Layers: []string{hex + "/layer.tar"}}
+		if containsString(img.tags, normalizeRef(ref)) {
+			item.RepoTags = []string{normalizeRef(ref)}
+		}
+		manifests = append(manifests, item)
+		if written[img.id] {
+			continue
+		}
+		written[img.id] = true
+
+		config := *img.config
+		config.Labels = copyLabels(img.labels)
+		saved := savedConfig{Architecture: "amd64", OS: "linux", Created: formatTime(img.created), Config: &config}
+		if img.platform != nil {
+			saved.Architecture, saved.OS, saved.Variant = img.platform.Architecture, img.platform.OS, img.platform.Variant
+		}
+		content, _ := json.Marshal(saved)
+		writeFile(tw, item.Config, content)
+		writeFile(tw, item.Layers[0], make([]byte, img.size))
+	}
+	content, _ := json.Marshal(manifests)
+	writeFile(tw, "manifest.json", content)
+	_ = tw.Close()
+	return io.NopCloser(&buf), nil
+}
+
+func writeFile(tw *tar.Writer, name string, content []byte) {
+	_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))})
+	_, _ = tw.Write(content)
+}
@@ -0,0 +1,149 @@
+package fake
+
+import (
+	"context"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+	"sort"
+)
+
+const anonymousLabel = "com.docker.volume.anonymous"
+
+type fakeVolume struct {
+	volume.Volume
+	anonymous bool
+}
+
+// ensureVolume 返回已有的卷，不存在时创建，name 为空时创建匿名卷
+func (e *Engine) ensureVolume(name string) *fakeVolume {
+	if v, ok := e.volumes[name]; ok {
+		return v
+	}
+	anonymous := name == ""
+	if anonymous {
+		name = e.newID("volume")
+	}
+	return e.addVolume(name, "local", nil, nil, anonymous)
+}
+
+func (e *Engine) addVolume(name, driver string, labels, options map[string]string, anonymous bool) *fakeVolume {
+	labels = copyLabels(labels)
+	if anonymous {
+		labels[anonymousLabel] = ""
+	}
+	v := &fakeVolume{
+		Volume: volume.Volume{
+			Name:       name,
+			Driver:     driver,
+			Mountpoint: "/var/lib/docker/volumes/" + name + "/_data",
+			CreatedAt:  formatTime(e.Now()),
+			Labels:     labels,
+			Options:    copyLabels(options),
+			Scope:      "local",
+		},
+		anonymous: anonymous,
+	}
+	e.volumes[name] = v
+	return v
+}
+
+func (e *Engine) volumeInUse(name string) bool {
+	for _, c := range e.containers {
+		for _, item := range c.mounts {
+			if item.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *Engine) VolumeList(ctx context.Context, options volume.ListOptions) (volume.ListResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	result := volume.ListResponse{Volumes: []*volume.Volume{}, Warnings: []string{}}
+	for _, v := range e.volumes {
+		if !matchContains(options.Filters, "name", v.Name) || !matchLabels(options.Filters, v.Labels) {
+			continue
+		}
+		if values := options.Filters.Get("dangling"); len(values) > 0 {
+			want := values[0] == "true" || values[0] == "1"
+			if want == e.volumeInUse(v.Name) {
+				continue
+			}
+		}
+		item := v.Volume
+		item.Labels = copyLabels(v.Labels)
+		result.Volumes = append(result.Volumes, &item)
+	}
+	sort.Slice(result.Volumes, func(i, j int) bool {
+		return result.Volumes[i].Name < result.Volumes[j].Name
+	})
+	return result, nil
+}
+
+func (e *Engine) VolumeInspect(ctx context.Context, volumeID string) (volume.Volume, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	v, ok := e.volumes[volumeID]
+	if !ok {
+		return volume.Volume{}, notFound("volume", volumeID)
+	}
+	item := v.Volume
+	item.Labels = copyLabels(v.Labels)
+	return item, nil
+}
+
+func (e *Engine) VolumeCreate(ctx context.Context, options volume.CreateOptions) (volume.Volume, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	driver := options.Driver
+	if driver == "" {
+		driver = "local"
+	}
+	if v, ok := e.volumes[options.Name]; ok {
+		// 与 Docker 一致，同名同驱动的卷直接返回
+		if v.Driver != driver {
+			return volume.Volume{}, conflict("volume name %s already in use with driver %s", v.Name, v.Driver)
+		}
+		return v.Volume, nil
+	}
+	name, anonymous := options.Name, options.Name == ""
+	if anonymous {
+		name = e.newID("volume")
+	}
+	return e.addVolume(name, driver, options.Labels, options.DriverOpts, anonymous).Volume, nil
+}
+
+func (e *Engine) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if _, ok := e.volumes[volumeID]; !ok {
+		if force {
+			return nil
+		}
+		return notFound("volume", volumeID)
+	}
+	if e.volumeInUse(volumeID) {
+		return conflict("remove %s: volume is in use", volumeID)
+	}
+	delete(e.volumes, volumeID)
+	return nil
+}
+
+// VolumesPrune 默认只清理匿名卷，all=true 时清理所有未使用的卷
+func (e *Engine) VolumesPrune(ctx context.Context, pruneFilters filters.Args) (volume.PruneReport, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	all := pruneFilters.ExactMatch("all", "true") || pruneFilters.ExactMatch("all", "1")
+	report := volume.PruneReport{VolumesDeleted: []string{}}
+	for name, v := range e.volumes {
+		if (!all && !v.anonymous) || e.volumeInUse(name) || !matchLabels(pruneFilters, v.Labels) {
+			continue
+		}
+		delete(e.volumes, name)
+		report.VolumesDeleted = append(report.VolumesDeleted, name)
+	}
+	sort.Strings(report.VolumesDeleted)
+	return report, nil
+}
@@ -0,0 +1,462 @@
+package docker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"cyber-docker/pkg/container/di"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/errdefs"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	RegistryName = di.TypeInstanceToName(Registry{})
+
+	ErrEndpointNotFound = errors.New("endpoint not found")
+	ErrEndpointExists   = errors.New("endpoint already exists")
+	ErrEndpointReadOnly = errors.New("endpoint is defined in config and can not be changed")
+
+	endpointNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+func RegistryFrom(get di.Get) *Registry {
+	return get(RegistryName).(*Registry)
+}
+
+type EndpointTLS struct {
+	CACert     string `json:"ca_cert,omitempty"`
+	Cert       string `json:"cert,omitempty"`
+	Key        string `json:"key,omitempty"`
+	SkipVerify bool   `json:"skip_verify,omitempty"`
+}
+
+type Endpoint struct {
+	Name string `json:"name"`
+	// 为空时使用 DOCKER_HOST 等标准环境变量
+	Host   string            `json:"host"`
+	TLS    *EndpointTLS      `json:"tls,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func (e Endpoint) Validate() error {
+	if !endpointNamePattern.MatchString(e.Name) {
+		return fmt.Errorf("invalid endpoint name %q", e.Name)
+	}
+	if e.TLS != nil {
+		if _, err := e.TLS.config(); err != nil {
+			return fmt.Errorf("endpoint %s: %w", e.Name, err)
+		}
+	}
+	if e.Host == "" {
+		return nil
+	}
+	u, err := url.Parse(e.Host)
+	if err != nil {
+		return fmt.Errorf("endpoint %s: %w", e.Name, err)
+	}
+	switch u.Scheme {
+	case "unix", "npipe", "tcp", "http", "https":
+	default:
+		return fmt.Errorf("endpoint %s: unsupported scheme %q", e.Name, u.Scheme)
+	}
+	return nil
+}
+
+type EndpointStatus struct {
+	Healthy    bool      `json:"healthy"`
+	APIVersion string    `json:"api_version,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+type EndpointInfo struct {
+	Endpoint
+	Default bool `json:"default"`
+	// Static 为 true 时端点定义在配置文件中，不能通过接口修改或删除
+	Static bool            `json:"static"`
+	Status *EndpointStatus `json:"status,omitempty"`
+}
+
+// EndpointRepository 持久化通过接口添加的端点，配置文件中的端点不保存
+type EndpointRepository interface {
+	List() ([]Endpoint, error)
+	Save(endpoint Endpoint) error
+	Delete(name string) error
+}
+
+// Registry 管理多个 Docker 主机，客户端在首次使用时创建并缓存
+type Registry struct {
+	mutex       sync.RWMutex
+	defaultName string
+	endpoints   map[string]Endpoint
+	// static 记录配置文件中定义的端点，这些端点不能通过接口修改或删除
+	static  map[string]bool
+	clients map[string]*clientRef
+	status  map[string]EndpointStatus
+	factory EngineFactory
+	repo    EndpointRepository
+}
+
+// NewRegistry repo 为 nil 时通过接口添加的端点只保存在内存中
+func NewRegistry(repo EndpointRepository, defaultEndpoint Endpoint, endpoints ...Endpoint) (*Registry, error) {
+	return NewRegistryWithFactory(newEndpointClient, repo, defaultEndpoint, endpoints...)
+}
+
+// NewRegistryWithFactory 使用自定义的引擎创建方式，例如测试时使用 fake.Engine
+func NewRegistryWithFactory(factory EngineFactory, repo EndpointRepository, defaultEndpoint Endpoint, endpoints ...Endpoint) (*Registry, error) {
+	r := &Registry{
+		defaultName: defaultEndpoint.Name,
+		endpoints:   make(map[string]Endpoint),
+		static:      make(map[string]bool),
+		clients:     make(map[string]*clientRef),
+		status:      make(map[string]EndpointStatus),
+		factory:     factory,
+		repo:        repo,
+	}
+	for _, item := range append([]Endpoint{defaultEndpoint}, endpoints...) {
+		if err := r.add(item); err != nil {
+			return nil, err
+		}
+		r.static[item.Name] = true
+	}
+	if repo == nil {
+		return r, nil
+	}
+	saved, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range saved {
+		if r.static[item.Name] {
+			slog.Warn("docker", "skip saved endpoint", item.Name, "reason", "name is defined in config")
+			continue
+		}
+		if err = r.add(item); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Registry) add(endpoint Endpoint) error {
+	if err := endpoint.Validate(); err != nil {
+		return err
+	}
+	if _, ok := r.endpoints[endpoint.Name]; ok {
+		return ErrEndpointExists
+	}
+	r.endpoints[endpoint.Name] = endpoint
+	return nil
+}
+
+func (r *Registry) Default() string {
+	return r.defaultName
+}
+
+func (r *Registry) List() []EndpointInfo {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	list := make([]EndpointInfo, 0, len(r.endpoints))
+	for name := range r.endpoints {
+		list = append(list, r.info(name))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func (r *Registry) Get(name string) (EndpointInfo, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if _, ok := r.endpoints[name]; !ok {
+		return EndpointInfo{}, ErrEndpointNotFound
+	}
+	return r.info(name), nil
+}
+
+func (r *Registry) info(name string) EndpointInfo {
+	info := EndpointInfo{
+		Endpoint: r.endpoints[name],
+		Default:  name == r.defaultName,
+		Static:   r.static[name],
+	}
+	if status, ok := r.status[name]; ok {
+		info.Status = &status
+	}
+	return info
+}
+
+// Add 创建客户端并检查连通性，成功后才保存端点；TLS 配置无效或无法连接时不做任何修改
+func (r *Registry) Add(ctx context.Context, endpoint Endpoint) error {
+	if err := endpoint.Validate(); err != nil {
+		return err
+	}
+	r.mutex.RLock()
+	_, exists := r.endpoints[endpoint.Name]
+	r.mutex.RUnlock()
+	if exists {
+		return ErrEndpointExists
+	}
+	cli, status, err := r.connect(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.endpoints[endpoint.Name]; ok {
+		closeClient(endpoint.Name, cli)
+		return ErrEndpointExists
+	}
+	if r.repo != nil {
+		if err = r.repo.Save(endpoint); err != nil {
+			closeClient(endpoint.Name, cli)
+			return err
+		}
+	}
+	r.endpoints[endpoint.Name] = endpoint
+	r.clients[endpoint.Name] = &clientRef{engine: cli}
+	r.status[endpoint.Name] = status
+	return nil
+}
+
+// Update 检查新配置可以连接后替换端点，原来的客户端会被关闭
+func (r *Registry) Update(ctx context.Context, endpoint Endpoint) error {
+	if err := endpoint.Validate(); err != nil {
+		return err
+	}
+	if err := r.checkMutable(endpoint.Name); err != nil {
+		return err
+	}
+	cli, status, err := r.connect(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.endpoints[endpoint.Name]; !ok {
+		closeClient(endpoint.Name, cli)
+		return ErrEndpointNotFound
+	}
+	if r.repo != nil {
+		if err = r.repo.Save(endpoint); err != nil {
+			closeClient(endpoint.Name, cli)
+			return err
+		}
+	}
+	r.evict(endpoint.Name)
+	r.endpoints[endpoint.Name] = endpoint
+	r.clients[endpoint.Name] = &clientRef{engine: cli}
+	r.status[endpoint.Name] = status
+	return nil
+}
+
+func (r *Registry) Remove(name string) error {
+	if err := r.checkMutable(name); err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.endpoints[name]; !ok {
+		return ErrEndpointNotFound
+	}
+	if r.repo != nil {
+		if err := r.repo.Delete(name); err != nil && !errors.Is(err, ErrEndpointNotFound) {
+			return err
+		}
+	}
+	delete(r.endpoints, name)
+	r.evict(name)
+	return nil
+}
+
+func (r *Registry) checkMutable(name string) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if _, ok := r.endpoints[name]; !ok {
+		return ErrEndpointNotFound
+	}
+	if r.static[name] {
+		return ErrEndpointReadOnly
+	}
+	return nil
+}
+
+// connect 创建客户端并检查连通性，失败时关闭客户端
+func (r *Registry) connect(ctx context.Context, endpoint Endpoint) (Engine, EndpointStatus, error) {
+	cli, err := r.factory(endpoint)
+	if err != nil {
+		return nil, EndpointStatus{}, fmt.Errorf("endpoint %s: %w", endpoint.Name, err)
+	}
+	ping, err := cli.Ping(ctx)
+	if err != nil {
+		closeClient(endpoint.Name, cli)
+		return nil, EndpointStatus{}, errdefs.Unavailable(fmt.Errorf("endpoint %s: %w", endpoint.Name, err))
+	}
+	return cli, EndpointStatus{Healthy: true, APIVersion: ping.APIVersion, CheckedAt: time.Now()}, nil
+}
+
+// clientRef 记录客户端的使用者数量，端点修改或删除后等最后一个使用者释放时才关闭客户端，
+// 避免中断正在进行的日志、终端等长连接
+type clientRef struct {
+	engine Engine
+	refs   int
+	// evicted 为 true 时客户端已从缓存中移除
+	evicted bool
+}
+
+// evict 从缓存中移除客户端，没有使用者时立即关闭
+func (r *Registry) evict(name string) {
+	delete(r.status, name)
+	if ref, ok := r.clients[name]; ok {
+		delete(r.clients, name)
+		ref.evicted = true
+		if ref.refs == 0 {
+			closeClient(name, ref.engine)
+		}
+	}
+}
+
+func closeClient(name string, cli Engine) {
+	if err := cli.Close(); err != nil {
+		slog.Warn("docker", "close client", name, "err", err)
+	}
+}
+
+// Acquire 返回端点的客户端，首次使用时创建。使用完毕后必须调用 release，
+// 在此之前即使端点被修改或删除，客户端也不会被关闭
+func (r *Registry) Acquire(name string) (cli Engine, release func(), err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ref, ok := r.clients[name]
+	if !ok {
+		endpoint, ok := r.endpoints[name]
+		if !ok {
+			return nil, nil, ErrEndpointNotFound
+		}
+		engine, err := r.factory(endpoint)
+		if err != nil {
+			return nil, nil, fmt.Errorf("endpoint %s: %w", name, err)
+		}
+		ref = &clientRef{engine: engine}
+		r.clients[name] = ref
+	}
+	ref.refs++
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
+			ref.refs--
+			if ref.evicted && ref.refs == 0 {
+				closeClient(name, ref.engine)
+			}
+		})
+	}
+	return ref.engine, release, nil
+}
+
+// Ping 检查端点是否可用并记录结果
+func (r *Registry) Ping(ctx context.Context, name string) (EndpointStatus, error) {
+	cli, release, err := r.Acquire(name)
+	if err != nil {
+		return EndpointStatus{}, err
+	}
+	defer release()
+	ping, err := cli.Ping(ctx)
+	status := EndpointStatus{
+		Healthy:    err == nil,
+		APIVersion: ping.APIVersion,
+		CheckedAt:  time.Now(),
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	// 检查期间端点被修改时，结果属于旧的配置，不再记录
+	if ref, ok := r.clients[name]; ok && ref.engine == cli {
+		r.status[name] = status
+	}
+	return status, nil
+}
+
+// Watch 定期检查所有端点，直到 done 关闭
+func (r *Registry) Watch(done <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		for _, item := range r.List() {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			if _, err := r.Ping(ctx, item.Name); err != nil {
+				slog.Warn("docker", "ping endpoint", item.Name, "err", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Close 在服务退出时关闭所有缓存的客户端
+func (r *Registry) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var errs []error
+	for name, ref := range r.clients {
+		if err := ref.engine.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("endpoint %s: %w", name, err))
+		}
+	}
+	clear(r.clients)
+	return errors.Join(errs...)
+}
+
+func newEndpointClient(endpoint Endpoint) (Engine, error) {
+	if endpoint.Host == "" {
+		return NewDockerClientFromEnv()
+	}
+	var tlsConfig *tls.Config
+	if endpoint.TLS != nil {
+		var err error
+		if tlsConfig, err = endpoint.TLS.config(); err != nil {
+			return nil, err
+		}
+	}
+	return NewDockerClientFromHost(endpoint.Host, tlsConfig)
+}
+
+func (t *EndpointTLS) config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.SkipVerify,
+	}
+	if t.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(t.CACert)) {
+			return nil, errors.New("invalid tls ca certificate")
+		}
+		cfg.RootCAs = pool
+	}
+	if t.Cert != "" || t.Key != "" {
+		cert, err := tls.X509KeyPair([]byte(t.Cert), []byte(t.Key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
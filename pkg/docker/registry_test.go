@@ -0,0 +1,189 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	"sort"
+	"testing"
+)
+
+// stubEngine 只实现 Ping 和 Close，调用其他方法会 panic
+type stubEngine struct {
+	Engine
+	pingErr error
+	closed  int
+}
+
+func (e *stubEngine) Ping(ctx context.Context) (types.Ping, error) {
+	return types.Ping{APIVersion: "1.48"}, e.pingErr
+}
+
+func (e *stubEngine) Close() error {
+	e.closed++
+	return nil
+}
+
+type memoryEndpoints map[string]Endpoint
+
+func (m memoryEndpoints) List() ([]Endpoint, error) {
+	list := make([]Endpoint, 0, len(m))
+	for _, item := range m {
+		list = append(list, item)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+func (m memoryEndpoints) Save(endpoint Endpoint) error {
+	m[endpoint.Name] = endpoint
+	return nil
+}
+
+func (m memoryEndpoints) Delete(name string) error {
+	if _, ok := m[name]; !ok {
+		return ErrEndpointNotFound
+	}
+	delete(m, name)
+	return nil
+}
+
+// stubFactory 记录创建的引擎，pingErr 按端点名称指定
+func stubFactory(engines *[]*stubEngine, pingErr map[string]error) EngineFactory {
+	return func(endpoint Endpoint) (Engine, error) {
+		e := &stubEngine{pingErr: pingErr[endpoint.Name]}
+		*engines = append(*engines, e)
+		return e, nil
+	}
+}
+
+func TestRegistryAddRollsBackWhenUnreachable(t *testing.T) {
+	var engines []*stubEngine
+	repo := memoryEndpoints{}
+	r, err := NewRegistryWithFactory(stubFactory(&engines, map[string]error{"down": errors.New("connection refused")}), repo, Endpoint{Name: "local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Add(context.Background(), Endpoint{Name: "down", Host: "tcp://10.0.0.1:2376"})
+	if !errdefs.IsUnavailable(err) {
+		t.Fatalf("Add unreachable endpoint: got %v, want unavailable", err)
+	}
+	if _, err = r.Get("down"); !errors.Is(err, ErrEndpointNotFound) {
+		t.Errorf("unreachable endpoint is registered: %v", err)
+	}
+	if len(repo) != 0 {
+		t.Errorf("unreachable endpoint is saved: %v", repo)
+	}
+	if len(engines) != 1 || engines[0].closed != 1 {
+		t.Errorf("client of unreachable endpoint is not closed")
+	}
+
+	err = r.Add(context.Background(), Endpoint{Name: "tls", Host: "tcp://10.0.0.2:2376", TLS: &EndpointTLS{CACert: "invalid"}})
+	if err == nil {
+		t.Fatal("Add with invalid tls ca certificate succeeded")
+	}
+	if len(engines) != 1 {
+		t.Errorf("client is created for invalid tls config")
+	}
+}
+
+func TestRegistryPersistsEndpoints(t *testing.T) {
+	var engines []*stubEngine
+	repo := memoryEndpoints{}
+	factory := stubFactory(&engines, nil)
+	r, err := NewRegistryWithFactory(factory, repo, Endpoint{Name: "local"}, Endpoint{Name: "static", Host: "tcp://10.0.0.1:2376"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Add(context.Background(), Endpoint{Name: "prod", Host: "tcp://10.0.0.2:2376"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := repo["prod"]; !ok {
+		t.Fatal("added endpoint is not saved")
+	}
+	if _, ok := repo["static"]; ok {
+		t.Error("config endpoint is saved")
+	}
+
+	for _, name := range []string{"local", "static"} {
+		if err = r.Update(context.Background(), Endpoint{Name: name, Host: "tcp://10.0.0.3:2376"}); !errors.Is(err, ErrEndpointReadOnly) {
+			t.Errorf("Update %s: got %v, want ErrEndpointReadOnly", name, err)
+		}
+		if err = r.Remove(name); !errors.Is(err, ErrEndpointReadOnly) {
+			t.Errorf("Remove %s: got %v, want ErrEndpointReadOnly", name, err)
+		}
+	}
+
+	reloaded, err := NewRegistryWithFactory(factory, repo, Endpoint{Name: "local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := reloaded.Get("prod")
+	if err != nil {
+		t.Fatalf("saved endpoint is not loaded: %v", err)
+	}
+	if info.Static || info.Host != "tcp://10.0.0.2:2376" {
+		t.Errorf("loaded endpoint = %+v", info)
+	}
+	if err = reloaded.Remove("prod"); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo) != 0 {
+		t.Errorf("removed endpoint is still saved: %v", repo)
+	}
+}
+
+func TestRegistryClosesEvictedClientAfterRelease(t *testing.T) {
+	var engines []*stubEngine
+	r, err := NewRegistryWithFactory(stubFactory(&engines, nil), nil, Endpoint{Name: "local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Add(context.Background(), Endpoint{Name: "prod", Host: "tcp://10.0.0.1:2376"}); err != nil {
+		t.Fatal(err)
+	}
+	first := engines[0]
+
+	cli, release, err := r.Acquire("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cli != first {
+		t.Fatal("Acquire does not reuse the client created by Add")
+	}
+	if err = r.Update(context.Background(), Endpoint{Name: "prod", Host: "tcp://10.0.0.2:2376"}); err != nil {
+		t.Fatal(err)
+	}
+	if first.closed != 0 {
+		t.Fatal("client is closed while still in use")
+	}
+	next, releaseNext, err := r.Acquire("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next == first {
+		t.Fatal("Acquire returns the evicted client")
+	}
+
+	release()
+	release()
+	if first.closed != 1 {
+		t.Errorf("evicted client closed %d times after release, want 1", first.closed)
+	}
+
+	if err = r.Remove("prod"); err != nil {
+		t.Fatal(err)
+	}
+	second := next.(*stubEngine)
+	if second.closed != 0 {
+		t.Fatal("removed client is closed while still in use")
+	}
+	releaseNext()
+	if second.closed != 1 {
+		t.Errorf("removed client closed %d times after release, want 1", second.closed)
+	}
+}
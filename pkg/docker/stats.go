@@ -0,0 +1,130 @@
+package docker
+
+import (
+	"github.com/docker/docker/api/types/container"
+	"math"
+)
+
+// Stats 容器统计数据的派生指标，计算方式与 docker stats 命令一致，速率单位为字节每秒
+type Stats struct {
+	CPUPercent     float64 `json:"cpu_percent"`
+	MemoryUsage    uint64  `json:"memory_usage"`
+	MemoryLimit    uint64  `json:"memory_limit"`
+	MemoryPercent  float64 `json:"memory_percent"`
+	NetworkRx      uint64  `json:"network_rx"`
+	NetworkTx      uint64  `json:"network_tx"`
+	NetworkRxRate  float64 `json:"network_rx_rate"`
+	NetworkTxRate  float64 `json:"network_tx_rate"`
+	BlockRead      uint64  `json:"block_read"`
+	BlockWrite     uint64  `json:"block_write"`
+	BlockReadRate  float64 `json:"block_read_rate"`
+	BlockWriteRate float64 `json:"block_write_rate"`
+	PIDs           uint64  `json:"pids"`
+}
+
+// CalculateStats 根据当前和上一条统计数据计算派生指标，previous 为 nil 时速率为 0。
+// osType 为 ContainerStats 返回的守护进程系统类型
+func CalculateStats(previous, current *container.StatsResponse, osType string) Stats {
+	var stats Stats
+	if osType == "windows" {
+		stats.CPUPercent = cpuPercentWindows(current)
+		stats.MemoryUsage = current.MemoryStats.PrivateWorkingSet
+	} else {
+		stats.CPUPercent = cpuPercentUnix(current)
+		stats.MemoryUsage = memoryUsageNoCache(current.MemoryStats)
+		stats.MemoryLimit = current.MemoryStats.Limit
+		if stats.MemoryLimit != 0 {
+			stats.MemoryPercent = round(float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100)
+		}
+		stats.PIDs = current.PidsStats.Current
+	}
+	stats.NetworkRx, stats.NetworkTx = networkBytes(current)
+	stats.BlockRead, stats.BlockWrite = blockIOBytes(current, osType)
+
+	if previous == nil {
+		return stats
+	}
+	elapsed := current.Read.Sub(previous.Read).Seconds()
+	if elapsed <= 0 {
+		return stats
+	}
+	rx, tx := networkBytes(previous)
+	read, write := blockIOBytes(previous, osType)
+	stats.NetworkRxRate = rate(rx, stats.NetworkRx, elapsed)
+	stats.NetworkTxRate = rate(tx, stats.NetworkTx, elapsed)
+	stats.BlockReadRate = rate(read, stats.BlockRead, elapsed)
+	stats.BlockWriteRate = rate(write, stats.BlockWrite, elapsed)
+	return stats
+}
+
+func cpuPercentUnix(s *container.StatsResponse) float64 {
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	onlineCPUs := float64(s.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+	return round(cpuDelta / systemDelta * onlineCPUs * 100)
+}
+
+// cpuPercentWindows Windows 的 CPU 时间以 100 纳秒为单位
+func cpuPercentWindows(s *container.StatsResponse) float64 {
+	possible := uint64(s.Read.Sub(s.PreRead).Nanoseconds()) / 100 * uint64(s.NumProcs)
+	used := s.CPUStats.CPUUsage.TotalUsage - s.PreCPUStats.CPUUsage.TotalUsage
+	if possible == 0 || s.CPUStats.CPUUsage.TotalUsage < s.PreCPUStats.CPUUsage.TotalUsage {
+		return 0
+	}
+	return round(float64(used) / float64(possible) * 100)
+}
+
+// memoryUsageNoCache 去掉页缓存，cgroup v1 为 total_inactive_file，cgroup v2 为 inactive_file
+func memoryUsageNoCache(mem container.MemoryStats) uint64 {
+	if v, ok := mem.Stats["total_inactive_file"]; ok && v < mem.Usage {
+		return mem.Usage - v
+	}
+	if v := mem.Stats["inactive_file"]; v < mem.Usage {
+		return mem.Usage - v
+	}
+	return mem.Usage
+}
+
+func networkBytes(s *container.StatsResponse) (rx, tx uint64) {
+	for _, item := range s.Networks {
+		rx += item.RxBytes
+		tx += item.TxBytes
+	}
+	return rx, tx
+}
+
+func blockIOBytes(s *container.StatsResponse, osType string) (read, write uint64) {
+	if osType == "windows" {
+		return s.StorageStats.ReadSizeBytes, s.StorageStats.WriteSizeBytes
+	}
+	for _, entry := range s.BlkioStats.IoServiceBytesRecursive {
+		if entry.Op == "" {
+			continue
+		}
+		switch entry.Op[0] {
+		case 'r', 'R':
+			read += entry.Value
+		case 'w', 'W':
+			write += entry.Value
+		}
+	}
+	return read, write
+}
+
+// rate 计数器回绕或容器重启后数值变小时按 0 处理
+func rate(previous, current uint64, seconds float64) float64 {
+	if current < previous {
+		return 0
+	}
+	return round(float64(current-previous) / seconds)
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
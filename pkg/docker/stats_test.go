@@ -0,0 +1,134 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// loadStats 读取 stats 接口流式返回的两条记录
+func loadStats(t *testing.T, name string) (previous, current *container.StatsResponse) {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "stats", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	if err = dec.Decode(&previous); err != nil {
+		t.Fatal(err)
+	}
+	if err = dec.Decode(&current); err != nil {
+		t.Fatal(err)
+	}
+	return previous, current
+}
+
+// statsColumns 按 docker stats 的格式输出 CPU %、MEM USAGE / LIMIT、MEM %、NET I/O、BLOCK I/O 和 PIDS 列
+func statsColumns(s Stats, osType string) []string {
+	memory, memoryPercent, pids := units.BytesSize(float64(s.MemoryUsage)), "--", "--"
+	if osType != "windows" {
+		memory += " / " + units.BytesSize(float64(s.MemoryLimit))
+		memoryPercent = fmt.Sprintf("%.2f%%", s.MemoryPercent)
+		pids = fmt.Sprintf("%d", s.PIDs)
+	}
+	return []string{
+		fmt.Sprintf("%.2f%%", s.CPUPercent),
+		memory,
+		memoryPercent,
+		units.HumanSizeWithPrecision(float64(s.NetworkRx), 3) + " / " + units.HumanSizeWithPrecision(float64(s.NetworkTx), 3),
+		units.HumanSizeWithPrecision(float64(s.BlockRead), 3) + " / " + units.HumanSizeWithPrecision(float64(s.BlockWrite), 3),
+		pids,
+	}
+}
+
+func TestCalculateStats(t *testing.T) {
+	tests := []struct {
+		fixture string
+		osType  string
+		// docker stats 对 current 输出的各列
+		columns []string
+		memory  uint64
+		// 网络收发、磁盘读写速率
+		rates [4]float64
+	}{
+		{
+			// cgroup v1 同时有 inactive_file 和 total_inactive_file，以后者为准，Sync、Async、Total 不计入读写
+			"cgroup1.json", "linux",
+			[]string{"1.02%", "40.8MiB / 7.638GiB", "0.52%", "15.2MB / 2.35MB", "12.3MB / 4.1kB", "5"},
+			52318208 - 9531392,
+			[4]float64{34505.66, 5667.92, 0, 4088.73},
+		},
+		{
+			// cgroup v2 没有 percpu_usage，操作名为小写，多个网卡和设备累加
+			"cgroup2.json", "linux",
+			[]string{"63.29%", "186MiB / 1GiB", "18.16%", "989MB / 126MB", "53.5MB / 9.24MB", "24"},
+			270532608 - 75497472,
+			[4]float64{246814.27, 43193.72, 0, 1048156.74},
+		},
+		{
+			// Windows 的内存为 PrivateWorkingSet，磁盘读写来自 StorageStats，没有内存限制和进程数
+			"windows.json", "windows",
+			[]string{"3.91%", "64.19MiB", "--", "1.05MB / 524kB", "105MB / 2.1MB", "--"},
+			67305472,
+			[4]float64{48576, 24288, 4194304, 1048576},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			previous, current := loadStats(t, tt.fixture)
+			stats := CalculateStats(previous, current, tt.osType)
+			if columns := statsColumns(stats, tt.osType); !reflect.DeepEqual(columns, tt.columns) {
+				t.Errorf("columns = %q, want %q", columns, tt.columns)
+			}
+			if stats.MemoryUsage != tt.memory {
+				t.Errorf("memory usage = %d, want %d", stats.MemoryUsage, tt.memory)
+			}
+			rates := [4]float64{stats.NetworkRxRate, stats.NetworkTxRate, stats.BlockReadRate, stats.BlockWriteRate}
+			for i := range rates {
+				if math.Abs(rates[i]-tt.rates[i]) > 0.01 {
+					t.Errorf("rates = %v, want %v", rates, tt.rates)
+					break
+				}
+			}
+
+			// 第一条数据没有上一条，速率为 0
+			first := CalculateStats(nil, current, tt.osType)
+			if first.NetworkRxRate != 0 || first.BlockWriteRate != 0 || first.CPUPercent != stats.CPUPercent {
+				t.Errorf("without previous = %+v", first)
+			}
+			// 容器重启后计数器变小
+			if restarted := CalculateStats(current, previous, tt.osType); restarted.NetworkRxRate != 0 {
+				t.Errorf("restarted = %+v", restarted)
+			}
+		})
+	}
+}
+
+func TestCPUPercentUnix(t *testing.T) {
+	s := &container.StatsResponse{}
+	s.CPUStats.CPUUsage.TotalUsage = 300
+	s.CPUStats.CPUUsage.PercpuUsage = []uint64{100, 100, 50, 50}
+	s.CPUStats.SystemUsage = 2000
+	s.PreCPUStats.CPUUsage.TotalUsage = 100
+	s.PreCPUStats.SystemUsage = 1000
+	// 旧版本守护进程没有 online_cpus，按 percpu_usage 的数量计算
+	if got := cpuPercentUnix(s); got != 80 {
+		t.Errorf("percpu fallback = %v, want 80", got)
+	}
+	s.CPUStats.OnlineCPUs = 2
+	if got := cpuPercentUnix(s); got != 40 {
+		t.Errorf("online cpus = %v, want 40", got)
+	}
+	// CPU 时间没有变化时为 0
+	s.PreCPUStats.CPUUsage.TotalUsage = 300
+	if got := cpuPercentUnix(s); got != 0 {
+		t.Errorf("no cpu delta = %v, want 0", got)
+	}
+}
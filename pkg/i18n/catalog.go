@@ -0,0 +1,81 @@
+package i18n
+
+// catalogs 每种语言的信息，key 相同的信息参数顺序也必须相同
+var catalogs = map[string]map[string]string{
+	ZhCN: {
+		"success": "操作成功",
+
+		"error.invalid_parameter": "请求参数错误",
+		"error.unauthorized":      "未登录或登录已失效",
+		"error.forbidden":         "没有权限执行该操作",
+		"error.not_found":         "资源不存在",
+		"error.conflict":          "资源状态冲突",
+		"error.locked":            "账号已被锁定",
+		"error.canceled":          "请求已取消",
+		"error.not_implemented":   "当前端点不支持该操作",
+		"error.unavailable":       "服务暂时不可用",
+		"error.timeout":           "请求超时",
+		"error.internal":          "服务器内部错误",
+
+		"validation.separator":  "；",
+		"validation.json":       "请求体不是有效的 JSON",
+		"validation.type":       "%s 的类型应为 %s",
+		"validation.default":    "%s 校验失败（%s）",
+		"validation.required":   "%s 为必填字段",
+		"validation.oneof":      "%s 必须是 [%s] 中的一个",
+		"validation.len.string": "%s 的长度必须为 %s 个字符",
+		"validation.len.items":  "%s 必须包含 %s 项",
+		"validation.len.number": "%s 必须等于 %s",
+		"validation.min.string": "%s 的长度不能少于 %s 个字符",
+		"validation.min.items":  "%s 至少包含 %s 项",
+		"validation.min.number": "%s 不能小于 %s",
+		"validation.max.string": "%s 的长度不能超过 %s 个字符",
+		"validation.max.items":  "%s 最多包含 %s 项",
+		"validation.max.number": "%s 不能大于 %s",
+		"validation.gt.number":  "%s 必须大于 %s",
+		"validation.lt.number":  "%s 必须小于 %s",
+		"validation.email":      "%s 必须是有效的邮箱地址",
+		"validation.url":        "%s 必须是有效的 URL",
+		"validation.hostname":   "%s 必须是有效的主机名",
+		"validation.ip":         "%s 必须是有效的 IP 地址",
+		"validation.numeric":    "%s 必须是数字",
+	},
+	EnUS: {
+		"success": "Success",
+
+		"error.invalid_parameter": "Invalid request parameters",
+		"error.unauthorized":      "Authentication required",
+		"error.forbidden":         "You are not allowed to perform this operation",
+		"error.not_found":         "Resource not found",
+		"error.conflict":          "Resource state conflict",
+		"error.locked":            "Account is locked",
+		"error.canceled":          "Request canceled",
+		"error.not_implemented":   "Operation not supported by this endpoint",
+		"error.unavailable":       "Service temporarily unavailable",
+		"error.timeout":           "Request timed out",
+		"error.internal":          "Internal server error",
+
+		"validation.separator":  "; ",
+		"validation.json":       "Request body is not valid JSON",
+		"validation.type":       "%s must be of type %s",
+		"validation.default":    "%s failed validation (%s)",
+		"validation.required":   "%s is required",
+		"validation.oneof":      "%s must be one of [%s]",
+		"validation.len.string": "%s must be exactly %s characters long",
+		"validation.len.items":  "%s must contain exactly %s item(s)",
+		"validation.len.number": "%s must equal %s",
+		"validation.min.string": "%s must be at least %s characters long",
+		"validation.min.items":  "%s must contain at least %s item(s)",
+		"validation.min.number": "%s must be at least %s",
+		"validation.max.string": "%s must be at most %s characters long",
+		"validation.max.items":  "%s must contain at most %s item(s)",
+		"validation.max.number": "%s must be at most %s",
+		"validation.gt.number":  "%s must be greater than %s",
+		"validation.lt.number":  "%s must be less than %s",
+		"validation.email":      "%s must be a valid email address",
+		"validation.url":        "%s must be a valid URL",
+		"validation.hostname":   "%s must be a valid hostname",
+		"validation.ip":         "%s must be a valid IP address",
+		"validation.numeric":    "%s must be numeric",
+	},
+}
@@ -0,0 +1,129 @@
+// Package i18n 提供响应信息的多语言支持，语言按用户偏好、Accept-Language 请求头、默认语言的顺序确定
+package i18n
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ZhCN = "zh-CN"
+	EnUS = "en-US"
+	// Default 未配置默认语言时使用
+	Default = ZhCN
+)
+
+const (
+	contextKey    = "locale"
+	preferenceKey = "locale_preference"
+)
+
+// Supported 判断是否支持该语言，locale 必须是规范形式，例如 zh-CN
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Match 把语言标签匹配到支持的语言，例如 zh、zh-Hans、zh_CN 都匹配 zh-CN，无法匹配时返回空字符串
+func Match(tag string) string {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if tag == "" {
+		return ""
+	}
+	for locale := range catalogs {
+		if strings.ToLower(locale) == tag {
+			return locale
+		}
+	}
+	primary, _, _ := strings.Cut(tag, "-")
+	switch primary {
+	case "zh":
+		return ZhCN
+	case "en":
+		return EnUS
+	}
+	return ""
+}
+
+// Negotiate 按 q 值从高到低选择 Accept-Language 中第一个支持的语言，无法匹配时返回空字符串
+func Negotiate(header string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				q = v
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	for _, item := range candidates {
+		if locale := Match(item.tag); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+// Middleware 协商请求的语言，defaultLocale 不支持时使用 Default
+func Middleware(defaultLocale string) gin.HandlerFunc {
+	if !Supported(defaultLocale) {
+		defaultLocale = Default
+	}
+	return func(c *gin.Context) {
+		locale := Negotiate(c.GetHeader("Accept-Language"))
+		if locale == "" {
+			locale = defaultLocale
+		}
+		c.Set(contextKey, locale)
+		c.Next()
+	}
+}
+
+// WithPreference 保存用户设置的语言，优先于 Accept-Language
+func WithPreference(c *gin.Context, locale string) {
+	if Supported(locale) {
+		c.Set(preferenceKey, locale)
+	}
+}
+
+// Locale 返回当前请求使用的语言
+func Locale(c *gin.Context) string {
+	if v, ok := c.Get(preferenceKey); ok {
+		return v.(string)
+	}
+	if v, ok := c.Get(contextKey); ok {
+		return v.(string)
+	}
+	if locale := Negotiate(c.GetHeader("Accept-Language")); locale != "" {
+		return locale
+	}
+	return Default
+}
+
+// T 返回 key 在 locale 下的信息，缺少翻译时依次使用默认语言和 key 本身
+func T(locale, key string, args ...interface{}) string {
+	message, ok := catalogs[locale][key]
+	if !ok {
+		if message, ok = catalogs[Default][key]; !ok {
+			message = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
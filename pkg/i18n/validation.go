@@ -0,0 +1,110 @@
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// RegisterValidator 让校验错误使用 json、form 或 uri 标签中的字段名，与请求参数保持一致
+func RegisterValidator() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, key := range []string{"json", "form", "uri"} {
+			name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+			// 返回 - 会让校验器跳过该字段
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+// Validation 把 gin binding 的参数错误逐个字段翻译为 locale 下的信息，其他错误返回 false。
+// err 应为已经确定是参数错误的错误，请求体为空或被截断时 binding 返回的是 io.EOF 和 io.ErrUnexpectedEOF
+func Validation(locale string, err error) (string, bool) {
+	var (
+		fieldErrs validator.ValidationErrors
+		typeErr   *json.UnmarshalTypeError
+		syntaxErr *json.SyntaxError
+		messages  []string
+	)
+	switch {
+	case errors.As(err, &fieldErrs):
+		for _, item := range fieldErrs {
+			messages = append(messages, fieldMessage(locale, item))
+		}
+		return strings.Join(messages, T(locale, "validation.separator")), true
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return T(locale, "validation.type", field, typeErr.Type.String()), true
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		// 空请求体和被截断的请求体也按 JSON 格式错误处理
+		return T(locale, "validation.json"), true
+	}
+	return "", false
+}
+
+func fieldMessage(locale string, fe validator.FieldError) string {
+	field := fieldPath(fe)
+	tag := fe.Tag()
+	// gte、lte 与 min、max 的含义相同
+	switch tag {
+	case "gte":
+		tag = "min"
+	case "lte":
+		tag = "max"
+	}
+	switch tag {
+	case "required", "required_if", "required_with", "required_without":
+		return T(locale, "validation.required", field)
+	case "oneof":
+		return T(locale, "validation.oneof", field, strings.Join(strings.Fields(fe.Param()), ", "))
+	case "len", "min", "max":
+		return T(locale, "validation."+tag+"."+kindOf(fe.Kind()), field, fe.Param())
+	case "gt", "lt":
+		return T(locale, "validation."+tag+".number", field, fe.Param())
+	case "email", "url", "hostname", "ip", "numeric":
+		return T(locale, "validation."+tag, field)
+	}
+	return T(locale, "validation.default", field, tag)
+}
+
+// fieldPath 返回嵌套字段的路径，例如 ports[0].protocol。Namespace 以 DTO 类型名开头，
+// 嵌入的结构体没有标签，名称与 StructNamespace 中的相同，这些段都不出现在路径中
+func fieldPath(fe validator.FieldError) string {
+	names := strings.Split(fe.Namespace(), ".")
+	goNames := strings.Split(fe.StructNamespace(), ".")
+	if len(names) != len(goNames) {
+		return fe.Field()
+	}
+	path := make([]string, 0, len(names))
+	for i := 1; i < len(names); i++ {
+		if i < len(names)-1 && names[i] == goNames[i] {
+			continue
+		}
+		path = append(path, names[i])
+	}
+	return strings.Join(path, ".")
+}
+
+func kindOf(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return "items"
+	}
+	return "number"
+}
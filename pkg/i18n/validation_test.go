@@ -0,0 +1,107 @@
+package i18n
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin/binding"
+	"testing"
+)
+
+type testPort struct {
+	Protocol string `json:"protocol" binding:"oneof=tcp udp"`
+}
+
+type testPaging struct {
+	Limit int `form:"limit" binding:"lte=100"`
+}
+
+type testRequest struct {
+	testPaging
+	Name    string            `json:"name" binding:"required"`
+	Image   string            `json:"image" binding:"min=3"`
+	Ports   []testPort        `json:"ports" binding:"dive"`
+	Tags    []string          `json:"tags" binding:"max=1"`
+	Labels  map[string]string `json:"labels" binding:"len=1"`
+	Timeout int               `json:"timeout" binding:"gt=0"`
+	Owner   string            `uri:"owner" binding:"email"`
+	Ignored string            `json:"-" binding:"alpha"`
+}
+
+func valid() testRequest {
+	return testRequest{
+		Name:    "web",
+		Image:   "nginx",
+		Tags:    []string{"a"},
+		Labels:  map[string]string{"team": "payments"},
+		Timeout: 10,
+		Owner:   "ops@example.com",
+		Ignored: "x",
+	}
+}
+
+func TestValidation(t *testing.T) {
+	RegisterValidator()
+	tests := []struct {
+		name   string
+		locale string
+		modify func(r *testRequest)
+		want   string
+	}{
+		{"required", EnUS, func(r *testRequest) { r.Name = "" }, "name is required"},
+		{"required zh", ZhCN, func(r *testRequest) { r.Name = "" }, "name 为必填字段"},
+		{"min string", EnUS, func(r *testRequest) { r.Image = "a" }, "image must be at least 3 characters long"},
+		{"max items", EnUS, func(r *testRequest) { r.Tags = []string{"a", "b"} }, "tags must contain at most 1 item(s)"},
+		{"len items", EnUS, func(r *testRequest) { r.Labels = nil }, "labels must contain exactly 1 item(s)"},
+		{"gt number", EnUS, func(r *testRequest) { r.Timeout = 0 }, "timeout must be greater than 0"},
+		{"email from uri tag", EnUS, func(r *testRequest) { r.Owner = "ops" }, "owner must be a valid email address"},
+		{"nested slice path", EnUS, func(r *testRequest) { r.Ports = []testPort{{"tcp"}, {"sctp"}} }, "ports[1].protocol must be one of [tcp, udp]"},
+		{"embedded struct is flattened", EnUS, func(r *testRequest) { r.Limit = 101 }, "limit must be at most 100"},
+		{"json dash keeps go name", EnUS, func(r *testRequest) { r.Ignored = "1" }, "Ignored failed validation (alpha)"},
+		{"fields are joined", EnUS, func(r *testRequest) { r.Name, r.Timeout = "", 0 }, "name is required; timeout must be greater than 0"},
+		{"unsupported locale", "fr-FR", func(r *testRequest) { r.Name = "" }, "name 为必填字段"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.modify(&r)
+			err := binding.Validator.ValidateStruct(&r)
+			if err == nil {
+				t.Fatal("expected a validation error")
+			}
+			got, ok := Validation(tt.locale, err)
+			if !ok || got != tt.want {
+				t.Errorf("Validation = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+	r := valid()
+	if err := binding.Validator.ValidateStruct(&r); err != nil {
+		t.Fatalf("valid request: %v", err)
+	}
+}
+
+func TestValidationDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"field type", `{"timeout":"10"}`, "timeout must be of type int"},
+		{"body type", `[1]`, "body must be of type i18n.testRequest"},
+		{"syntax", `{"name":}`, "Request body is not valid JSON"},
+		{"truncated", `{"name":`, "Request body is not valid JSON"},
+		{"empty", ``, "Request body is not valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r testRequest
+			err := binding.JSON.BindBody([]byte(tt.body), &r)
+			got, ok := Validation(EnUS, err)
+			if !ok || got != tt.want {
+				t.Errorf("Validation(%v) = %q, %v, want %q", err, got, ok, tt.want)
+			}
+		})
+	}
+	if _, ok := Validation(EnUS, errors.New("connection refused")); ok {
+		t.Error("other errors are not validation errors")
+	}
+}
@@ -0,0 +1,42 @@
+// Package identity 保存当前请求的调用者，由认证中间件写入，供授权和审计使用
+package identity
+
+import "github.com/gin-gonic/gin"
+
+const contextKey = "identity"
+
+// Identity 是通过认证的调用者
+type Identity struct {
+	Username string `json:"username"`
+	// SessionID 为登录会话 ID，登出时作废
+	SessionID string `json:"session_id,omitempty"`
+	Role      string `json:"role"`
+	// Labels 不为空时只能访问带有全部这些标签的资源
+	Labels map[string]string `json:"labels,omitempty"`
+	// TokenID 通过 API 令牌认证时为令牌 ID
+	TokenID string `json:"token_id,omitempty"`
+	// Scopes 为 API 令牌允许的权限，实际权限是角色权限与 Scopes 的交集
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// InScope 判断带有 labels 的资源是否在调用者的访问范围内
+func (i *Identity) InScope(labels map[string]string) bool {
+	for key, value := range i.Labels {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+func WithContext(c *gin.Context, id *Identity) {
+	c.Set(contextKey, id)
+}
+
+// FromContext 未通过认证的请求返回 nil
+func FromContext(c *gin.Context) *Identity {
+	if v, ok := c.Get(contextKey); ok {
+		return v.(*Identity)
+	}
+	return nil
+}
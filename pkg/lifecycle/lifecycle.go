@@ -0,0 +1,89 @@
+package lifecycle
+
+import (
+	"context"
+	"cyber-docker/pkg/container/di"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+var Name = di.TypeInstanceToName(Manager{})
+
+func From(get di.Get) *Manager {
+	return get(Name).(*Manager)
+}
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Manager 管理进程退出流程：先取消所有长连接流，再按注册的逆序执行关闭钩子
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	mutex  sync.Mutex
+	hooks  []hook
+	once   sync.Once
+	err    error
+}
+
+func New() *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Done 在开始关闭时关闭
+func (m *Manager) Done() <-chan struct{} {
+	return m.ctx.Done()
+}
+
+// Bind 派生一个在 parent 结束或开始关闭时被取消的 context，用于 stats、日志、导入等长时间运行的流
+func (m *Manager) Bind(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	stop := context.AfterFunc(m.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (m *Manager) AddHook(name string, fn func(ctx context.Context) error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.hooks = append(m.hooks, hook{name: name, fn: fn})
+}
+
+func (m *Manager) AddCloser(name string, closer io.Closer) {
+	m.AddHook(name, func(ctx context.Context) error {
+		return closer.Close()
+	})
+}
+
+// Shutdown 只执行一次，重复调用返回第一次的结果
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.once.Do(func() {
+		m.cancel()
+
+		m.mutex.Lock()
+		hooks := make([]hook, len(m.hooks))
+		copy(hooks, m.hooks)
+		m.mutex.Unlock()
+
+		var errs []error
+		for i := len(hooks) - 1; i >= 0; i-- {
+			slog.Info("lifecycle", "closing", hooks[i].name)
+			if err := hooks[i].fn(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("close %s: %w", hooks[i].name, err))
+			}
+		}
+		m.err = errors.Join(errs...)
+	})
+	return m.err
+}
@@ -0,0 +1,165 @@
+// Package query 在内存中对 Docker 返回的列表做过滤、搜索、排序和分页，各资源通过 Fields 说明如何取出字段
+package query
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	SortCreated = "created"
+	SortName    = "name"
+	SortSize    = "size"
+	SortState   = "state"
+)
+
+// Params 是列表接口共用的查询参数，不传 page_size 时返回全部结果
+type Params struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=500"`
+	// 默认保持 Docker 返回的顺序
+	SortBy string `form:"sort_by" binding:"omitempty,oneof=created name size state"`
+	// created、size 默认降序，name、state 默认升序
+	Order string `form:"order" binding:"omitempty,oneof=asc desc"`
+	// 在名称、镜像、标签等文本中搜索，不区分大小写
+	Search string `form:"search"`
+	// key 或 key=value，可以重复，要求全部匹配
+	Label []string `form:"label"`
+	// 匹配任意一个状态即可
+	Status []string `form:"status"`
+}
+
+// Fields 从列表项中取出过滤和排序用到的字段，资源没有的字段为 nil，按该字段排序或过滤时返回错误
+type Fields[T any] struct {
+	Name    func(T) string
+	Created func(T) int64
+	Size    func(T) int64
+	State   func(T) string
+	Labels  func(T) map[string]string
+	// Text 返回名称和标签以外参与搜索的文本，例如容器的镜像
+	Text func(T) []string
+}
+
+// Apply 返回当前页的结果和过滤后的总数，参数不适用于该资源时返回的错误可以直接作为参数错误
+func Apply[T any](items []T, p Params, f Fields[T]) ([]T, int64, error) {
+	if len(p.Label) > 0 && f.Labels == nil {
+		return nil, 0, errors.New("label filter is not supported")
+	}
+	if len(p.Status) > 0 && f.State == nil {
+		return nil, 0, errors.New("status filter is not supported")
+	}
+	less, err := sortFunc(p, f)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	search := strings.ToLower(strings.TrimSpace(p.Search))
+	result := make([]T, 0, len(items))
+	for _, item := range items {
+		if matchLabels(p.Label, f, item) && matchStatus(p.Status, f, item) && matchSearch(search, f, item) {
+			result = append(result, item)
+		}
+	}
+	if less != nil {
+		sort.SliceStable(result, func(i, j int) bool {
+			return less(result[i], result[j])
+		})
+	}
+
+	total := int64(len(result))
+	if p.PageSize > 0 {
+		page := max(p.Page, 1)
+		start := min((page-1)*p.PageSize, len(result))
+		end := min(start+p.PageSize, len(result))
+		result = result[start:end]
+	}
+	return result, total, nil
+}
+
+func sortFunc[T any](p Params, f Fields[T]) (func(a, b T) bool, error) {
+	desc := p.Order == "desc"
+	var less func(a, b T) bool
+	switch p.SortBy {
+	case "":
+		return nil, nil
+	case SortName:
+		if f.Name != nil {
+			less = func(a, b T) bool { return strings.ToLower(f.Name(a)) < strings.ToLower(f.Name(b)) }
+		}
+	case SortState:
+		if f.State != nil {
+			less = func(a, b T) bool { return f.State(a) < f.State(b) }
+		}
+	case SortCreated:
+		if f.Created != nil {
+			less = func(a, b T) bool { return f.Created(a) < f.Created(b) }
+			desc = p.Order != "asc"
+		}
+	case SortSize:
+		if f.Size != nil {
+			less = func(a, b T) bool { return f.Size(a) < f.Size(b) }
+			desc = p.Order != "asc"
+		}
+	}
+	if less == nil {
+		return nil, fmt.Errorf("sorting by %s is not supported", p.SortBy)
+	}
+	if desc {
+		return func(a, b T) bool { return less(b, a) }, nil
+	}
+	return less, nil
+}
+
+func matchLabels[T any](filters []string, f Fields[T], item T) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	labels := f.Labels(item)
+	for _, filter := range filters {
+		key, value, hasValue := strings.Cut(filter, "=")
+		actual, ok := labels[key]
+		if !ok || (hasValue && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchStatus[T any](statuses []string, f Fields[T], item T) bool {
+	if len(statuses) == 0 {
+		return true
+	}
+	state := f.State(item)
+	for _, status := range statuses {
+		if strings.EqualFold(status, state) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSearch[T any](search string, f Fields[T], item T) bool {
+	if search == "" {
+		return true
+	}
+	var texts []string
+	if f.Name != nil {
+		texts = append(texts, f.Name(item))
+	}
+	if f.Text != nil {
+		texts = append(texts, f.Text(item)...)
+	}
+	if f.Labels != nil {
+		for key, value := range f.Labels(item) {
+			texts = append(texts, key+"="+value)
+		}
+	}
+	for _, text := range texts {
+		if strings.Contains(strings.ToLower(text), search) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+type item struct {
+	name    string
+	created int64
+	size    int64
+	state   string
+	labels  map[string]string
+	image   string
+}
+
+var fields = Fields[item]{
+	Name:    func(i item) string { return i.name },
+	Created: func(i item) int64 { return i.created },
+	Size:    func(i item) int64 { return i.size },
+	State:   func(i item) string { return i.state },
+	Labels:  func(i item) map[string]string { return i.labels },
+	Text:    func(i item) []string { return []string{i.image} },
+}
+
+var items = []item{
+	{name: "web", created: 3, size: 10, state: "running", labels: map[string]string{"team": "payments", "tier": "front"}, image: "nginx:1.25"},
+	{name: "Cache", created: 1, size: 30, state: "exited", labels: map[string]string{"team": "billing"}, image: "redis:7"},
+	{name: "db", created: 2, size: 20, state: "running", labels: map[string]string{"team": "billing", "backup": ""}, image: "postgres:16"},
+	{name: "api", created: 4, size: 5, state: "paused", image: "golang:1.23"},
+}
+
+func names(list []item) []string {
+	result := make([]string, 0, len(list))
+	for _, i := range list {
+		result = append(result, i.name)
+	}
+	return result
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		p     Params
+		want  []string
+		total int64
+	}{
+		{"docker order", Params{}, []string{"web", "Cache", "db", "api"}, 4},
+		{"first page", Params{PageSize: 3}, []string{"web", "Cache", "db"}, 4},
+		{"last page", Params{Page: 2, PageSize: 3}, []string{"api"}, 4},
+		{"past the end", Params{Page: 3, PageSize: 3}, []string{}, 4},
+		{"page without size", Params{Page: 2}, []string{"web", "Cache", "db", "api"}, 4},
+		{"name ascending by default", Params{SortBy: SortName}, []string{"api", "Cache", "db", "web"}, 4},
+		{"name descending", Params{SortBy: SortName, Order: "desc"}, []string{"web", "db", "Cache", "api"}, 4},
+		{"state ascending by default", Params{SortBy: SortState}, []string{"Cache", "api", "web", "db"}, 4},
+		{"created descending by default", Params{SortBy: SortCreated}, []string{"api", "web", "db", "Cache"}, 4},
+		{"created ascending", Params{SortBy: SortCreated, Order: "asc"}, []string{"Cache", "db", "web", "api"}, 4},
+		{"size descending by default", Params{SortBy: SortSize}, []string{"Cache", "db", "web", "api"}, 4},
+		{"size ascending", Params{SortBy: SortSize, Order: "asc"}, []string{"api", "web", "db", "Cache"}, 4},
+		{"sort then page", Params{SortBy: SortName, Page: 2, PageSize: 2}, []string{"db", "web"}, 4},
+		{"label key", Params{Label: []string{"team"}}, []string{"web", "Cache", "db"}, 3},
+		{"label key with empty value", Params{Label: []string{"backup"}}, []string{"db"}, 1},
+		{"label key=value", Params{Label: []string{"team=billing"}}, []string{"Cache", "db"}, 2},
+		{"label empty value", Params{Label: []string{"backup="}}, []string{"db"}, 1},
+		{"label empty value does not match missing key", Params{Label: []string{"tier="}}, []string{}, 0},
+		{"labels all match", Params{Label: []string{"team=billing", "backup"}}, []string{"db"}, 1},
+		{"status any", Params{Status: []string{"Exited", "paused"}}, []string{"Cache", "api"}, 2},
+		{"search name", Params{Search: " CACHE "}, []string{"Cache"}, 1},
+		{"search text", Params{Search: "redis"}, []string{"Cache"}, 1},
+		{"search labels", Params{Search: "team=pay"}, []string{"web"}, 1},
+		{"filters and total", Params{Status: []string{"running"}, SortBy: SortName, PageSize: 1}, []string{"db"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := Apply(items, tt.p, fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(names(got), tt.want) || total != tt.total {
+				t.Errorf("Apply = %v, %d, want %v, %d", names(got), total, tt.want, tt.total)
+			}
+		})
+	}
+}
+
+func TestApplyUnsupported(t *testing.T) {
+	nameOnly := Fields[item]{Name: fields.Name}
+	tests := []struct {
+		name string
+		p    Params
+		want string
+	}{
+		{"sort by created", Params{SortBy: SortCreated}, "sorting by created is not supported"},
+		{"sort by size", Params{SortBy: SortSize}, "sorting by size is not supported"},
+		{"sort by state", Params{SortBy: SortState}, "sorting by state is not supported"},
+		{"unknown sort field", Params{SortBy: "id"}, "sorting by id is not supported"},
+		{"label", Params{Label: []string{"team"}}, "label filter is not supported"},
+		{"status", Params{Status: []string{"running"}}, "status filter is not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Apply(items, tt.p, nameOnly)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Apply error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+	if _, _, err := Apply(items, Params{SortBy: SortName, Search: "web"}, nameOnly); err != nil {
+		t.Errorf("sorting by name: %v", err)
+	}
+}
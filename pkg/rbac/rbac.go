@@ -0,0 +1,167 @@
+// Package rbac 定义角色和权限，权限格式为 资源:操作，例如 containers:stop
+package rbac
+
+import (
+	"cyber-docker/pkg/identity"
+	"cyber-docker/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type Permission string
+
+const (
+	ContainersRead   Permission = "containers:read"
+	ContainersLogs   Permission = "containers:logs"
+	ContainersCreate Permission = "containers:create"
+	ContainersUpdate Permission = "containers:update"
+	ContainersStart  Permission = "containers:start"
+	ContainersStop   Permission = "containers:stop"
+	ContainersExec   Permission = "containers:exec"
+	ContainersCommit Permission = "containers:commit"
+	ContainersExport Permission = "containers:export"
+	ContainersDelete Permission = "containers:delete"
+	ContainersPrune  Permission = "containers:prune"
+
+	ImagesRead   Permission = "images:read"
+	ImagesPull   Permission = "images:pull"
+	ImagesPush   Permission = "images:push"
+	ImagesBuild  Permission = "images:build"
+	ImagesTag    Permission = "images:tag"
+	ImagesImport Permission = "images:import"
+	ImagesExport Permission = "images:export"
+	ImagesDelete Permission = "images:delete"
+	ImagesPrune  Permission = "images:prune"
+
+	NetworksRead    Permission = "networks:read"
+	NetworksCreate  Permission = "networks:create"
+	NetworksConnect Permission = "networks:connect"
+	NetworksDelete  Permission = "networks:delete"
+	NetworksPrune   Permission = "networks:prune"
+
+	VolumesRead   Permission = "volumes:read"
+	VolumesCreate Permission = "volumes:create"
+	VolumesDelete Permission = "volumes:delete"
+	VolumesPrune  Permission = "volumes:prune"
+
+	EndpointsRead   Permission = "endpoints:read"
+	EndpointsManage Permission = "endpoints:manage"
+
+	RegistriesRead   Permission = "registries:read"
+	RegistriesLogin  Permission = "registries:login"
+	RegistriesManage Permission = "registries:manage"
+
+	UsersManage  Permission = "users:manage"
+	SystemBackup Permission = "system:backup"
+	AuditRead    Permission = "audit:read"
+)
+
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleReadOnly = "read-only"
+)
+
+var readOnly = []Permission{
+	ContainersRead, ContainersLogs,
+	ImagesRead,
+	NetworksRead,
+	VolumesRead,
+	EndpointsRead,
+	RegistriesRead,
+}
+
+// rolePermissions 中的 * 匹配所有权限，资源:* 匹配该资源的所有操作
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {"*"},
+	RoleOperator: append([]Permission{
+		ContainersCreate, ContainersUpdate, ContainersStart, ContainersStop, ContainersExec,
+		ContainersCommit, ContainersExport, ContainersDelete,
+		ImagesPull, ImagesPush, ImagesBuild, ImagesTag, ImagesImport, ImagesExport,
+		NetworksCreate, NetworksConnect,
+		VolumesCreate,
+		RegistriesLogin,
+	}, readOnly...),
+	RoleReadOnly: readOnly,
+}
+
+var all = []Permission{
+	ContainersRead, ContainersLogs, ContainersCreate, ContainersUpdate, ContainersStart, ContainersStop,
+	ContainersExec, ContainersCommit, ContainersExport, ContainersDelete, ContainersPrune,
+	ImagesRead, ImagesPull, ImagesPush, ImagesBuild, ImagesTag, ImagesImport, ImagesExport, ImagesDelete, ImagesPrune,
+	NetworksRead, NetworksCreate, NetworksConnect, NetworksDelete, NetworksPrune,
+	VolumesRead, VolumesCreate, VolumesDelete, VolumesPrune,
+	EndpointsRead, EndpointsManage,
+	RegistriesRead, RegistriesLogin, RegistriesManage,
+	UsersManage, SystemBackup, AuditRead,
+}
+
+// Valid 判断 p 是否为已定义的权限，也接受 * 和 资源:*
+func Valid(p Permission) bool {
+	for _, item := range all {
+		resource, _, _ := strings.Cut(string(item), ":")
+		if p == "*" || item == p || p == Permission(resource+":*") {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Permissions 返回角色拥有的权限
+func Permissions(role string) []Permission {
+	return append([]Permission{}, rolePermissions[role]...)
+}
+
+// Match 判断 granted 中是否包含 p
+func Match(granted []Permission, p Permission) bool {
+	resource, _, _ := strings.Cut(string(p), ":")
+	for _, item := range granted {
+		if item == "*" || item == p || item == Permission(resource+":*") {
+			return true
+		}
+	}
+	return false
+}
+
+func Allowed(role string, p Permission) bool {
+	return Match(rolePermissions[role], p)
+}
+
+// Granted 判断调用者是否拥有权限 p，API 令牌还要求 p 在令牌的范围内
+func Granted(id *identity.Identity, p Permission) bool {
+	if !Allowed(id.Role, p) {
+		return false
+	}
+	if id.TokenID == "" {
+		return true
+	}
+	scopes := make([]Permission, 0, len(id.Scopes))
+	for _, item := range id.Scopes {
+		scopes = append(scopes, Permission(item))
+	}
+	return Match(scopes, p)
+}
+
+// Require 要求调用者拥有所有指定的权限
+func Require(permissions ...Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := identity.FromContext(c)
+		if id == nil {
+			utils.ResError(c, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		for _, p := range permissions {
+			if !Granted(id, p) {
+				utils.ResError(c, http.StatusForbidden, "permission denied: "+string(p))
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package rbac
+
+import (
+	"cyber-docker/pkg/identity"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []Permission
+		p       Permission
+		want    bool
+	}{
+		{"exact", []Permission{ContainersStop}, ContainersStop, true},
+		{"other action", []Permission{ContainersStop}, ContainersStart, false},
+		{"all", []Permission{"*"}, UsersManage, true},
+		{"resource wildcard", []Permission{"images:*"}, ImagesPush, true},
+		{"other resource wildcard", []Permission{"images:*"}, ContainersRead, false},
+		{"prefix is not a wildcard", []Permission{"containers"}, ContainersRead, false},
+		{"action wildcard is not supported", []Permission{"*:read"}, ContainersRead, false},
+		{"empty", nil, ContainersRead, false},
+		{"any of", []Permission{VolumesRead, ContainersRead}, ContainersRead, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.granted, tt.p); got != tt.want {
+				t.Errorf("Match(%v, %s) = %v, want %v", tt.granted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGranted(t *testing.T) {
+	tests := []struct {
+		name string
+		id   identity.Identity
+		p    Permission
+		want bool
+	}{
+		{"admin", identity.Identity{Role: RoleAdmin}, SystemBackup, true},
+		{"operator", identity.Identity{Role: RoleOperator}, ContainersStop, true},
+		{"operator cannot prune", identity.Identity{Role: RoleOperator}, ContainersPrune, false},
+		{"operator cannot manage users", identity.Identity{Role: RoleOperator}, UsersManage, false},
+		{"read-only reads", identity.Identity{Role: RoleReadOnly}, ContainersLogs, true},
+		{"read-only cannot start", identity.Identity{Role: RoleReadOnly}, ContainersStart, false},
+		{"no role", identity.Identity{}, ContainersRead, false},
+		{"unknown role", identity.Identity{Role: "root"}, ContainersRead, false},
+		{"token in scope", identity.Identity{Role: RoleOperator, TokenID: "t1", Scopes: []string{"containers:*"}}, ContainersStart, true},
+		{"token out of scope", identity.Identity{Role: RoleOperator, TokenID: "t1", Scopes: []string{"containers:read"}}, ContainersStart, false},
+		{"token scope beyond role", identity.Identity{Role: RoleReadOnly, TokenID: "t1", Scopes: []string{"*"}}, ContainersStart, false},
+		{"token without scopes", identity.Identity{Role: RoleAdmin, TokenID: "t1"}, ContainersRead, false},
+		{"scopes without token are ignored", identity.Identity{Role: RoleAdmin, Scopes: []string{"images:read"}}, ContainersRead, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Granted(&tt.id, tt.p); got != tt.want {
+				t.Errorf("Granted(%+v, %s) = %v, want %v", tt.id, tt.p, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Package secret 使用 AES-GCM 加密保存到磁盘的敏感数据
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// prefix 标识加密格式的版本，便于以后更换算法
+const prefix = "enc:v1:"
+
+var ErrInvalidCiphertext = errors.New("secret: invalid ciphertext")
+
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New 由任意长度的密钥派生 AES-256 密钥
+func New(key string) (*Cipher, error) {
+	if key == "" {
+		return nil, errors.New("secret: key is empty")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt 空字符串原样返回
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *Cipher) Decrypt(ciphertext string) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+	encoded, ok := strings.CutPrefix(ciphertext, prefix)
+	if !ok {
+		return "", ErrInvalidCiphertext
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("secret: decrypt failed, the key may have changed: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// LoadOrCreateKey 读取密钥文件，不存在时生成随机密钥并以 0600 权限保存
+func LoadOrCreateKey(path string) (string, error) {
+	buf, err := os.ReadFile(path)
+	if err == nil {
+		return strings.TrimSpace(string(buf)), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	key := make([]byte, 32)
+	if _, err = rand.Read(key); err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	value := hex.EncodeToString(key)
+	if err = os.WriteFile(path, []byte(value+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	return value, nil
+}
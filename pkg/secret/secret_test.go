@@ -0,0 +1,109 @@
+package secret
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newCipher(t *testing.T, key string) *Cipher {
+	t.Helper()
+	c, err := New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	c := newCipher(t, "key")
+	for _, plaintext := range []string{"", "s3cret", "多字节密码"} {
+		ciphertext, err := c.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plaintext != "" && (!strings.HasPrefix(ciphertext, prefix) || strings.Contains(ciphertext, plaintext)) {
+			t.Errorf("Encrypt(%q) = %q", plaintext, ciphertext)
+		}
+		got, err := c.Decrypt(ciphertext)
+		if err != nil || got != plaintext {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", plaintext, got, err)
+		}
+	}
+	// 每次加密使用不同的 nonce
+	first, _ := c.Encrypt("s3cret")
+	second, _ := c.Encrypt("s3cret")
+	if first == second {
+		t.Error("same plaintext is encrypted to the same ciphertext")
+	}
+	if _, err := New(""); err == nil {
+		t.Error("empty key is accepted")
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	c := newCipher(t, "key")
+	ciphertext, err := c.Encrypt("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 0xff
+	tampered := prefix + base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name       string
+		cipher     *Cipher
+		ciphertext string
+		invalid    bool
+	}{
+		{"wrong key", newCipher(t, "other"), ciphertext, false},
+		{"tampered", c, tampered, false},
+		{"plaintext", c, "s3cret", true},
+		{"bad base64", c, prefix + "!!", true},
+		{"short", c, prefix + base64.StdEncoding.EncodeToString([]byte("short")), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Decrypt(tt.ciphertext)
+			if err == nil || got != "" {
+				t.Fatalf("Decrypt = %q, %v", got, err)
+			}
+			if errors.Is(err, ErrInvalidCiphertext) != tt.invalid {
+				t.Errorf("Decrypt error = %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "conf")
+	path := filepath.Join(dir, "secret.key")
+	key, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw, err := hex.DecodeString(key); err != nil || len(raw) != 32 {
+		t.Fatalf("key = %q", key)
+	}
+	for name, want := range map[string]os.FileMode{dir: 0o700, path: 0o600} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != want {
+			t.Errorf("%s mode = %o, want %o", name, mode, want)
+		}
+	}
+	again, err := LoadOrCreateKey(path)
+	if err != nil || again != key {
+		t.Fatalf("reloaded key = %q, %v, want %q", again, err, key)
+	}
+}
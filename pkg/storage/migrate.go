@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// migrationBucket 记录已执行的迁移
+const migrationBucket = "_migrations"
+
+// Migration 是一次数据结构变更，ID 全局唯一，已执行的迁移不能修改
+type Migration struct {
+	ID string
+	Up func(tx *Tx) error
+}
+
+type migrationRecord struct {
+	ID        string    `json:"id"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Migrate 按顺序执行尚未执行过的迁移，每个迁移在独立的事务中执行，失败时停止
+func (db *DB) Migrate(migrations ...Migration) error {
+	seen := make(map[string]bool, len(migrations))
+	for _, item := range migrations {
+		if item.ID == "" || seen[item.ID] {
+			return fmt.Errorf("storage: invalid or duplicate migration id %q", item.ID)
+		}
+		seen[item.ID] = true
+	}
+	for _, item := range migrations {
+		err := db.Update(func(tx *Tx) error {
+			var record migrationRecord
+			if err := tx.Get(migrationBucket, item.ID, &record); err == nil {
+				return nil
+			} else if !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			if err := item.Up(tx); err != nil {
+				return err
+			}
+			slog.Info("storage", "migration applied", item.ID)
+			return tx.Put(migrationBucket, item.ID, migrationRecord{ID: item.ID, AppliedAt: time.Now()})
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", item.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+// Package storage 基于 bbolt 的嵌入式存储，数据以 JSON 保存在按用途划分的 bucket 中
+package storage
+
+import (
+	"cyber-docker/pkg/container/di"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	Name = di.TypeInstanceToName(DB{})
+
+	ErrNotFound = errors.New("storage: record not found")
+	// ErrLocked 数据库文件被其他进程（通常是正在运行的服务）占用
+	ErrLocked = errors.New("storage: database is in use by another process")
+)
+
+func From(get di.Get) *DB {
+	return get(Name).(*DB)
+}
+
+type DB struct {
+	bolt *bolt.DB
+}
+
+type Options struct {
+	ReadOnly bool
+	// 等待文件锁的时间，为 0 时一直等待
+	Timeout time.Duration
+}
+
+// Open 打开数据库文件，不存在时创建
+func Open(path string, opts *Options) (*DB, error) {
+	if opts == nil {
+		opts = &Options{Timeout: time.Second}
+	}
+	if !opts.ReadOnly {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{
+		ReadOnly: opts.ReadOnly,
+		Timeout:  opts.Timeout,
+	})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &DB{bolt: db}, nil
+}
+
+func (db *DB) Close() error {
+	return db.bolt.Close()
+}
+
+func (db *DB) Path() string {
+	return db.bolt.Path()
+}
+
+// View 在只读事务中执行 fn
+func (db *DB) View(fn func(tx *Tx) error) error {
+	return db.bolt.View(func(tx *bolt.Tx) error {
+		return fn(&Tx{tx: tx})
+	})
+}
+
+// Update 在读写事务中执行 fn，fn 返回错误时回滚
+func (db *DB) Update(fn func(tx *Tx) error) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		return fn(&Tx{tx: tx})
+	})
+}
+
+// Backup 把一致的数据库快照写入 w，备份期间不阻塞写入
+func (db *DB) Backup(w io.Writer) (int64, error) {
+	var size int64
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		var err error
+		size, err = tx.WriteTo(w)
+		return err
+	})
+	return size, err
+}
+
+// Restore 用 r 中的备份替换 path 处的数据库，原文件保留为 path.bak，调用前必须关闭数据库
+func Restore(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".restore"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = verify(tmp)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if _, err = os.Stat(path); err == nil {
+		if err = os.Rename(path, path+".bak"); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, path)
+}
+
+// verify 检查文件是否为完整的数据库
+func verify(path string) error {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("invalid backup: %w", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return db.View(func(tx *bolt.Tx) error {
+		// 截断的文件在检查时会访问超出文件末尾的页
+		if info.Size() < tx.Size() {
+			return fmt.Errorf("invalid backup: file is truncated to %d of %d bytes", info.Size(), tx.Size())
+		}
+		for err := range tx.Check() {
+			return fmt.Errorf("invalid backup: %w", err)
+		}
+		return nil
+	})
+}
+
+type Tx struct {
+	tx *bolt.Tx
+}
+
+func (t *Tx) bucket(name string) (*bolt.Bucket, error) {
+	if !t.tx.Writable() {
+		return t.tx.Bucket([]byte(name)), nil
+	}
+	return t.tx.CreateBucketIfNotExists([]byte(name))
+}
+
+// Get 读取 key 并解码到 v，不存在时返回 ErrNotFound
+func (t *Tx) Get(bucket, key string, v interface{}) error {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return ErrNotFound
+	}
+	buf := b.Get([]byte(key))
+	if buf == nil {
+		return ErrNotFound
+	}
+	return json.Unmarshal(buf, v)
+}
+
+// Put 把 v 编码为 JSON 保存，bucket 不存在时自动创建
+func (t *Tx) Put(bucket, key string, v interface{}) error {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), buf)
+}
+
+// Delete 删除不存在的 key 时返回 ErrNotFound
+func (t *Tx) Delete(bucket, key string) error {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return err
+	}
+	if b.Get([]byte(key)) == nil {
+		return ErrNotFound
+	}
+	return b.Delete([]byte(key))
+}
+
+// ForEach 按 key 的字节序遍历，fn 返回 ErrStop 时提前结束
+func (t *Tx) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	return t.scan(bucket, (*bolt.Cursor).First, (*bolt.Cursor).Next, fn)
+}
+
+// ForEachReverse 按 key 的字节序倒序遍历
+func (t *Tx) ForEachReverse(bucket string, fn func(key string, value []byte) error) error {
+	return t.scan(bucket, (*bolt.Cursor).Last, (*bolt.Cursor).Prev, fn)
+}
+
+// ForEachReverseBefore 从小于 before 的 key 开始倒序遍历，before 为空时与 ForEachReverse 相同。
+// 用于分多个事务读取大量数据，避免长时间持有只读事务
+func (t *Tx) ForEachReverseBefore(bucket, before string, fn func(key string, value []byte) error) error {
+	if before == "" {
+		return t.ForEachReverse(bucket, fn)
+	}
+	seek := func(cursor *bolt.Cursor) ([]byte, []byte) {
+		// Seek 定位到第一个大于等于 before 的 key
+		if k, _ := cursor.Seek([]byte(before)); k == nil {
+			return cursor.Last()
+		}
+		return cursor.Prev()
+	}
+	return t.scan(bucket, seek, (*bolt.Cursor).Prev, fn)
+}
+
+// ErrStop 用于提前结束遍历，不作为错误返回
+var ErrStop = errors.New("storage: stop iteration")
+
+func (t *Tx) scan(bucket string, first, next func(cursor *bolt.Cursor) ([]byte, []byte), fn func(key string, value []byte) error) error {
+	b, err := t.bucket(bucket)
+	if err != nil || b == nil {
+		return err
+	}
+	cursor := b.Cursor()
+	for k, v := first(cursor); k != nil; k, v = next(cursor) {
+		if err = fn(string(k), v); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// NextSequence 返回 bucket 中自增的序号
+func (t *Tx) NextSequence(bucket string) (uint64, error) {
+	b, err := t.bucket(bucket)
+	if err != nil {
+		return 0, err
+	}
+	return b.NextSequence()
+}
+
+// SequenceKey 把序号编码为按数值排序的 key
+func SequenceKey(seq uint64) string {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], seq)
+	return string(buf[:])
+}
+
+// DeleteBucket 删除整个 bucket，不存在时忽略
+func (t *Tx) DeleteBucket(bucket string) error {
+	err := t.tx.DeleteBucket([]byte(bucket))
+	if errors.Is(err, bolt.ErrBucketNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type record struct {
+	Name string `json:"name"`
+}
+
+func open(t *testing.T, path string) *DB {
+	t.Helper()
+	db, err := Open(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	db := open(t, path)
+	var applied []string
+	migration := func(id string) Migration {
+		return Migration{ID: id, Up: func(tx *Tx) error {
+			applied = append(applied, id)
+			return tx.Put("items", id, record{Name: id})
+		}}
+	}
+	if err := db.Migrate(migration("0001"), migration("0002")); err != nil {
+		t.Fatal(err)
+	}
+	// 重新打开后已执行的迁移不再执行，新迁移按顺序执行
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db = open(t, path)
+	defer db.Close()
+	if err := db.Migrate(migration("0001"), migration("0002"), migration("0003"), migration("0004")); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"0001", "0002", "0003", "0004"}; !reflect.DeepEqual(applied, want) {
+		t.Fatalf("applied = %v, want %v", applied, want)
+	}
+
+	for _, migrations := range [][]Migration{
+		{migration("0001"), migration("0001")},
+		{migration("")},
+	} {
+		if err := db.Migrate(migrations...); err == nil {
+			t.Errorf("invalid migrations %v are accepted", migrations)
+		}
+	}
+}
+
+func TestMigrateRollback(t *testing.T) {
+	db := open(t, filepath.Join(t.TempDir(), "data.db"))
+	defer db.Close()
+	failed := errors.New("disk full")
+	attempts := 0
+	broken := Migration{ID: "0002", Up: func(tx *Tx) error {
+		attempts++
+		if err := tx.Put("items", "partial", record{Name: "partial"}); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return failed
+		}
+		return nil
+	}}
+	ran := false
+	after := Migration{ID: "0003", Up: func(tx *Tx) error {
+		ran = true
+		return nil
+	}}
+	first := Migration{ID: "0001", Up: func(tx *Tx) error {
+		return tx.Put("items", "first", record{Name: "first"})
+	}}
+
+	err := db.Migrate(first, broken, after)
+	if !errors.Is(err, failed) {
+		t.Fatalf("migrate error = %v", err)
+	}
+	if ran {
+		t.Fatal("migrations after the failed one are executed")
+	}
+	err = db.View(func(tx *Tx) error {
+		var r record
+		if err := tx.Get("items", "first", &r); err != nil {
+			return fmt.Errorf("committed migration: %w", err)
+		}
+		if err := tx.Get("items", "partial", &r); !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("failed migration is not rolled back: %v", err)
+		}
+		return tx.Get(migrationBucket, "0002", &migrationRecord{})
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("failed migration is recorded: %v", err)
+	}
+
+	// 再次执行时从失败的迁移继续
+	if err = db.Migrate(first, broken, after); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 || !ran {
+		t.Fatalf("attempts = %d, ran = %v", attempts, ran)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	source := open(t, filepath.Join(dir, "source.db"))
+	defer source.Close()
+	err := source.Update(func(tx *Tx) error {
+		return tx.Put("items", "a", record{Name: "backup"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backup bytes.Buffer
+	size, err := source.Backup(&backup)
+	if err != nil || size != int64(backup.Len()) {
+		t.Fatalf("backup size = %d, %v", size, err)
+	}
+
+	path := filepath.Join(dir, "data.db")
+	current := open(t, path)
+	err = current.Update(func(tx *Tx) error {
+		return tx.Put("items", "a", record{Name: "current"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = current.Close(); err != nil {
+		t.Fatal(err)
+	}
+	read := func(path string) string {
+		t.Helper()
+		db, err := Open(path, &Options{ReadOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		var r record
+		err = db.View(func(tx *Tx) error {
+			return tx.Get("items", "a", &r)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.Name
+	}
+
+	if err = Restore(path, bytes.NewReader(backup.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if name := read(path); name != "backup" {
+		t.Fatalf("restored record = %s", name)
+	}
+	if name := read(path + ".bak"); name != "current" {
+		t.Fatalf("previous database = %s", name)
+	}
+
+	// 损坏的备份不替换现有数据库，也不覆盖 .bak
+	for name, content := range map[string][]byte{
+		"garbage":   []byte("not a database"),
+		"truncated": backup.Bytes()[:backup.Len()/2],
+		"empty":     nil,
+	} {
+		if err = Restore(path, bytes.NewReader(content)); err == nil {
+			t.Errorf("%s backup is restored", name)
+		}
+	}
+	if name := read(path); name != "backup" {
+		t.Fatalf("database after rejected restore = %s", name)
+	}
+	if name := read(path + ".bak"); name != "current" {
+		t.Fatalf("previous database after rejected restore = %s", name)
+	}
+	if _, err = os.Stat(path + ".restore"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("temporary file is left: %v", err)
+	}
+}
+
+func TestForEachReverseBefore(t *testing.T) {
+	db := open(t, filepath.Join(t.TempDir(), "data.db"))
+	defer db.Close()
+	err := db.Update(func(tx *Tx) error {
+		for _, key := range []string{"b", "d", "f"} {
+			if err := tx.Put("items", key, record{Name: key}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		before string
+		want   []string
+	}{
+		{"", []string{"f", "d", "b"}},
+		{"f", []string{"d", "b"}},
+		{"e", []string{"d", "b"}},
+		{"z", []string{"f", "d", "b"}},
+		{"b", nil},
+		{"a", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.before, func(t *testing.T) {
+			var keys []string
+			err := db.View(func(tx *Tx) error {
+				return tx.ForEachReverseBefore("items", tt.before, func(key string, value []byte) error {
+					keys = append(keys, key)
+					return nil
+				})
+			})
+			if err != nil || !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("keys before %q = %v, %v, want %v", tt.before, keys, err, tt.want)
+			}
+		})
+	}
+
+	var keys []string
+	err = db.View(func(tx *Tx) error {
+		if err := tx.ForEachReverseBefore("missing", "z", func(key string, value []byte) error {
+			return errors.New("empty bucket has no keys")
+		}); err != nil {
+			return err
+		}
+		return tx.ForEachReverseBefore("items", "z", func(key string, value []byte) error {
+			keys = append(keys, key)
+			return ErrStop
+		})
+	})
+	if err != nil || !reflect.DeepEqual(keys, []string{"f"}) {
+		t.Fatalf("stop = %v, %v", keys, err)
+	}
+}
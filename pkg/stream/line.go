@@ -0,0 +1,36 @@
+package stream
+
+import "bytes"
+
+// LineWriter 把写入的数据按行切分后回调，不完整的行在 Flush 时输出
+type LineWriter struct {
+	buf  bytes.Buffer
+	emit func(line string) error
+}
+
+func NewLineWriter(emit func(line string) error) *LineWriter {
+	return &LineWriter{emit: emit}
+}
+
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(bytes.TrimSuffix(w.buf.Next(i + 1)[:i], []byte("\r")))
+		if err := w.emit(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+func (w *LineWriter) Flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	line := w.buf.String()
+	w.buf.Reset()
+	return w.emit(line)
+}
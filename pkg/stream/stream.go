@@ -0,0 +1,145 @@
+// Package stream 把长时间运行的输出（日志、拉取进度、统计数据等）以事件的形式推送给客户端，
+// 支持 Server-Sent Events 和 WebSocket 两种传输方式
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// EventError 在流中途出错时发送，data 为错误信息
+	EventError = "error"
+	// EventDone 在流正常结束时发送
+	EventDone = "done"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+type Event struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
+type Sender interface {
+	Send(event string, data interface{}) error
+	// Done 在客户端断开连接时关闭
+	Done() <-chan struct{}
+	Close() error
+}
+
+// IsWebSocket 判断请求是否为 WebSocket 升级请求
+func IsWebSocket(c *gin.Context) bool {
+	return websocket.IsWebSocketUpgrade(c.Request)
+}
+
+// Open 根据请求选择传输方式，WebSocket 升级请求使用 WebSocket，否则使用 SSE
+func Open(c *gin.Context) (Sender, error) {
+	if IsWebSocket(c) {
+		conn, err := Upgrade(c)
+		if err != nil {
+			return nil, err
+		}
+		return newWebSocketSender(c.Request.Context(), conn), nil
+	}
+	return NewSSE(c), nil
+}
+
+// Upgrade 升级为 WebSocket 连接，失败时已向客户端写入错误响应
+func Upgrade(c *gin.Context) (*websocket.Conn, error) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		c.Abort()
+		return nil, err
+	}
+	c.Abort()
+	return conn, nil
+}
+
+type sseSender struct {
+	c     *gin.Context
+	mutex sync.Mutex
+}
+
+func NewSSE(c *gin.Context) Sender {
+	// 流式响应不受 http.Server 的 WriteTimeout 限制
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+	c.Abort()
+	return &sseSender{c: c}
+}
+
+func (s *sseSender) Send(event string, data interface{}) error {
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, err = fmt.Fprintf(s.c.Writer, "event: %s\ndata: %s\n\n", event, buf); err != nil {
+		return err
+	}
+	s.c.Writer.Flush()
+	return nil
+}
+
+func (s *sseSender) Done() <-chan struct{} {
+	return s.c.Request.Context().Done()
+}
+
+func (s *sseSender) Close() error {
+	return nil
+}
+
+type webSocketSender struct {
+	conn  *websocket.Conn
+	mutex sync.Mutex
+	ctx   context.Context
+}
+
+func newWebSocketSender(parent context.Context, conn *websocket.Conn) Sender {
+	ctx, cancel := context.WithCancel(parent)
+	s := &webSocketSender{conn: conn, ctx: ctx}
+	// 只用于处理控制帧和感知客户端断开
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *webSocketSender) Send(event string, data interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.conn.WriteJSON(Event{Event: event, Data: data})
+}
+
+func (s *webSocketSender) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+func (s *webSocketSender) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_ = s.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	return s.conn.Close()
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
+)
+
+// 稳定的错误码，客户端应根据错误码而不是错误信息判断错误类型
+const (
+	CodeInvalidParameter = "invalid_parameter"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeLocked           = "locked"
+	CodeCanceled         = "canceled"
+	CodeNotImplemented   = "not_implemented"
+	CodeUnavailable      = "unavailable"
+	CodeTimeout          = "timeout"
+	CodeInternal         = "internal"
+)
+
+// Error 是带有 HTTP 状态码的错误，用于 errdefs 无法表示的状态，例如 423 Locked
+type Error struct {
+	Status int
+	Err    error
+}
+
+func NewError(status int, err error) *Error {
+	return &Error{Status: status, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// statusCodes 状态码与错误码的对应关系
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          CodeInvalidParameter,
+	http.StatusUnauthorized:        CodeUnauthorized,
+	http.StatusForbidden:           CodeForbidden,
+	http.StatusNotFound:            CodeNotFound,
+	http.StatusConflict:            CodeConflict,
+	http.StatusLocked:              CodeLocked,
+	499:                            CodeCanceled,
+	http.StatusNotImplemented:      CodeNotImplemented,
+	http.StatusServiceUnavailable:  CodeUnavailable,
+	http.StatusGatewayTimeout:      CodeTimeout,
+	http.StatusInternalServerError: CodeInternal,
+}
+
+// StatusOf 把 Docker errdefs 错误映射为 HTTP 状态码，未分类的错误为 500
+func StatusOf(err error) int {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e.Status
+	case errdefs.IsInvalidParameter(err):
+		return http.StatusBadRequest
+	case errdefs.IsUnauthorized(err):
+		return http.StatusUnauthorized
+	case errdefs.IsForbidden(err):
+		return http.StatusForbidden
+	case errdefs.IsNotFound(err):
+		return http.StatusNotFound
+	case errdefs.IsConflict(err):
+		return http.StatusConflict
+	case errdefs.IsNotImplemented(err):
+		return http.StatusNotImplemented
+	case errdefs.IsUnavailable(err):
+		return http.StatusServiceUnavailable
+	case errdefs.IsDeadline(err), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errdefs.IsCancelled(err), errors.Is(err, context.Canceled):
+		// 客户端已断开，沿用 nginx 的 499
+		return 499
+	}
+	return http.StatusInternalServerError
+}
+
+// CodeOf 返回 HTTP 状态码对应的错误码
+func CodeOf(status int) string {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeInvalidParameter
+}
+
+// HandlerFunc 返回响应数据或错误，由 Handle 统一写入响应
+type HandlerFunc func(c *gin.Context) (interface{}, error)
+
+// Page 是 HandlerFunc 返回的分页数据，Handle 通过 ResPage 写入总数
+type Page struct {
+	Total int64
+	Data  interface{}
+}
+
+// Handle 把 HandlerFunc 转换为 gin.HandlerFunc，保证每个请求只写入一次响应。
+// 返回的 data 为 nil 时响应 ResOK，为 Page 时响应 ResPage，处理函数已自行写入响应（文件下载、事件流等）时不再写入
+func Handle(fn HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := fn(c)
+		if c.Writer.Written() || c.IsAborted() {
+			if err != nil {
+				slog.Debug("handler", "path", c.FullPath(), "err", err)
+			}
+			return
+		}
+		switch page, ok := data.(Page); {
+		case err != nil:
+			ResFail(c, err)
+		case ok:
+			ResPage(c, page.Total, page.Data)
+		case data == nil:
+			ResOK(c)
+		default:
+			ResSuccess(c, data)
+		}
+	}
+}
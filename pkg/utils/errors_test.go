@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/errdefs"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestStatusOf(t *testing.T) {
+	cause := errors.New("cause")
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"invalid parameter", errdefs.InvalidParameter(cause), http.StatusBadRequest, CodeInvalidParameter},
+		{"unauthorized", errdefs.Unauthorized(cause), http.StatusUnauthorized, CodeUnauthorized},
+		{"forbidden", errdefs.Forbidden(cause), http.StatusForbidden, CodeForbidden},
+		{"not found", errdefs.NotFound(cause), http.StatusNotFound, CodeNotFound},
+		{"conflict", errdefs.Conflict(cause), http.StatusConflict, CodeConflict},
+		{"not implemented", errdefs.NotImplemented(cause), http.StatusNotImplemented, CodeNotImplemented},
+		{"unavailable", errdefs.Unavailable(cause), http.StatusServiceUnavailable, CodeUnavailable},
+		{"deadline", errdefs.Deadline(cause), http.StatusGatewayTimeout, CodeTimeout},
+		{"context deadline", fmt.Errorf("pull: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, CodeTimeout},
+		{"cancelled", errdefs.Cancelled(cause), 499, CodeCanceled},
+		{"context canceled", context.Canceled, 499, CodeCanceled},
+		{"system", errdefs.System(cause), http.StatusInternalServerError, CodeInternal},
+		{"unclassified", cause, http.StatusInternalServerError, CodeInternal},
+		{"wrapped", fmt.Errorf("remove: %w", errdefs.NotFound(cause)), http.StatusNotFound, CodeNotFound},
+		{"explicit status", NewError(http.StatusLocked, cause), http.StatusLocked, CodeLocked},
+		{"explicit status wins", NewError(http.StatusForbidden, errdefs.NotFound(cause)), http.StatusForbidden, CodeForbidden},
+		{"wrapped explicit status", fmt.Errorf("login: %w", NewError(http.StatusLocked, cause)), http.StatusLocked, CodeLocked},
+		{"unknown client status", NewError(http.StatusRequestEntityTooLarge, cause), http.StatusRequestEntityTooLarge, CodeInvalidParameter},
+		{"unknown server status", NewError(http.StatusBadGateway, cause), http.StatusBadGateway, CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := StatusOf(tt.err)
+			if status != tt.status {
+				t.Errorf("StatusOf(%v) = %d, want %d", tt.err, status, tt.status)
+			}
+			if code := CodeOf(status); code != tt.code {
+				t.Errorf("CodeOf(%d) = %s, want %s", status, code, tt.code)
+			}
+		})
+	}
+}
+
+// countingWriter 记录写入响应的次数
+type countingWriter struct {
+	gin.ResponseWriter
+	writes int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.writes++
+	return w.ResponseWriter.Write(b)
+}
+
+func TestHandle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		fn     HandlerFunc
+		status int
+		body   string
+	}{
+		{
+			"streamed then failed",
+			func(c *gin.Context) (interface{}, error) {
+				c.String(http.StatusOK, "partial")
+				return nil, errors.New("broken pipe")
+			},
+			http.StatusOK, "partial",
+		},
+		{
+			"streamed with data",
+			func(c *gin.Context) (interface{}, error) {
+				c.String(http.StatusOK, "file")
+				return "ignored", nil
+			},
+			http.StatusOK, "file",
+		},
+		{
+			"aborted",
+			func(c *gin.Context) (interface{}, error) {
+				ResError(c, http.StatusConflict, "in use")
+				return nil, errdefs.NotFound(errors.New("missing"))
+			},
+			http.StatusConflict, "",
+		},
+		{"error", func(c *gin.Context) (interface{}, error) { return nil, errdefs.NotFound(errors.New("missing")) }, http.StatusNotFound, ""},
+		{"data", func(c *gin.Context) (interface{}, error) { return "ok", nil }, http.StatusOK, ""},
+		{"nil data", func(c *gin.Context) (interface{}, error) { return nil, nil }, http.StatusOK, ""},
+		{"page", func(c *gin.Context) (interface{}, error) { return Page{Total: 3, Data: []int{1}}, nil }, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writer *countingWriter
+			engine := gin.New()
+			engine.Use(func(c *gin.Context) {
+				writer = &countingWriter{ResponseWriter: c.Writer}
+				c.Writer = writer
+			})
+			engine.GET("/", Handle(tt.fn))
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if writer.writes != 1 {
+				t.Errorf("writes = %d, want 1, body %q", writer.writes, w.Body.String())
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestHandleResult(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name string
+		fn   HandlerFunc
+		want ResponseResult
+	}{
+		{"error", func(c *gin.Context) (interface{}, error) {
+			return nil, NewError(http.StatusLocked, errors.New("locked"))
+		}, ResponseResult{Code: http.StatusLocked, Error: CodeLocked, Detail: "locked"}},
+		{"data", func(c *gin.Context) (interface{}, error) { return "ok", nil }, ResponseResult{Success: true, Code: http.StatusOK, Data: "ok"}},
+		{"nil data", func(c *gin.Context) (interface{}, error) { return nil, nil }, ResponseResult{Success: true, Code: http.StatusOK, Data: true}},
+		{"page", func(c *gin.Context) (interface{}, error) { return Page{Total: 3, Data: "a"}, nil }, ResponseResult{Success: true, Code: http.StatusOK, Data: "a", Total: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.GET("/", Handle(tt.fn))
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			var got ResponseResult
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid response %q", w.Body.String())
+			}
+			got.Msg = ""
+			if got != tt.want {
+				t.Errorf("response = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResFail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		msg    string
+	}{
+		{"truncated body", errdefs.InvalidParameter(io.ErrUnexpectedEOF), http.StatusBadRequest, "Request body is not valid JSON"},
+		{"empty body", NewError(http.StatusBadRequest, io.EOF), http.StatusBadRequest, "Request body is not valid JSON"},
+		{"other parameter error", errdefs.InvalidParameter(errors.New("tag is required")), http.StatusBadRequest, "Invalid request parameters"},
+		// Docker 连接断开不是请求体格式错误
+		{"unexpected EOF", io.ErrUnexpectedEOF, http.StatusInternalServerError, "Internal server error"},
+		{"dropped connection", &url.Error{Op: "Get", URL: "http://docker/containers/json", Err: io.EOF}, http.StatusInternalServerError, "Internal server error"},
+		{"unavailable", errdefs.Unavailable(io.ErrUnexpectedEOF), http.StatusServiceUnavailable, "Service temporarily unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.GET("/", func(c *gin.Context) {
+				ResFail(c, tt.err)
+			})
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept-Language", "en-US")
+			engine.ServeHTTP(w, req)
+			var got ResponseResult
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid response %q", w.Body.String())
+			}
+			if w.Code != tt.status || got.Msg != tt.msg {
+				t.Errorf("response = %d %+v, want %d %q", w.Code, got, tt.status, tt.msg)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"cyber-docker/pkg/i18n"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -12,6 +13,10 @@ type ResponseResult struct {
 	Code    int         `json:"code,omitempty"`
 	Total   int64       `json:"total,omitempty"`
 	Msg     string      `json:"msg,omitempty"`
+	// Error 为失败时的错误码，例如 not_found
+	Error string `json:"error,omitempty"`
+	// Detail 为未翻译的原始错误信息
+	Detail string `json:"detail,omitempty"`
 }
 
 // ResJSON Response json data with status code
@@ -29,7 +34,7 @@ func ResSuccess(c *gin.Context, v interface{}) {
 	ResJSON(c, http.StatusOK, ResponseResult{
 		Success: true,
 		Code:    http.StatusOK,
-		Msg:     "操作成功",
+		Msg:     i18n.T(i18n.Locale(c), "success"),
 		Data:    v,
 	})
 }
@@ -38,7 +43,7 @@ func ResPage(c *gin.Context, total int64, v interface{}) {
 	ResJSON(c, http.StatusOK, ResponseResult{
 		Success: true,
 		Code:    http.StatusOK,
-		Msg:     "操作成功",
+		Msg:     i18n.T(i18n.Locale(c), "success"),
 		Data:    v,
 		Total:   total,
 	})
@@ -48,15 +53,39 @@ func ResOK(c *gin.Context) {
 	ResJSON(c, http.StatusOK, ResponseResult{
 		Success: true,
 		Code:    http.StatusOK,
-		Msg:     "操作成功",
+		Msg:     i18n.T(i18n.Locale(c), "success"),
 		Data:    true,
 	})
 }
 
-func ResError(c *gin.Context, code int, msg string) {
-	ResJSON(c, http.StatusOK, ResponseResult{
+// ResError code 为 HTTP 状态码，同时写入响应体。msg 为错误码翻译后的信息，原始信息保存在 detail 中
+func ResError(c *gin.Context, code int, detail string) {
+	ResJSON(c, code, ResponseResult{
 		Success: false,
 		Code:    code,
-		Msg:     msg,
+		Msg:     i18n.T(i18n.Locale(c), "error."+CodeOf(code)),
+		Error:   CodeOf(code),
+		Detail:  detail,
 	})
 }
+
+// ResFail 根据错误类型选择 HTTP 状态码，参数校验错误逐个字段翻译。
+// 只翻译状态码为 400 的错误，Docker 连接断开等错误中的 io.EOF 不能当作请求体格式错误
+func ResFail(c *gin.Context, err error) {
+	status := StatusOf(err)
+	if status != http.StatusBadRequest {
+		ResError(c, status, err.Error())
+		return
+	}
+	if msg, ok := i18n.Validation(i18n.Locale(c), err); ok {
+		ResJSON(c, http.StatusBadRequest, ResponseResult{
+			Success: false,
+			Code:    http.StatusBadRequest,
+			Msg:     msg,
+			Error:   CodeInvalidParameter,
+			Detail:  err.Error(),
+		})
+		return
+	}
+	ResError(c, status, err.Error())
+}